@@ -75,6 +75,7 @@ var (
 	analysisTimeout   time.Duration
 	recursive         bool
 	ignoreUnknown     bool
+	diffBase          []string
 
 	fileExtensions = []string{".json", ".yaml", ".yml"}
 )
@@ -103,6 +104,10 @@ func Analyze() *cobra.Command {
   # and suppress MisplacedAnnotation on deployment foobar in namespace default.
   istioctl analyze -S "IST0103=Pod *.testing" -S "IST0107=Deployment foobar.default"
 
+  # Analyze the files of a pull request, and only report the messages that are new, resolved or changed
+  # compared to the files of the base branch, as SARIF
+  istioctl analyze --use-kube=false --diff-base base/my-istio-config/ -o sarif my-istio-config/
+
   # List available analyzers
   istioctl analyze -L`,
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -147,78 +152,27 @@ func Analyze() *cobra.Command {
 				selectedNamespace = ""
 			}
 
-			sa := local.NewIstiodAnalyzer(analyzers.AllCombined(),
-				resource.Namespace(selectedNamespace),
-				resource.Namespace(istioNamespace), nil, true)
-
-			// Check for suppressions and add them to our SourceAnalyzer
-			suppressions := make([]local.AnalysisSuppression, 0, len(suppress))
-			for _, s := range suppress {
-				parts := strings.Split(s, "=")
-				if len(parts) != 2 {
-					return fmt.Errorf("%s is not a valid suppression value. See istioctl analyze --help", s)
-				}
-				// Check to see if the supplied code is valid. If not, emit a
-				// warning but continue.
-				codeIsValid := false
-				for _, at := range msg.All() {
-					if at.Code() == parts[0] {
-						codeIsValid = true
-						break
-					}
-				}
+			suppressions, err := parseSuppressions(cmd)
+			if err != nil {
+				return err
+			}
 
-				if !codeIsValid {
-					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Supplied message code '%s' is an unknown message code and will not have any effect.\n", parts[0])
-				}
-				suppressions = append(suppressions, local.AnalysisSuppression{
-					Code:         parts[0],
-					ResourceName: parts[1],
-				})
+			result, parseErrors, err := analyzeReaders(cmd, readers, suppressions, cancel)
+			if err != nil {
+				return err
 			}
-			sa.SetSuppressions(suppressions)
 
-			// If we're using kube, use that as a base source.
-			if useKube {
-				// Set up the kube client
-				restConfig, err := kube.DefaultRestConfig(kubeconfig, configContext)
-				if err != nil {
-					return err
-				}
-				k, err := kube.NewClient(kube.NewClientConfigForRestConfig(restConfig))
+			// In diff mode, only report the differences with the analysis of the base files
+			if len(diffBase) > 0 {
+				baseReaders, err := gatherFiles(cmd, diffBase)
 				if err != nil {
 					return err
 				}
-				sa.AddRunningKubeSource(k)
-			}
-
-			// If we explicitly specify mesh config, use it.
-			// This takes precedence over default mesh config or mesh config from a running Kube instance.
-			if meshCfgFile != "" {
-				_ = sa.AddFileKubeMeshConfig(meshCfgFile)
-			}
-
-			// If we're not using kube (files only), add defaults for some resources we expect to be provided by Istio
-			if !useKube {
-				err := sa.AddDefaultResources()
+				baseResult, baseParseErrors, err := analyzeReaders(cmd, baseReaders, suppressions, cancel)
 				if err != nil {
 					return err
 				}
-			}
-
-			// If files are provided, treat them (collectively) as a source.
-			parseErrors := 0
-			if len(readers) > 0 {
-				if err = sa.AddReaderKubeSource(readers); err != nil {
-					fmt.Fprintf(cmd.ErrOrStderr(), "Error(s) adding files: %v", err)
-					parseErrors++
-				}
-			}
-
-			// Do the analysis
-			result, err := sa.Analyze(cancel)
-			if err != nil {
-				return err
+				return printAnalysisDiff(cmd, baseResult, result, baseParseErrors+parseErrors)
 			}
 
 			// Maybe output details about which analyzers ran
@@ -317,9 +271,139 @@ func Analyze() *cobra.Command {
 		"Process directory arguments recursively. Useful when you want to analyze related manifests organized within the same directory.")
 	analysisCmd.PersistentFlags().BoolVar(&ignoreUnknown, "ignore-unknown", false,
 		"Don't complain about un-parseable input documents, for cases where analyze should run only on k8s compliant inputs.")
+	analysisCmd.PersistentFlags().StringArrayVar(&diffBase, "diff-base", []string{},
+		"Files or directories with the previous revision of the configuration. If set, only the messages that are new, "+
+			"resolved or changed compared to the analysis of these files are reported. Can be repeated.")
 	return analysisCmd
 }
 
+// parseSuppressions parses the --suppress flags.
+func parseSuppressions(cmd *cobra.Command) ([]local.AnalysisSuppression, error) {
+	suppressions := make([]local.AnalysisSuppression, 0, len(suppress))
+	for _, s := range suppress {
+		parts := strings.Split(s, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s is not a valid suppression value. See istioctl analyze --help", s)
+		}
+		// Check to see if the supplied code is valid. If not, emit a
+		// warning but continue.
+		codeIsValid := false
+		for _, at := range msg.All() {
+			if at.Code() == parts[0] {
+				codeIsValid = true
+				break
+			}
+		}
+
+		if !codeIsValid {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Supplied message code '%s' is an unknown message code and will not have any effect.\n", parts[0])
+		}
+		suppressions = append(suppressions, local.AnalysisSuppression{
+			Code:         parts[0],
+			ResourceName: parts[1],
+		})
+	}
+	return suppressions, nil
+}
+
+// analyzeReaders analyzes the files of the readers, with the live cluster if --use-kube is set. It returns the
+// result of the analysis and the number of files that could not be parsed.
+func analyzeReaders(cmd *cobra.Command, readers []local.ReaderSource, suppressions []local.AnalysisSuppression,
+	cancel chan struct{},
+) (local.AnalysisResult, int, error) {
+	sa := local.NewIstiodAnalyzer(analyzers.AllCombined(),
+		resource.Namespace(selectedNamespace),
+		resource.Namespace(istioNamespace), nil, true)
+	sa.SetSuppressions(suppressions)
+
+	// If we're using kube, use that as a base source.
+	if useKube {
+		// Set up the kube client
+		restConfig, err := kube.DefaultRestConfig(kubeconfig, configContext)
+		if err != nil {
+			return local.AnalysisResult{}, 0, err
+		}
+		k, err := kube.NewClient(kube.NewClientConfigForRestConfig(restConfig))
+		if err != nil {
+			return local.AnalysisResult{}, 0, err
+		}
+		sa.AddRunningKubeSource(k)
+	}
+
+	// If we explicitly specify mesh config, use it.
+	// This takes precedence over default mesh config or mesh config from a running Kube instance.
+	if meshCfgFile != "" {
+		_ = sa.AddFileKubeMeshConfig(meshCfgFile)
+	}
+
+	// If we're not using kube (files only), add defaults for some resources we expect to be provided by Istio
+	if !useKube {
+		err := sa.AddDefaultResources()
+		if err != nil {
+			return local.AnalysisResult{}, 0, err
+		}
+	}
+
+	// If files are provided, treat them (collectively) as a source.
+	parseErrors := 0
+	if len(readers) > 0 {
+		if err := sa.AddReaderKubeSource(readers); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error(s) adding files: %v", err)
+			parseErrors++
+		}
+	}
+
+	// Do the analysis
+	result, err := sa.Analyze(cancel)
+	if err != nil {
+		return local.AnalysisResult{}, 0, err
+	}
+	return result, parseErrors, nil
+}
+
+// printAnalysisDiff prints the messages that are new, resolved or changed compared to the analysis of the base files.
+// Only new and changed messages are considered for the exit code.
+func printAnalysisDiff(cmd *cobra.Command, base, result local.AnalysisResult, parseErrors int) error {
+	before := base.Messages.SetDocRef("istioctl-analyze").FilterOutLowerThan(outputThreshold.Level)
+	after := result.Messages.SetDocRef("istioctl-analyze").FilterOutLowerThan(outputThreshold.Level)
+	diffs := diag.Diff(before, after)
+
+	output, err := formatting.PrintDiff(diffs, msgOutputFormat, colorize)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), output)
+
+	if len(diffs) == 0 {
+		if parseErrors == 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "\u2714 No new, resolved or changed validation issues found when analyzing %s.\n",
+				analyzeTargetAsString())
+		} else {
+			fmt.Fprintf(cmd.ErrOrStderr(),
+				"No new, resolved or changed validation issues found when analyzing %s (but %d file(s) could not be parsed).\n",
+				analyzeTargetAsString(), parseErrors)
+		}
+	}
+
+	if msgOutputFormat != formatting.LogFormat {
+		return nil
+	}
+	// Return code is based on the unfiltered validation messages, as without diff mode
+	var added diag.Messages
+	for _, d := range diag.Diff(base.Messages, result.Messages) {
+		if d.Status != diag.DiffResolved {
+			added = append(added, d.Message)
+		}
+	}
+	if err := errorIfMessagesExceedThreshold(added); err != nil {
+		return err
+	}
+	if parseErrors > 0 && !ignoreUnknown {
+		return FileParseError{}
+	}
+	return nil
+}
+
 func gatherFiles(cmd *cobra.Command, args []string) ([]local.ReaderSource, error) {
 	var readers []local.ReaderSource
 	for _, f := range args {
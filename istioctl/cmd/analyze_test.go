@@ -15,11 +15,15 @@
 package cmd
 
 import (
+	"bytes"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
 
+	"istio.io/istio/istioctl/pkg/util/formatting"
 	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/local"
 )
 
 func TestErrorOnIssuesFound(t *testing.T) {
@@ -63,3 +67,19 @@ func TestNoErrorIfMessageLevelsBelowThreshold(t *testing.T) {
 
 	g.Expect(err).To(BeNil())
 }
+
+func TestAnalysisDiffParseErrors(t *testing.T) {
+	g := NewWithT(t)
+	defer func(format string) { msgOutputFormat = format }(msgOutputFormat)
+	msgOutputFormat = formatting.LogFormat
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(&bytes.Buffer{})
+	stderr := &bytes.Buffer{}
+	cmd.SetErr(stderr)
+
+	g.Expect(printAnalysisDiff(cmd, local.AnalysisResult{}, local.AnalysisResult{}, 0)).To(BeNil())
+	// Files of either tree that could not be parsed fail the analysis, as without diff mode.
+	g.Expect(printAnalysisDiff(cmd, local.AnalysisResult{}, local.AnalysisResult{}, 1)).To(BeIdenticalTo(FileParseError{}))
+	g.Expect(stderr.String()).To(ContainSubstring("1 file(s) could not be parsed"))
+}
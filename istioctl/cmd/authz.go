@@ -19,12 +19,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/istioctl/pkg/authz"
 	"istio.io/istio/istioctl/pkg/util/configdump"
 	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/kube"
 	"istio.io/pkg/log"
 )
@@ -140,6 +145,174 @@ func getConfigDumpFromPod(podName, podNamespace string) (*configdump.Wrapper, er
 	return envoyConfig, nil
 }
 
+func authzSimulateCmd() *cobra.Command {
+	var (
+		files          []string
+		meshConfigFile string
+		workloadLabels map[string]string
+		output         string
+		forTCP         bool
+
+		req             authz.Request
+		sourceNamespace string
+		headers         []string
+		claims          []string
+	)
+	cmd := &cobra.Command{
+		Use:   "simulate [<type>/]<name>[.<namespace>]",
+		Short: "Simulate the authorization of a request by the AuthorizationPolicies of a workload.",
+		Long: `Simulate evaluates a request against the AuthorizationPolicies applied to a workload, and prints
+whether it is allowed or denied and the policy rule responsible for the decision.
+
+The RBAC filters are built from the policies the same way as Istiod does, and the request is matched against
+them in the order enforced by Envoy: CUSTOM, DENY and then ALLOW. The providers of the matching CUSTOM
+policies are assumed to allow the request. The matching rules of dry-run policies are also printed.
+
+The policies are read from the cluster, or from files with --file. The workload is a pod, or the labels given
+with --workload-labels in the namespace given with --namespace.`,
+		Example: `  # Check if the frontend service account can get /api from a pod of the productpage deployment
+  istioctl x authz simulate deployment/productpage-v1 --source-principal cluster.local/ns/default/sa/frontend \
+    --method GET --path /api
+
+  # Check a request with a JWT against the policies of a file, and print the result as JSON
+  istioctl x authz simulate -f policies.yaml --workload-labels app=httpbin -n foo \
+    --request-principal https://issuer.example.com/user --claim groups=admin -o json
+
+  # Check a TCP connection from the bar namespace to port 3306
+  istioctl x authz simulate deployment/mysql --source-namespace bar --port 3306 --tcp`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("simulate requires only [<type>/]<name>[.<namespace>]")
+			}
+			if len(args) == 0 && workloadLabels == nil {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("expecting a pod name or --workload-labels")
+			}
+			if output != summaryOutput && output != jsonOutput {
+				return fmt.Errorf("unknown output format %q, expected %s or %s", output, summaryOutput, jsonOutput)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ns := handlers.HandleNamespace(namespace, defaultNamespace)
+			var client kube.CLIClient
+			if len(args) == 1 || len(files) == 0 || meshConfigFile == "" {
+				var err error
+				if client, err = kubeClient(kubeconfig, configContext); err != nil {
+					return fmt.Errorf("failed to create k8s client: %w", err)
+				}
+			}
+
+			workload := authz.Workload{Namespace: ns, Labels: workloadLabels}
+			if len(args) == 1 {
+				podName, podNamespace, err := handlers.InferPodInfoFromTypedResource(args[0], ns, client.UtilFactory())
+				if err != nil {
+					return err
+				}
+				pod, err := client.Kube().CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
+				if err != nil {
+					return fmt.Errorf("failed to get pod %s.%s: %v", podName, podNamespace, err)
+				}
+				workload = authz.Workload{Namespace: podNamespace, Labels: pod.Labels}
+			}
+
+			var policies []model.AuthorizationPolicy
+			if len(files) > 0 {
+				for _, f := range files {
+					b, err := os.ReadFile(f)
+					if err != nil {
+						return fmt.Errorf("failed to read %s: %v", f, err)
+					}
+					ps, err := authz.ParsePolicies(string(b), ns)
+					if err != nil {
+						return fmt.Errorf("failed to parse %s: %v", f, err)
+					}
+					policies = append(policies, ps...)
+				}
+			} else {
+				var err error
+				if policies, err = authz.ClusterPolicies(context.TODO(), client); err != nil {
+					return err
+				}
+			}
+
+			var mc *meshconfig.MeshConfig
+			var err error
+			if meshConfigFile != "" {
+				if mc, err = mesh.ReadMeshConfig(meshConfigFile); err != nil {
+					return fmt.Errorf("failed to read mesh config: %v", err)
+				}
+			} else if mc, err = getMeshConfig(client); err != nil {
+				return err
+			}
+
+			if sourceNamespace != "" && req.Principal == "" {
+				req.Principal = fmt.Sprintf("%s/ns/%s/sa/default", mc.GetTrustDomain(), sourceNamespace)
+			}
+			req.Headers = map[string]string{}
+			for _, h := range headers {
+				k, v, ok := strings.Cut(h, "=")
+				if !ok {
+					return fmt.Errorf("invalid header %q, expected <name>=<value>", h)
+				}
+				req.Headers[k] = v
+			}
+			req.Claims = map[string][]string{}
+			for _, c := range claims {
+				k, v, ok := strings.Cut(c, "=")
+				if !ok {
+					return fmt.Errorf("invalid claim %q, expected <name>=<value>", c)
+				}
+				req.Claims[k] = append(req.Claims[k], v)
+			}
+
+			res, err := authz.Simulate(policies, mc, workload, req, forTCP)
+			if err != nil {
+				return err
+			}
+			if output == jsonOutput {
+				return res.PrintJSON(cmd.OutOrStdout())
+			}
+			res.Print(cmd.OutOrStdout())
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringSliceVarP(&files, "file", "f", nil,
+		"Files with the AuthorizationPolicies to evaluate. If not set, the policies of the cluster are used")
+	cmd.PersistentFlags().StringVar(&meshConfigFile, "meshConfigFile", "",
+		"Mesh configuration filename. If not set, the mesh configuration of the cluster is used")
+	cmd.PersistentFlags().StringToStringVar(&workloadLabels, "workload-labels", nil,
+		"Labels of the workload receiving the request, when no pod is given")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", summaryOutput, "Output format: one of short|json")
+	cmd.PersistentFlags().BoolVar(&forTCP, "tcp", false, "Evaluate a TCP connection instead of a HTTP request")
+
+	cmd.PersistentFlags().StringVar(&req.Principal, "source-principal", "",
+		"Principal of the source workload, as <trust-domain>/ns/<namespace>/sa/<service-account>. "+
+			"If neither this nor --source-namespace is set, the request is plaintext")
+	cmd.PersistentFlags().StringVar(&sourceNamespace, "source-namespace", "",
+		"Namespace of the source workload, using its default service account as principal")
+	cmd.PersistentFlags().StringVar(&req.SourceIP, "source-ip", "", "IP address of the source")
+	cmd.PersistentFlags().StringVar(&req.RemoteIP, "remote-ip", "",
+		"Original IP address of the client, e.g. from X-Forwarded-For. Defaults to the source IP")
+	cmd.PersistentFlags().StringVar(&req.DestinationIP, "destination-ip", "", "Destination IP address of the request")
+	cmd.PersistentFlags().IntVar(&req.DestinationPort, "port", 80, "Destination port of the request")
+	cmd.PersistentFlags().StringVar(&req.SNI, "sni", "", "SNI of the request")
+	cmd.PersistentFlags().StringVar(&req.Host, "host", "", "Host header of the request")
+	cmd.PersistentFlags().StringVar(&req.Method, "method", "GET", "Method of the request")
+	cmd.PersistentFlags().StringVar(&req.Path, "path", "/", "Path of the request")
+	cmd.PersistentFlags().StringSliceVarP(&headers, "header", "H", nil, "Headers of the request, as <name>=<value>")
+	cmd.PersistentFlags().StringVar(&req.RequestPrincipal, "request-principal", "",
+		"Principal of the request JWT, as <iss>/<sub>")
+	cmd.PersistentFlags().StringVar(&req.Audiences, "audiences", "", "Audience of the request JWT")
+	cmd.PersistentFlags().StringVar(&req.Presenter, "presenter", "", "Authorized presenter of the request JWT")
+	cmd.PersistentFlags().StringArrayVar(&claims, "claim", nil,
+		"Top-level claim of the request JWT, as <name>=<value>. Repeat the flag for multiple values")
+
+	return cmd
+}
+
 // AuthZ groups commands used for inspecting and interacting the authorization policy.
 // Note: this is still under active development and is not ready for real use.
 func AuthZ() *cobra.Command {
@@ -149,6 +322,7 @@ func AuthZ() *cobra.Command {
 	}
 
 	cmd.AddCommand(checkCmd)
+	cmd.AddCommand(authzSimulateCmd())
 	cmd.Long += "\n\n" + ExperimentalMsg
 	return cmd
 }
@@ -0,0 +1,152 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+func endpointExplainCmd() *cobra.Command {
+	var (
+		opts    clioptions.ControlPlaneOptions
+		address string
+		cluster string
+		output  string
+	)
+	cmd := &cobra.Command{
+		Use:   "endpoint-explain <pod-name>[.<namespace>] --address <ip>",
+		Short: "Explain why an endpoint is, or is not, in the EDS of a pod",
+		Long: `Endpoint-explain asks the istiod the pod is connected to to trace the endpoints with an address through
+the generation of the EDS of the pod, and reports each decision that kept or dropped them: the visibility of
+the service, the cluster-local and discoverability policies, the service port, the subset labels, the health
+of the endpoint, the network and the locality it is sent in.
+
+Without --cluster, all the EDS clusters of the pod that have an endpoint with the address are explained.`,
+		Example: `  # Explain why 10.1.2.3 is, or is not, an endpoint of the clusters of a pod
+  istioctl x endpoint-explain productpage-v1-7f44c4d57c-2zpfd --address 10.1.2.3
+
+  # Explain the endpoint for the v1 subset of reviews
+  istioctl x endpoint-explain deployment/productpage-v1 --address 10.1.2.3 \
+    --cluster "outbound|9080|v1|reviews.default.svc.cluster.local"`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("endpoint-explain requires <pod-name>[.<namespace>]")
+			}
+			if address == "" {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("endpoint-explain requires --address")
+			}
+			if output != summaryOutput && output != jsonOutput {
+				return fmt.Errorf("unknown output format %q, expected %s or %s", output, summaryOutput, jsonOutput)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			podName, ns, err := handlers.InferPodInfoFromTypedResource(args[0],
+				handlers.HandleNamespace(namespace, defaultNamespace),
+				kubeClient.UtilFactory())
+			if err != nil {
+				return err
+			}
+			query := url.Values{}
+			query.Set("proxyID", podName+"."+ns)
+			query.Set("address", address)
+			if cluster != "" {
+				query.Set("cluster", cluster)
+			}
+			res, err := kubeClient.AllDiscoveryDo(context.TODO(), istioNamespace, "/debug/endpoint_explain?"+query.Encode())
+			if err != nil {
+				return err
+			}
+			explanations, err := parseEndpointExplanations(res)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", podName, ns, err)
+			}
+			if output == jsonOutput {
+				b, err := json.MarshalIndent(explanations, "", "  ")
+				if err != nil {
+					return err
+				}
+				_, err = fmt.Fprintln(cmd.OutOrStdout(), string(b))
+				return err
+			}
+			writeEndpointExplanations(cmd.OutOrStdout(), address, explanations)
+			return nil
+		},
+	}
+
+	opts.AttachControlPlaneFlags(cmd)
+	cmd.PersistentFlags().StringVar(&address, "address", "", "IP address of the endpoint to explain")
+	cmd.PersistentFlags().StringVar(&cluster, "cluster", "",
+		"Envoy cluster to explain the endpoint for. If not set, all the EDS clusters with the endpoint are explained")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", summaryOutput, "Output format: one of short|json")
+
+	return cmd
+}
+
+// parseEndpointExplanations returns the explanations of the istiod the proxy is connected to. The
+// other istiods respond with an error, which is not JSON.
+func parseEndpointExplanations(res map[string][]byte) ([]xds.EndpointExplanation, error) {
+	for _, b := range res {
+		var explanations []xds.EndpointExplanation
+		if err := json.Unmarshal(b, &explanations); err == nil {
+			return explanations, nil
+		}
+	}
+	return nil, fmt.Errorf("the proxy is not connected to any istiod")
+}
+
+func writeEndpointExplanations(out io.Writer, address string, explanations []xds.EndpointExplanation) {
+	if len(explanations) == 0 {
+		_, _ = fmt.Fprintf(out, "%s is not an endpoint of any EDS cluster of the proxy\n", address)
+		return
+	}
+	for i, e := range explanations {
+		if i > 0 {
+			_, _ = fmt.Fprintln(out)
+		}
+		status := "not sent"
+		if e.Sent {
+			status = "sent"
+		}
+		_, _ = fmt.Fprintf(out, "Cluster %s: %s\n", e.Cluster, status)
+		w := new(tabwriter.Writer).Init(out, 0, 8, 3, ' ', 0)
+		_, _ = fmt.Fprintln(w, "  ENDPOINT\tSTAGE\tDECISION\tREASON")
+		for _, d := range e.Decisions {
+			decision := "dropped"
+			if d.Kept {
+				decision = "kept"
+			}
+			_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", d.Endpoint, d.Stage, decision, d.Reason)
+		}
+		_ = w.Flush()
+	}
+}
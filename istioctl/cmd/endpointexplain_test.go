@@ -0,0 +1,49 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestEndpointExplanations(t *testing.T) {
+	res := map[string][]byte{
+		"istiod-1": []byte("Proxy not connected to this Pilot instance. It may be connected to another instance.\n"),
+		"istiod-2": []byte(`[{"cluster": "outbound|80|v1|reviews.default.svc.cluster.local", "sent": false, "decisions": [
+  {"endpoint": "10.1.2.3:9080", "stage": "subset", "kept": false, "reason": "the labels of the endpoint do not match"}]}]`),
+	}
+	explanations, err := parseEndpointExplanations(res)
+	assert.NoError(t, err)
+	assert.Equal(t, len(explanations), 1)
+
+	out := &bytes.Buffer{}
+	writeEndpointExplanations(out, "10.1.2.3", explanations)
+	assert.Equal(t, out.String(), `Cluster outbound|80|v1|reviews.default.svc.cluster.local: not sent
+  ENDPOINT        STAGE    DECISION   REASON
+  10.1.2.3:9080   subset   dropped    the labels of the endpoint do not match
+`)
+
+	out.Reset()
+	writeEndpointExplanations(out, "10.1.2.3", nil)
+	assert.Equal(t, out.String(), "10.1.2.3 is not an endpoint of any EDS cluster of the proxy\n")
+
+	_, err = parseEndpointExplanations(map[string][]byte{"istiod-1": res["istiod-1"]})
+	if err == nil {
+		t.Fatalf("expected an error when no istiod has the proxy")
+	}
+}
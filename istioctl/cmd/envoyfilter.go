@@ -0,0 +1,166 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/istioctl/pkg/envoyfilter"
+	"istio.io/istio/istioctl/pkg/simulate"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+// envoyFilterCmd groups commands used for inspecting the EnvoyFilters applied to proxies.
+func envoyFilterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "envoyfilter",
+		Short: "Inspect EnvoyFilters applied to proxies",
+	}
+	cmd.AddCommand(envoyFilterExplainCmd())
+	return cmd
+}
+
+func envoyFilterExplainCmd() *cobra.Command {
+	var (
+		files          []string
+		meshConfigFile string
+		proxyVersion   string
+		output         string
+	)
+	cmd := &cobra.Command{
+		Use:   "explain [<type>/]<name>[.<namespace>]",
+		Short: "Explain the effect of each EnvoyFilter patch on the configuration of a pod",
+		Long: `Explain generates the configuration of the proxy of a pod with and without each EnvoyFilter applied to
+it, adding the patches of the EnvoyFilter one at a time, and reports for each patch whether it was applied,
+the resources it changed, and whether it failed to apply. Patches that no longer match after an upgrade
+show up as not applied.
+
+The Istio configuration and Kubernetes objects are read from the cluster, or from files with --file. The
+configuration is generated for the Istio version of the proxy, which is read from the image tag of the
+istio-proxy container unless --proxy-version is set.`,
+		Example: `  # Explain the EnvoyFilters applied to a pod of the productpage deployment
+  istioctl x envoyfilter explain deployment/productpage-v1
+
+  # Explain the EnvoyFilters of a file for a 1.16 proxy, and print the changed fields as JSON
+  istioctl x envoyfilter explain productpage-v1-7f44c4d57c-2zpfd -f config.yaml --proxy-version 1.16.0 -o json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("explain requires <pod-name>[.<pod-namespace>]")
+			}
+			if output != summaryOutput && output != jsonOutput {
+				return fmt.Errorf("unknown output format %q, expected %s or %s", output, summaryOutput, jsonOutput)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %w", err)
+			}
+			podName, podNamespace, err := handlers.InferPodInfoFromTypedResource(args[0],
+				handlers.HandleNamespace(namespace, defaultNamespace),
+				client.UtilFactory())
+			if err != nil {
+				return err
+			}
+			pod, err := client.Kube().CoreV1().Pods(podNamespace).Get(context.TODO(), podName, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to get pod %s.%s: %v", podName, podNamespace, err)
+			}
+			proxy := proxyForPod(pod)
+			if proxyVersion != "" {
+				proxy.IstioVersion = proxyVersion
+			}
+
+			in := simulate.Input{}
+			if len(files) > 0 {
+				var configs []string
+				for _, f := range files {
+					b, err := os.ReadFile(f)
+					if err != nil {
+						return fmt.Errorf("failed to read %s: %v", f, err)
+					}
+					configs = append(configs, string(b))
+				}
+				in.Config = strings.Join(configs, "\n---\n")
+			} else {
+				if in.Config, in.KubernetesObjects, err = simulate.ClusterSnapshot(context.TODO(), client); err != nil {
+					return fmt.Errorf("failed to read the cluster configuration: %v", err)
+				}
+			}
+			if meshConfigFile != "" {
+				if in.MeshConfig, err = mesh.ReadMeshConfig(meshConfigFile); err != nil {
+					return fmt.Errorf("failed to read mesh config: %v", err)
+				}
+			} else if in.MeshConfig, err = getMeshConfig(client); err != nil {
+				return err
+			}
+
+			report, err := envoyfilter.Explain(in, proxy)
+			if err != nil {
+				return err
+			}
+			if output == jsonOutput {
+				return report.PrintJSON(cmd.OutOrStdout())
+			}
+			report.Print(cmd.OutOrStdout())
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringSliceVarP(&files, "file", "f", nil,
+		"Files with the Istio configuration and Kubernetes objects to generate the proxy configuration from. "+
+			"If not set, the configuration of the cluster is used")
+	cmd.PersistentFlags().StringVar(&meshConfigFile, "meshConfigFile", "",
+		"Mesh configuration filename. If not set, the mesh configuration of the cluster is used")
+	cmd.PersistentFlags().StringVar(&proxyVersion, "proxy-version", "",
+		"Istio version of the proxy, matched by the proxy version of EnvoyFilters. Defaults to the image tag of the proxy")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", summaryOutput, "Output format: one of short|json")
+
+	return cmd
+}
+
+// proxyForPod returns the proxy of the pod. The proxy is a router if the istio-proxy container runs a gateway.
+func proxyForPod(pod *corev1.Pod) simulate.Proxy {
+	proxy := simulate.Proxy{
+		Type:      model.SidecarProxy,
+		Namespace: pod.Namespace,
+		Labels:    pod.Labels,
+		IP:        pod.Status.PodIP,
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name != util.IstioProxyName {
+			continue
+		}
+		for _, arg := range c.Args {
+			if arg == string(model.Router) {
+				proxy.Type = model.Router
+			}
+		}
+		proxy.IstioVersion = util.ProxyImageVersion(c.Image)
+	}
+	return proxy
+}
@@ -27,6 +27,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/istioctl/pkg/writer/compare"
 	"istio.io/istio/istioctl/pkg/writer/envoy/clusters"
 	"istio.io/istio/istioctl/pkg/writer/envoy/configdump"
 	"istio.io/istio/pilot/pkg/model"
@@ -1217,6 +1218,84 @@ func rootCACompareConfigCmd() *cobra.Command {
 	return rootCACompareConfigCmd
 }
 
+func diffConfigCmd() *cobra.Command {
+	var files []string
+
+	diffConfigCmd := &cobra.Command{
+		Use:   "diff [[<type>/]<name-1>[.<namespace-1>]] [[<type>/]<name-2>[.<namespace-2>]]",
+		Short: "Compares the listeners, routes and clusters of two Envoy config dumps",
+		Long: `Compares the listeners, routes and clusters of the Envoy config dumps of two pods, of a pod and a saved
+config dump, or of two saved config dumps. The resources are compared field by field, ignoring their
+ordering, versions and update times, and the changed fields are reported for each resource.
+
+The config dumps read with --file are compared before the config dumps of the pods, in order.`,
+		Example: `  # Compare the Envoy config of a canary pod with the config of a stable pod.
+  istioctl proxy-config diff productpage-v1-6b746f74dc-9stvs productpage-v2-5b7b6c9c9d-2xqrm
+
+  # Save the Envoy config of a pod, and compare it later with the live config.
+  istioctl proxy-config all productpage-v1-6b746f74dc-9stvs -o json > before.json
+  istioctl proxy-config diff productpage-v1-6b746f74dc-9stvs --file before.json
+
+  # Compare two saved config dumps, and print the changed values as JSON.
+  istioctl proxy-config diff --file before.json --file after.json -o json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args)+len(files) != 2 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("diff requires two pods or --file parameters in total")
+			}
+			if outputFormat != summaryOutput && outputFormat != jsonOutput {
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var names []string
+			var dumps [][]byte
+			for _, f := range files {
+				dump, err := readFile(f)
+				if err != nil {
+					return err
+				}
+				names = append(names, f)
+				dumps = append(dumps, dump)
+			}
+			for _, arg := range args {
+				podName, podNamespace, err := getPodName(arg)
+				if err != nil {
+					return err
+				}
+				dump, err := extractConfigDump(podName, podNamespace, false)
+				if err != nil {
+					return err
+				}
+				names = append(names, fmt.Sprintf("%s.%s", podName, podNamespace))
+				dumps = append(dumps, dump)
+			}
+
+			comparator, err := compare.NewDumpComparator(c.OutOrStdout(), names[0], dumps[0], names[1], dumps[1])
+			if err != nil {
+				return err
+			}
+			diffs, err := comparator.Diff()
+			if err != nil {
+				return err
+			}
+			if outputFormat == jsonOutput {
+				return comparator.PrintJSON(diffs)
+			}
+			comparator.Print(diffs)
+			return nil
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
+
+	diffConfigCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|short")
+	diffConfigCmd.PersistentFlags().StringSliceVarP(&files, "file", "f", nil,
+		"Envoy config dump JSON file, compared before the config dumps of the pods")
+
+	return diffConfigCmd
+}
+
 func proxyConfig() *cobra.Command {
 	configCmd := &cobra.Command{
 		Use:   "proxy-config",
@@ -1239,6 +1318,7 @@ func proxyConfig() *cobra.Command {
 	configCmd.AddCommand(edsConfigCmd())
 	configCmd.AddCommand(secretConfigCmd())
 	configCmd.AddCommand(rootCACompareConfigCmd())
+	configCmd.AddCommand(diffConfigCmd())
 
 	return configCmd
 }
@@ -239,6 +239,9 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(preCheck())
 	experimentalCmd.AddCommand(statsConfigCmd())
 	experimentalCmd.AddCommand(checkInjectCommand())
+	experimentalCmd.AddCommand(simulateCmd())
+	experimentalCmd.AddCommand(envoyFilterCmd())
+	experimentalCmd.AddCommand(endpointExplainCmd())
 
 	analyzeCmd := Analyze()
 	hideInheritedFlags(analyzeCmd, FlagIstioNamespace)
@@ -0,0 +1,175 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/simulate"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/simulation"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func simulateCmd() *cobra.Command {
+	var (
+		files          []string
+		fromCluster    bool
+		meshConfigFile string
+		output         string
+
+		proxyType      string
+		proxyNamespace string
+		proxyLabels    map[string]string
+		proxyIP        string
+
+		call    simulation.Call
+		mode    string
+		headers []string
+	)
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate how a request is handled by a proxy",
+		Long: `Simulate generates the configuration of a proxy from Istio configuration and Kubernetes objects, and
+predicts how a request is handled by it: the listener, filter chain, virtual host, route and cluster matched,
+and whether mTLS is used.
+
+The configuration is read from files with --file, or from the cluster with --from-cluster. No proxy or
+Istiod is needed.`,
+		Example: `  # Simulate a request from a sidecar in the default namespace to the reviews service
+  istioctl x simulate -f config.yaml --host reviews.default.svc.cluster.local --port 9080 --path /reviews/1
+
+  # Simulate a request to an ingress gateway, using the configuration of the cluster
+  istioctl x simulate --from-cluster --proxy-type router --proxy-namespace istio-system \
+    --proxy-labels istio=ingressgateway --mode gateway --host bookinfo.example.com --port 80
+
+  # Simulate a request received by a workload, and print the result as JSON
+  istioctl x simulate -f config.yaml --proxy-labels app=reviews --proxy-ip 10.0.0.1 \
+    --mode inbound --address 10.0.0.1 --port 9080 --tls mtls -o json`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("simulate does not take arguments")
+			}
+			if len(files) == 0 && !fromCluster {
+				return fmt.Errorf("expecting configuration files with --file, or --from-cluster")
+			}
+			if output != summaryOutput && output != jsonOutput {
+				return fmt.Errorf("unknown output format %q, expected %s or %s", output, summaryOutput, jsonOutput)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := simulate.Input{}
+			var configs []string
+			for _, f := range files {
+				b, err := os.ReadFile(f)
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %v", f, err)
+				}
+				configs = append(configs, string(b))
+			}
+			if fromCluster {
+				client, err := kubeClient(kubeconfig, configContext)
+				if err != nil {
+					return fmt.Errorf("failed to create k8s client: %v", err)
+				}
+				cfg, objects, err := simulate.ClusterSnapshot(context.TODO(), client)
+				if err != nil {
+					return fmt.Errorf("failed to read the cluster configuration: %v", err)
+				}
+				configs = append(configs, cfg)
+				in.KubernetesObjects = objects
+				if meshConfigFile == "" {
+					if in.MeshConfig, err = getMeshConfig(client); err != nil {
+						return err
+					}
+				}
+			}
+			in.Config = strings.Join(configs, "\n---\n")
+			if meshConfigFile != "" {
+				m, err := mesh.ReadMeshConfig(meshConfigFile)
+				if err != nil {
+					return fmt.Errorf("failed to read mesh config: %v", err)
+				}
+				in.MeshConfig = m
+			}
+
+			proxy := simulate.Proxy{
+				Type:      model.NodeType(proxyType),
+				Namespace: proxyNamespace,
+				Labels:    proxyLabels,
+				IP:        proxyIP,
+			}
+			if !model.IsApplicationNodeType(proxy.Type) {
+				return fmt.Errorf("unknown proxy type %q, expected %s or %s", proxyType, model.SidecarProxy, model.Router)
+			}
+
+			call.CallMode = simulation.CallMode(mode)
+			call.Headers = http.Header{}
+			for _, h := range headers {
+				k, v, ok := strings.Cut(h, "=")
+				if !ok {
+					return fmt.Errorf("invalid header %q, expected <name>=<value>", h)
+				}
+				call.Headers.Add(k, v)
+			}
+
+			out, err := simulate.Run(in, proxy, call)
+			if err != nil {
+				return err
+			}
+			if output == jsonOutput {
+				return out.PrintJSON(cmd.OutOrStdout())
+			}
+			out.Print(cmd.OutOrStdout())
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringSliceVarP(&files, "file", "f", nil,
+		"Files with the Istio configuration and Kubernetes objects to generate the proxy configuration from")
+	cmd.PersistentFlags().BoolVar(&fromCluster, "from-cluster", false,
+		"Read the Istio configuration and Kubernetes objects from the cluster")
+	cmd.PersistentFlags().StringVar(&meshConfigFile, "meshConfigFile", "",
+		"Mesh configuration filename. If not set, the mesh configuration of the cluster is used with --from-cluster, "+
+			"and the default mesh configuration otherwise")
+	cmd.PersistentFlags().StringVarP(&output, "output", "o", summaryOutput, "Output format: one of short|json")
+
+	cmd.PersistentFlags().StringVar(&proxyType, "proxy-type", string(model.SidecarProxy), "Type of the proxy: sidecar or router")
+	cmd.PersistentFlags().StringVar(&proxyNamespace, "proxy-namespace", "default", "Namespace of the proxy")
+	cmd.PersistentFlags().StringToStringVar(&proxyLabels, "proxy-labels", nil, "Labels of the proxy workload")
+	cmd.PersistentFlags().StringVar(&proxyIP, "proxy-ip", "", "IP address of the proxy")
+
+	cmd.PersistentFlags().StringVar(&call.HostHeader, "host", "", "Host header of the request")
+	cmd.PersistentFlags().StringVar(&call.Address, "address", "", "Destination IP address of the request")
+	cmd.PersistentFlags().IntVar(&call.Port, "port", 80, "Destination port of the request")
+	cmd.PersistentFlags().StringVar(&call.Path, "path", "/", "Path of the request")
+	cmd.PersistentFlags().StringSliceVarP(&headers, "header", "H", nil, "Headers of the request, as <name>=<value>")
+	cmd.PersistentFlags().StringVar((*string)(&call.Protocol), "protocol", string(simulation.HTTP), "Protocol of the request: http, http2 or tcp")
+	cmd.PersistentFlags().StringVar((*string)(&call.TLS), "tls", string(simulation.Plaintext), "TLS mode of the request: plaintext, tls or mtls")
+	cmd.PersistentFlags().StringVar(&call.Sni, "sni", "", "SNI of the request. Defaults to the host header for TLS requests")
+	cmd.PersistentFlags().StringVar(&call.Alpn, "alpn", "", "ALPN of the request")
+	cmd.PersistentFlags().StringVar(&mode, "mode", string(simulation.CallModeOutbound),
+		"How the request reaches the proxy: outbound, inbound or gateway")
+
+	return cmd
+}
@@ -0,0 +1,326 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	routepb "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	matcher "github.com/envoyproxy/go-control-plane/envoy/type/matcher/v3"
+
+	authn "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/spiffe"
+)
+
+// peerPrincipalKey is the filter state holding the principal of the peer certificate.
+const peerPrincipalKey = "io.istio.peer_principal"
+
+// Request describes a request to evaluate against the RBAC filters of a workload.
+type Request struct {
+	// Principal is the identity of the source workload, as <trust-domain>/ns/<namespace>/sa/<service-account>.
+	// An empty principal is a plaintext request.
+	Principal string `json:"principal,omitempty"`
+	// SourceIP is the IP address of the downstream connection.
+	SourceIP string `json:"sourceIP,omitempty"`
+	// RemoteIP is the original client IP address, defaults to SourceIP.
+	RemoteIP        string `json:"remoteIP,omitempty"`
+	DestinationIP   string `json:"destinationIP,omitempty"`
+	DestinationPort int    `json:"destinationPort,omitempty"`
+	SNI             string `json:"sni,omitempty"`
+
+	Host    string            `json:"host,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// RequestPrincipal is the principal of the request JWT, as <iss>/<sub>.
+	RequestPrincipal string              `json:"requestPrincipal,omitempty"`
+	Audiences        string              `json:"audiences,omitempty"`
+	Presenter        string              `json:"presenter,omitempty"`
+	Claims           map[string][]string `json:"claims,omitempty"`
+}
+
+// matchingPolicies returns the sorted names of the policies of the RBAC config matching the request.
+func (r *Request) matchingPolicies(rbac *rbacpb.RBAC) []string {
+	var names []string
+	for name, p := range rbac.GetPolicies() {
+		if r.matchPolicy(p) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Request) matchPolicy(p *rbacpb.Policy) bool {
+	permission := false
+	for _, perm := range p.GetPermissions() {
+		if r.matchPermission(perm) {
+			permission = true
+			break
+		}
+	}
+	if !permission {
+		return false
+	}
+	for _, principal := range p.GetPrincipals() {
+		if r.matchPrincipal(principal) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Request) matchPermission(p *rbacpb.Permission) bool {
+	switch rule := p.GetRule().(type) {
+	case *rbacpb.Permission_Any:
+		return rule.Any
+	case *rbacpb.Permission_AndRules:
+		for _, sub := range rule.AndRules.GetRules() {
+			if !r.matchPermission(sub) {
+				return false
+			}
+		}
+		return true
+	case *rbacpb.Permission_OrRules:
+		for _, sub := range rule.OrRules.GetRules() {
+			if r.matchPermission(sub) {
+				return true
+			}
+		}
+		return false
+	case *rbacpb.Permission_NotRule:
+		return !r.matchPermission(rule.NotRule)
+	case *rbacpb.Permission_Header:
+		return r.matchHeader(rule.Header)
+	case *rbacpb.Permission_UrlPath:
+		path, _, _ := strings.Cut(r.Path, "?")
+		return matchString(rule.UrlPath.GetPath(), path, true)
+	case *rbacpb.Permission_DestinationIp:
+		return matchCidr(rule.DestinationIp, r.DestinationIP)
+	case *rbacpb.Permission_DestinationPort:
+		return int(rule.DestinationPort) == r.DestinationPort
+	case *rbacpb.Permission_RequestedServerName:
+		return matchString(rule.RequestedServerName, r.SNI, r.SNI != "")
+	}
+	// Envoy metadata and the other permissions are not generated from the request attributes
+	return false
+}
+
+func (r *Request) matchPrincipal(p *rbacpb.Principal) bool {
+	switch id := p.GetIdentifier().(type) {
+	case *rbacpb.Principal_Any:
+		return id.Any
+	case *rbacpb.Principal_AndIds:
+		for _, sub := range id.AndIds.GetIds() {
+			if !r.matchPrincipal(sub) {
+				return false
+			}
+		}
+		return true
+	case *rbacpb.Principal_OrIds:
+		for _, sub := range id.OrIds.GetIds() {
+			if r.matchPrincipal(sub) {
+				return true
+			}
+		}
+		return false
+	case *rbacpb.Principal_NotId:
+		return !r.matchPrincipal(id.NotId)
+	case *rbacpb.Principal_Authenticated_:
+		if r.Principal == "" {
+			return false
+		}
+		return id.Authenticated.GetPrincipalName() == nil ||
+			matchString(id.Authenticated.GetPrincipalName(), spiffe.URIPrefix+r.Principal, true)
+	case *rbacpb.Principal_FilterState:
+		if id.FilterState.GetKey() != peerPrincipalKey || r.Principal == "" {
+			return false
+		}
+		return matchString(id.FilterState.GetStringMatch(), spiffe.URIPrefix+r.Principal, true)
+	case *rbacpb.Principal_DirectRemoteIp:
+		return matchCidr(id.DirectRemoteIp, r.SourceIP)
+	case *rbacpb.Principal_SourceIp:
+		return matchCidr(id.SourceIp, r.SourceIP)
+	case *rbacpb.Principal_RemoteIp:
+		remote := r.RemoteIP
+		if remote == "" {
+			remote = r.SourceIP
+		}
+		return matchCidr(id.RemoteIp, remote)
+	case *rbacpb.Principal_Header:
+		return r.matchHeader(id.Header)
+	case *rbacpb.Principal_UrlPath:
+		path, _, _ := strings.Cut(r.Path, "?")
+		return matchString(id.UrlPath.GetPath(), path, true)
+	case *rbacpb.Principal_Metadata:
+		return r.matchMetadata(id.Metadata)
+	}
+	return false
+}
+
+func (r *Request) header(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case ":method":
+		return r.Method, r.Method != ""
+	case ":authority", "host":
+		return r.Host, r.Host != ""
+	case ":path":
+		return r.Path, r.Path != ""
+	}
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (r *Request) matchHeader(h *routepb.HeaderMatcher) bool {
+	v, present := r.header(h.GetName())
+	var matched bool
+	switch m := h.GetHeaderMatchSpecifier().(type) {
+	case *routepb.HeaderMatcher_ExactMatch:
+		matched = present && v == m.ExactMatch
+	case *routepb.HeaderMatcher_PrefixMatch:
+		matched = present && strings.HasPrefix(v, m.PrefixMatch)
+	case *routepb.HeaderMatcher_SuffixMatch:
+		matched = present && strings.HasSuffix(v, m.SuffixMatch)
+	case *routepb.HeaderMatcher_ContainsMatch:
+		matched = present && strings.Contains(v, m.ContainsMatch)
+	case *routepb.HeaderMatcher_SafeRegexMatch:
+		matched = present && matchRegex(m.SafeRegexMatch.GetRegex(), v)
+	case *routepb.HeaderMatcher_PresentMatch:
+		matched = present == m.PresentMatch
+	case *routepb.HeaderMatcher_StringMatch:
+		matched = matchString(m.StringMatch, v, present)
+	default:
+		matched = present
+	}
+	if h.GetInvertMatch() {
+		return !matched
+	}
+	return matched
+}
+
+// matchMetadata matches the metadata of the Istio authentication filter, holding the attributes of the request JWT.
+func (r *Request) matchMetadata(m *matcher.MetadataMatcher) bool {
+	if m.GetFilter() != authn.AuthnFilterName || len(m.GetPath()) == 0 {
+		return false
+	}
+	matched := false
+	switch m.GetPath()[0].GetKey() {
+	case "request.auth.principal":
+		matched = matchValue(m.GetValue(), r.RequestPrincipal, r.RequestPrincipal != "")
+	case "request.auth.audiences":
+		matched = matchValue(m.GetValue(), r.Audiences, r.Audiences != "")
+	case "request.auth.presenter":
+		matched = matchValue(m.GetValue(), r.Presenter, r.Presenter != "")
+	case "request.auth.claims":
+		if len(m.GetPath()) == 2 {
+			values, present := r.Claims[m.GetPath()[1].GetKey()]
+			matched = matchListValue(m.GetValue(), values, present)
+		}
+	}
+	if m.GetInvert() {
+		return !matched
+	}
+	return matched
+}
+
+func matchValue(m *matcher.ValueMatcher, v string, present bool) bool {
+	switch p := m.GetMatchPattern().(type) {
+	case *matcher.ValueMatcher_StringMatch:
+		return matchString(p.StringMatch, v, present)
+	case *matcher.ValueMatcher_PresentMatch:
+		return present == p.PresentMatch
+	}
+	return false
+}
+
+func matchListValue(m *matcher.ValueMatcher, values []string, present bool) bool {
+	list, ok := m.GetMatchPattern().(*matcher.ValueMatcher_ListMatch)
+	if !ok {
+		if len(values) == 1 {
+			return matchValue(m, values[0], present)
+		}
+		return false
+	}
+	for _, v := range values {
+		if matchValue(list.ListMatch.GetOneOf(), v, true) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchString(m *matcher.StringMatcher, v string, present bool) bool {
+	if !present {
+		return false
+	}
+	if m.GetIgnoreCase() {
+		v = strings.ToLower(v)
+	}
+	lower := func(s string) string {
+		if m.GetIgnoreCase() {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	switch p := m.GetMatchPattern().(type) {
+	case *matcher.StringMatcher_Exact:
+		return v == lower(p.Exact)
+	case *matcher.StringMatcher_Prefix:
+		return strings.HasPrefix(v, lower(p.Prefix))
+	case *matcher.StringMatcher_Suffix:
+		return strings.HasSuffix(v, lower(p.Suffix))
+	case *matcher.StringMatcher_Contains:
+		return strings.Contains(v, lower(p.Contains))
+	case *matcher.StringMatcher_SafeRegex:
+		return matchRegex(p.SafeRegex.GetRegex(), v)
+	}
+	return false
+}
+
+// matchRegex matches the full value, as done by Envoy.
+func matchRegex(regex, v string) bool {
+	re, err := regexp.Compile("^(?:" + regex + ")$")
+	return err == nil && re.MatchString(v)
+}
+
+func matchCidr(cidr *core.CidrRange, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	base, err := netip.ParseAddr(cidr.GetAddressPrefix())
+	if err != nil {
+		return false
+	}
+	bits := base.BitLen()
+	if cidr.GetPrefixLen() != nil {
+		bits = int(cidr.GetPrefixLen().GetValue())
+	}
+	prefix, err := base.Prefix(bits)
+	if err != nil {
+		return false
+	}
+	return prefix.Contains(addr)
+}
@@ -0,0 +1,269 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
+	rbactcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	authpb "istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/security/authz/builder"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	Allow = "ALLOW"
+	Deny  = "DENY"
+)
+
+// Workload is the workload receiving the simulated request.
+type Workload struct {
+	Namespace string
+	Labels    map[string]string
+}
+
+// PolicyMatch is a rule of an AuthorizationPolicy matching the simulated request.
+type PolicyMatch struct {
+	// Policy is the name of the policy, as <name>.<namespace>.
+	Policy   string `json:"policy"`
+	Rule     string `json:"rule"`
+	Action   string `json:"action"`
+	Provider string `json:"provider,omitempty"`
+}
+
+func (m PolicyMatch) String() string {
+	s := fmt.Sprintf("rule[%s] of %s policy %s", m.Rule, m.Action, m.Policy)
+	if m.Provider != "" {
+		s += fmt.Sprintf(" (provider %s)", m.Provider)
+	}
+	return s
+}
+
+// Result is the authorization decision of the simulated request.
+type Result struct {
+	// Decision is either ALLOW or DENY.
+	Decision string `json:"decision"`
+	Reason   string `json:"reason"`
+	// Policy is the rule the decision is made by, nil if the decision is made because no rule matched.
+	Policy *PolicyMatch `json:"policy,omitempty"`
+	// Custom are the rules of CUSTOM policies matching the request. The request is checked by the providers of
+	// these rules before the DENY and ALLOW policies, and the providers are assumed to allow it.
+	Custom []PolicyMatch `json:"custom,omitempty"`
+	// DryRun are the rules of dry-run policies matching the request. They do not change the decision.
+	DryRun []PolicyMatch `json:"dryRun,omitempty"`
+}
+
+// rbacConfig is the config of a RBAC filter built for the workload.
+type rbacConfig struct {
+	rules       *rbacpb.RBAC
+	shadowRules *rbacpb.RBAC
+}
+
+// Simulate evaluates the request against the AuthorizationPolicies applied to the workload. The RBAC filters are
+// built with the same builder used by Istiod, and the request is matched against them in the order enforced by
+// the proxy: CUSTOM, DENY and then ALLOW.
+func Simulate(policies []model.AuthorizationPolicy, mc *meshconfig.MeshConfig, workload Workload, req Request, forTCP bool) (*Result, error) {
+	authzPolicies := &model.AuthorizationPolicies{
+		NamespaceToPolicies: map[string][]model.AuthorizationPolicy{},
+		RootNamespace:       mc.GetRootNamespace(),
+	}
+	providers := map[string]string{}
+	for _, p := range policies {
+		authzPolicies.NamespaceToPolicies[p.Namespace] = append(authzPolicies.NamespaceToPolicies[p.Namespace], p)
+		if p.Spec.GetAction() == authpb.AuthorizationPolicy_CUSTOM {
+			providers[fmt.Sprintf("%s.%s", p.Name, p.Namespace)] = p.Spec.GetProvider().GetName()
+		}
+	}
+	applied := authzPolicies.ListAuthorizationPolicies(workload.Namespace, workload.Labels)
+	bundle := trustdomain.NewBundle(mc.GetTrustDomain(), mc.GetTrustDomainAliases())
+
+	res := &Result{}
+	// The CUSTOM policies are built as DENY rules, as done by the CUSTOM builder, so that the ext_authz filter of
+	// the provider is not needed.
+	custom, err := build(builder.New(bundle, nil, model.AuthorizationPoliciesResult{Deny: applied.Custom}, builder.Option{}), forTCP)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range custom {
+		for _, name := range req.matchingPolicies(c.rules) {
+			m := toPolicyMatch(name, authpb.AuthorizationPolicy_CUSTOM.String())
+			m.Provider = providers[m.Policy]
+			res.Custom = append(res.Custom, m)
+		}
+		for _, name := range req.matchingPolicies(c.shadowRules) {
+			m := toPolicyMatch(name, authpb.AuthorizationPolicy_CUSTOM.String())
+			m.Provider = providers[m.Policy]
+			res.DryRun = append(res.DryRun, m)
+		}
+	}
+
+	applied.Custom = nil
+	configs, err := build(builder.New(bundle, nil, applied, builder.Option{}), forTCP)
+	if err != nil {
+		return nil, err
+	}
+	var denied, allowed []PolicyMatch
+	hasAllow := false
+	for _, c := range configs {
+		for _, name := range req.matchingPolicies(c.shadowRules) {
+			res.DryRun = append(res.DryRun, toPolicyMatch(name, c.shadowRules.GetAction().String()))
+		}
+		if c.rules == nil {
+			// All the policies of the filter are dry-run
+			continue
+		}
+		switch c.rules.GetAction() {
+		case rbacpb.RBAC_DENY:
+			for _, name := range req.matchingPolicies(c.rules) {
+				denied = append(denied, toPolicyMatch(name, Deny))
+			}
+		case rbacpb.RBAC_ALLOW:
+			hasAllow = true
+			for _, name := range req.matchingPolicies(c.rules) {
+				allowed = append(allowed, toPolicyMatch(name, Allow))
+			}
+		}
+	}
+
+	switch {
+	case len(denied) > 0:
+		res.Decision, res.Policy = Deny, &denied[0]
+		res.Reason = "denied by " + denied[0].String()
+	case !hasAllow:
+		res.Decision, res.Reason = Allow, "no ALLOW policy applied to the workload"
+	case len(allowed) > 0:
+		res.Decision, res.Policy = Allow, &allowed[0]
+		res.Reason = "allowed by " + allowed[0].String()
+	default:
+		res.Decision, res.Reason = Deny, "no ALLOW policy matched"
+	}
+	return res, nil
+}
+
+// build returns the configs of the RBAC filters built by the builder.
+func build(b *builder.Builder, forTCP bool) ([]rbacConfig, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var configs []rbacConfig
+	if forTCP {
+		for _, filter := range b.BuildTCP() {
+			if filter.Name != wellknown.RoleBasedAccessControl {
+				continue
+			}
+			rbac := &rbactcp.RBAC{}
+			if err := getFilterConfig(filter, rbac); err != nil {
+				return nil, fmt.Errorf("failed to get RBAC config: %v", err)
+			}
+			configs = append(configs, rbacConfig{rules: rbac.Rules, shadowRules: rbac.ShadowRules})
+		}
+		return configs, nil
+	}
+	for _, filter := range b.BuildHTTP() {
+		if filter.Name != wellknown.HTTPRoleBasedAccessControl {
+			continue
+		}
+		rbac := &rbachttp.RBAC{}
+		if err := getHTTPFilterConfig(filter, rbac); err != nil {
+			return nil, fmt.Errorf("failed to get RBAC config: %v", err)
+		}
+		configs = append(configs, rbacConfig{rules: rbac.Rules, shadowRules: rbac.ShadowRules})
+	}
+	return configs, nil
+}
+
+func toPolicyMatch(name, action string) PolicyMatch {
+	policy, rule := extractName(name)
+	return PolicyMatch{Policy: policy, Rule: rule, Action: action}
+}
+
+// Print writes the result in a human readable form.
+func (r *Result) Print(w io.Writer) {
+	fmt.Fprintf(w, "%-10s%s\n", "Decision:", r.Decision)
+	fmt.Fprintf(w, "%-10s%s\n", "Reason:", r.Reason)
+	for _, m := range r.Custom {
+		fmt.Fprintf(w, "%-10s%s\n", "Custom:", m)
+	}
+	for _, m := range r.DryRun {
+		fmt.Fprintf(w, "%-10s%s\n", "Dry-run:", m)
+	}
+}
+
+// PrintJSON writes the result as JSON.
+func (r *Result) PrintJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// ParsePolicies returns the AuthorizationPolicies in the multi-document YAML. The other resources are ignored.
+// Policies without a namespace are put in the default namespace.
+func ParsePolicies(input, defaultNamespace string) ([]model.AuthorizationPolicy, error) {
+	configs, _, err := crd.ParseInputs(input)
+	if err != nil {
+		return nil, err
+	}
+	var policies []model.AuthorizationPolicy
+	for _, c := range configs {
+		if c.GroupVersionKind != gvk.AuthorizationPolicy {
+			continue
+		}
+		if c.Namespace == "" {
+			c.Namespace = defaultNamespace
+		}
+		policies = append(policies, model.AuthorizationPolicy{
+			Name:        c.Name,
+			Namespace:   c.Namespace,
+			Annotations: c.Annotations,
+			Spec:        c.Spec.(*authpb.AuthorizationPolicy),
+		})
+	}
+	return policies, nil
+}
+
+// ClusterPolicies returns the AuthorizationPolicies of all the namespaces of the cluster.
+func ClusterPolicies(ctx context.Context, client kube.Client) ([]model.AuthorizationPolicy, error) {
+	l, err := client.Istio().SecurityV1beta1().AuthorizationPolicies(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AuthorizationPolicies: %v", err)
+	}
+	var policies []model.AuthorizationPolicy
+	for i := range l.Items {
+		p := &l.Items[i]
+		policies = append(policies, model.AuthorizationPolicy{
+			Name:        p.Name,
+			Namespace:   p.Namespace,
+			Annotations: p.Annotations,
+			Spec:        &p.Spec,
+		})
+	}
+	return policies, nil
+}
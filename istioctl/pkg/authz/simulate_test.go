@@ -0,0 +1,219 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const policies = `
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: deny-admin
+  namespace: istio-system
+spec:
+  action: DENY
+  rules:
+  - to:
+    - operation:
+        paths: ["/admin/*"]
+    from:
+    - source:
+        notNamespaces: ["ops"]
+---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: allow-frontend
+  namespace: foo
+spec:
+  selector:
+    matchLabels:
+      app: backend
+  rules:
+  - from:
+    - source:
+        principals: ["cluster.local/ns/foo/sa/frontend"]
+    to:
+    - operation:
+        methods: ["GET"]
+  - from:
+    - source:
+        namespaces: ["ops"]
+  - when:
+    - key: request.auth.claims[groups]
+      values: ["admin"]
+---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: deny-legacy
+  namespace: foo
+  annotations:
+    istio.io/dry-run: "true"
+spec:
+  action: DENY
+  rules:
+  - from:
+    - source:
+        ipBlocks: ["10.1.0.0/16"]
+---
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: ext-authz
+  namespace: foo
+spec:
+  action: CUSTOM
+  provider:
+    name: opa
+  rules:
+  - to:
+    - operation:
+        hosts: ["*.example.com"]
+`
+
+func TestSimulate(t *testing.T) {
+	ps, err := ParsePolicies(policies, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mc := &meshconfig.MeshConfig{RootNamespace: "istio-system", TrustDomain: "cluster.local"}
+	backend := Workload{Namespace: "foo", Labels: map[string]string{"app": "backend"}}
+
+	cases := []struct {
+		name     string
+		workload Workload
+		req      Request
+		forTCP   bool
+		want     Result
+	}{
+		{
+			name:     "allowed by principal",
+			workload: backend,
+			req:      Request{Principal: "cluster.local/ns/foo/sa/frontend", Method: "GET", Path: "/api"},
+			want: Result{
+				Decision: Allow,
+				Reason:   "allowed by rule[0] of ALLOW policy allow-frontend.foo",
+				Policy:   &PolicyMatch{Policy: "allow-frontend.foo", Rule: "0", Action: Allow},
+			},
+		},
+		{
+			name:     "method not allowed",
+			workload: backend,
+			req:      Request{Principal: "cluster.local/ns/foo/sa/frontend", Method: "POST", Path: "/api"},
+			want:     Result{Decision: Deny, Reason: "no ALLOW policy matched"},
+		},
+		{
+			name:     "denied by root namespace policy",
+			workload: backend,
+			req:      Request{Principal: "cluster.local/ns/foo/sa/frontend", Method: "GET", Path: "/admin/users"},
+			want: Result{
+				Decision: Deny,
+				Reason:   "denied by rule[0] of DENY policy deny-admin.istio-system",
+				Policy:   &PolicyMatch{Policy: "deny-admin.istio-system", Rule: "0", Action: Deny},
+			},
+		},
+		{
+			name:     "excluded from deny",
+			workload: backend,
+			req:      Request{Principal: "cluster.local/ns/ops/sa/default", Method: "GET", Path: "/admin/users"},
+			want: Result{
+				Decision: Allow,
+				Reason:   "allowed by rule[1] of ALLOW policy allow-frontend.foo",
+				Policy:   &PolicyMatch{Policy: "allow-frontend.foo", Rule: "1", Action: Allow},
+			},
+		},
+		{
+			name:     "jwt claim",
+			workload: backend,
+			req:      Request{Method: "POST", Path: "/api", Claims: map[string][]string{"groups": {"dev", "admin"}}},
+			want: Result{
+				Decision: Allow,
+				Reason:   "allowed by rule[2] of ALLOW policy allow-frontend.foo",
+				Policy:   &PolicyMatch{Policy: "allow-frontend.foo", Rule: "2", Action: Allow},
+			},
+		},
+		{
+			name:     "custom and dry-run",
+			workload: backend,
+			req:      Request{Principal: "cluster.local/ns/foo/sa/frontend", Method: "GET", Host: "api.example.com", SourceIP: "10.1.2.3"},
+			want: Result{
+				Decision: Allow,
+				Reason:   "allowed by rule[0] of ALLOW policy allow-frontend.foo",
+				Policy:   &PolicyMatch{Policy: "allow-frontend.foo", Rule: "0", Action: Allow},
+				Custom:   []PolicyMatch{{Policy: "ext-authz.foo", Rule: "0", Action: "CUSTOM", Provider: "opa"}},
+				DryRun:   []PolicyMatch{{Policy: "deny-legacy.foo", Rule: "0", Action: Deny}},
+			},
+		},
+		{
+			name:     "no allow policy",
+			workload: Workload{Namespace: "bar"},
+			req:      Request{Method: "POST", Path: "/api"},
+			want:     Result{Decision: Allow, Reason: "no ALLOW policy applied to the workload"},
+		},
+		{
+			name:     "tcp",
+			workload: backend,
+			req:      Request{Principal: "cluster.local/ns/ops/sa/default", DestinationPort: 3306},
+			forTCP:   true,
+			want: Result{
+				Decision: Allow,
+				Reason:   "allowed by rule[1] of ALLOW policy allow-frontend.foo",
+				Policy:   &PolicyMatch{Policy: "allow-frontend.foo", Rule: "1", Action: Allow},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Simulate(ps, mc, tc.workload, tc.req, tc.forTCP)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, *got, tc.want)
+		})
+	}
+}
+
+func TestResultPrint(t *testing.T) {
+	r := &Result{
+		Decision: Deny,
+		Reason:   "denied by rule[0] of DENY policy deny-admin.istio-system",
+		Policy:   &PolicyMatch{Policy: "deny-admin.istio-system", Rule: "0", Action: Deny},
+		Custom:   []PolicyMatch{{Policy: "ext-authz.foo", Rule: "0", Action: "CUSTOM", Provider: "opa"}},
+	}
+	out := &bytes.Buffer{}
+	r.Print(out)
+	assert.Equal(t, out.String(), `Decision: DENY
+Reason:   denied by rule[0] of DENY policy deny-admin.istio-system
+Custom:   rule[0] of CUSTOM policy ext-authz.foo (provider opa)
+`)
+
+	out.Reset()
+	if err := r.PrintJSON(out); err != nil {
+		t.Fatal(err)
+	}
+	got := &Result{}
+	if err := json.Unmarshal(out.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, got, r)
+}
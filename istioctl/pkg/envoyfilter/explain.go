@@ -0,0 +1,326 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package envoyfilter explains the effect of the EnvoyFilters applied to a proxy. The config of the proxy is
+// generated with and without each EnvoyFilter, adding its patches one at a time, and the generated configs
+// are compared to find the resources changed by each patch.
+package envoyfilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"sigs.k8s.io/yaml"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/istioctl/pkg/simulate"
+	protodiff "istio.io/istio/istioctl/pkg/util/proto"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/xds"
+)
+
+// PatchStatus is the outcome of a patch of an EnvoyFilter.
+type PatchStatus string
+
+const (
+	// Applied means that the patch changed the config of the proxy.
+	Applied PatchStatus = "applied"
+	// NotApplied means that the patch did not match any resource of the proxy, or did not change it.
+	NotApplied PatchStatus = "not-applied"
+	// Failed means that the patch could not be applied. Istiod discards the patch, or all the patches of the
+	// EnvoyFilter for the resources it failed on.
+	Failed PatchStatus = "failed"
+)
+
+// Change is a resource of the proxy config changed by an EnvoyFilter.
+type Change struct {
+	// Type is the type of the resource: Listener, Cluster or RouteConfiguration.
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Action is added, removed or modified.
+	Action string `json:"action"`
+	// Fields are the fields changed in a modified resource.
+	Fields []protodiff.FieldDiff `json:"fields,omitempty"`
+}
+
+// PatchReport is the outcome of a config patch of an EnvoyFilter.
+type PatchReport struct {
+	Index     int         `json:"index"`
+	ApplyTo   string      `json:"applyTo"`
+	Operation string      `json:"operation"`
+	Status    PatchStatus `json:"status"`
+	Error     string      `json:"error,omitempty"`
+	Changes   []Change    `json:"changes,omitempty"`
+}
+
+// FilterReport is the outcome of an EnvoyFilter applied to the proxy.
+type FilterReport struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Changes are the resources changed by all the patches of the EnvoyFilter.
+	Changes []Change      `json:"changes,omitempty"`
+	Patches []PatchReport `json:"patches"`
+}
+
+// Report is the outcome of the EnvoyFilters applied to a proxy.
+type Report struct {
+	Filters []FilterReport `json:"filters"`
+}
+
+// Explain generates the config of the proxy with and without each EnvoyFilter applied to it, and reports the
+// changes made by each patch. The patches of an EnvoyFilter are added one at a time, after the other
+// EnvoyFilters, so that the changes of a patch include its interactions with the patches before it.
+func Explain(in simulate.Input, proxy simulate.Proxy) (*Report, error) {
+	input, filters, err := splitEnvoyFilters(in.Config)
+	if err != nil {
+		return nil, err
+	}
+	var others []config.Config
+	for _, c := range in.Configs {
+		if c.GroupVersionKind == gvk.EnvoyFilter {
+			filters = append(filters, c)
+		} else {
+			others = append(others, c)
+		}
+	}
+	generate := func(filters []config.Config) (*simulate.Config, error) {
+		gin := in
+		gin.Config = input
+		gin.Configs = append(append([]config.Config{}, others...), filters...)
+		return simulate.Generate(gin, proxy)
+	}
+
+	rootNamespace := mesh.DefaultMeshConfig().GetRootNamespace()
+	if in.MeshConfig != nil {
+		rootNamespace = in.MeshConfig.GetRootNamespace()
+	}
+	full, err := generate(filters)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{}
+	for i, ef := range filters {
+		if !appliesTo(ef, proxy, rootNamespace) {
+			continue
+		}
+		rest := append(append([]config.Config{}, filters[:i]...), filters[i+1:]...)
+		without, err := generate(rest)
+		if err != nil {
+			return nil, err
+		}
+		fr := FilterReport{Name: ef.Name, Namespace: ef.Namespace}
+		if fr.Changes, err = diffConfigs(without, full); err != nil {
+			return nil, err
+		}
+
+		spec := ef.Spec.(*networking.EnvoyFilter)
+		prev := without
+		for j, cp := range spec.ConfigPatches {
+			pr := PatchReport{Index: j, ApplyTo: cp.GetApplyTo().String(), Operation: cp.GetPatch().GetOperation().String()}
+			cur := full
+			if j < len(spec.ConfigPatches)-1 {
+				partial := ef.DeepCopy()
+				partial.Spec.(*networking.EnvoyFilter).ConfigPatches = partial.Spec.(*networking.EnvoyFilter).ConfigPatches[:j+1]
+				if cur, err = generate(append(append([]config.Config{}, rest...), partial)); err != nil {
+					return nil, err
+				}
+			}
+			if pr.Changes, err = diffConfigs(prev, cur); err != nil {
+				return nil, err
+			}
+			pr.Status, pr.Error = patchStatus(cp, pr.Changes, prev, without)
+			fr.Patches = append(fr.Patches, pr)
+			prev = cur
+		}
+		report.Filters = append(report.Filters, fr)
+	}
+	return report, nil
+}
+
+func patchStatus(cp *networking.EnvoyFilter_EnvoyConfigObjectPatch, changes []Change, prev, without *simulate.Config) (PatchStatus, string) {
+	if cp.GetPatch() == nil {
+		return Failed, "missing patch"
+	}
+	// Validation is not enforced for EnvoyFilters created without the validation webhook
+	if _, err := xds.BuildXDSObjectFromStruct(cp.ApplyTo, cp.Patch.Value, false); err != nil {
+		return Failed, err.Error()
+	}
+	if reverted(changes, prev, without) {
+		return Failed, "the patch failed, so the patches of the EnvoyFilter were not applied to the changed resources"
+	}
+	if len(changes) > 0 {
+		return Applied, ""
+	}
+	return NotApplied, ""
+}
+
+// splitEnvoyFilters returns the multi-document YAML without the EnvoyFilters, and the EnvoyFilters. The
+// EnvoyFilters are not validated, so that the patches failing to apply can be reported.
+func splitEnvoyFilters(input string) (string, []config.Config, error) {
+	var docs []string
+	var filters []config.Config
+	for _, doc := range strings.Split(input, "\n---") {
+		obj := crd.IstioKind{}
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil || obj.Kind != gvk.EnvoyFilter.Kind ||
+			obj.GroupVersionKind().Group != gvk.EnvoyFilter.Group {
+			docs = append(docs, doc)
+			continue
+		}
+		c, err := crd.ConvertObject(collections.IstioNetworkingV1Alpha3Envoyfilters, &obj, "")
+		if err != nil {
+			return "", nil, fmt.Errorf("cannot parse EnvoyFilter %v: %v", obj.Name, err)
+		}
+		if c.Namespace == "" {
+			c.Namespace = "default"
+		}
+		filters = append(filters, *c)
+	}
+	return strings.Join(docs, "\n---"), filters, nil
+}
+
+// appliesTo returns true if the EnvoyFilter selects the proxy.
+func appliesTo(ef config.Config, proxy simulate.Proxy, rootNamespace string) bool {
+	if ef.Namespace != rootNamespace && ef.Namespace != proxy.Namespace {
+		return false
+	}
+	selector := ef.Spec.(*networking.EnvoyFilter).GetWorkloadSelector().GetLabels()
+	return labels.Instance(selector).SubsetOf(proxy.Labels)
+}
+
+// reverted returns true if the changes only reset resources previously changed by the EnvoyFilter to their
+// config without the EnvoyFilter. This happens when a patch fails, and the other patches of the EnvoyFilter
+// are discarded for the resources it was applied to.
+func reverted(changes []Change, prev, without *simulate.Config) bool {
+	if len(changes) == 0 {
+		return false
+	}
+	prevResources, withoutResources := resources(prev), resources(without)
+	for _, c := range changes {
+		key := c.Type + "/" + c.Name
+		p, w := prevResources[key], withoutResources[key]
+		if p == nil || w == nil || proto.Equal(p, w) {
+			return false
+		}
+	}
+	return true
+}
+
+func resources(c *simulate.Config) map[string]proto.Message {
+	out := map[string]proto.Message{}
+	for _, l := range c.Listeners {
+		out["Listener/"+l.Name] = l
+	}
+	for _, cl := range c.Clusters {
+		out["Cluster/"+cl.Name] = cl
+	}
+	for _, r := range c.Routes {
+		out["RouteConfiguration/"+r.Name] = r
+	}
+	return out
+}
+
+// diffConfigs returns the resources changed between the configs, sorted by type and name.
+func diffConfigs(before, after *simulate.Config) ([]Change, error) {
+	b, a := resources(before), resources(after)
+	keys := map[string]struct{}{}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	var changes []Change
+	for k := range keys {
+		typ, name, _ := strings.Cut(k, "/")
+		c := Change{Type: typ, Name: name}
+		switch {
+		case b[k] == nil:
+			c.Action = "added"
+		case a[k] == nil:
+			c.Action = "removed"
+		case proto.Equal(b[k], a[k]):
+			continue
+		default:
+			c.Action = "modified"
+			fields, err := protodiff.Diff(b[k], a[k])
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare %s: %v", k, err)
+			}
+			c.Fields = fields
+		}
+		changes = append(changes, c)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Type != changes[j].Type {
+			return changes[i].Type < changes[j].Type
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+// Print writes the report in a human readable form. The values of the changed fields are only part of the
+// JSON output.
+func (r *Report) Print(w io.Writer) {
+	if len(r.Filters) == 0 {
+		fmt.Fprintln(w, "No EnvoyFilter applies to the proxy.")
+		return
+	}
+	for _, f := range r.Filters {
+		fmt.Fprintf(w, "EnvoyFilter %s/%s:\n", f.Namespace, f.Name)
+		for _, p := range f.Patches {
+			fmt.Fprintf(w, "  patch #%d %s %s: %s", p.Index, p.ApplyTo, p.Operation, p.Status)
+			if p.Error != "" {
+				fmt.Fprintf(w, " (%s)", p.Error)
+			}
+			fmt.Fprintln(w)
+			for _, c := range p.Changes {
+				fmt.Fprintf(w, "    %s %s %s\n", c.Action, c.Type, c.Name)
+				for _, field := range c.Fields {
+					fmt.Fprintf(w, "      %s: %s\n", field.Path, fieldAction(field))
+				}
+			}
+		}
+	}
+}
+
+func fieldAction(f protodiff.FieldDiff) string {
+	switch {
+	case f.Before == nil:
+		return "added"
+	case f.After == nil:
+		return "removed"
+	default:
+		return "modified"
+	}
+}
+
+// PrintJSON writes the report as JSON.
+func (r *Report) PrintJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
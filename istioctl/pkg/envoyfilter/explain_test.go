@@ -0,0 +1,128 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoyfilter
+
+import (
+	"testing"
+
+	"istio.io/istio/istioctl/pkg/simulate"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const testConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: timeouts
+  namespace: default
+spec:
+  configPatches:
+  - applyTo: CLUSTER
+    match:
+      cluster:
+        service: example.com
+    patch:
+      operation: MERGE
+      value:
+        connect_timeout: 7s
+  - applyTo: CLUSTER
+    match:
+      cluster:
+        service: unknown.com
+    patch:
+      operation: MERGE
+      value:
+        connect_timeout: 8s
+  - applyTo: CLUSTER
+    match:
+      cluster:
+        service: example.com
+    patch:
+      operation: MERGE
+      value:
+        connect_timeout: invalid
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: other-workload
+  namespace: default
+spec:
+  workloadSelector:
+    labels:
+      app: other
+  configPatches:
+  - applyTo: CLUSTER
+    patch:
+      operation: MERGE
+      value:
+        connect_timeout: 9s
+`
+
+func TestExplain(t *testing.T) {
+	proxy := simulate.Proxy{Type: model.SidecarProxy, Namespace: "default", Labels: map[string]string{"app": "test"}}
+	report, err := Explain(simulate.Input{Config: testConfig}, proxy)
+	assert.NoError(t, err)
+
+	assert.Equal(t, len(report.Filters), 1)
+	f := report.Filters[0]
+	assert.Equal(t, f.Name, "timeouts")
+	assert.Equal(t, len(f.Patches), 3)
+
+	assert.Equal(t, f.Patches[0].Status, Applied)
+	assert.Equal(t, len(f.Patches[0].Changes), 1)
+	c := f.Patches[0].Changes[0]
+	assert.Equal(t, c.Type, "Cluster")
+	assert.Equal(t, c.Name, "outbound|80||example.com")
+	assert.Equal(t, c.Action, "modified")
+	assert.Equal(t, len(c.Fields), 1)
+	assert.Equal(t, c.Fields[0].Path, "connectTimeout")
+	assert.Equal(t, c.Fields[0].After, any("7s"))
+
+	assert.Equal(t, f.Patches[1].Status, NotApplied)
+	assert.Equal(t, len(f.Patches[1].Changes), 0)
+
+	assert.Equal(t, f.Patches[2].Status, Failed)
+	if f.Patches[2].Error == "" {
+		t.Fatalf("expected an error for patch #2")
+	}
+}
+
+func TestSplitEnvoyFilters(t *testing.T) {
+	rest, filters, err := splitEnvoyFilters(testConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, len(filters), 2)
+	assert.Equal(t, filters[0].Name, "timeouts")
+	assert.Equal(t, filters[1].Name, "other-workload")
+
+	_, others, err := splitEnvoyFilters(rest)
+	assert.NoError(t, err)
+	assert.Equal(t, len(others), 0)
+}
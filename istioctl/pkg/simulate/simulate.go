@@ -0,0 +1,341 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package simulate predicts how a request is handled by a proxy, without a running proxy or cluster.
+// The proxy config is generated from Istio config and Kubernetes objects with a fake discovery server,
+// and the request is matched against it with the traffic simulation of pilot/pkg/simulation.
+package simulate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/simulation"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test"
+)
+
+// Proxy describes the proxy the request is sent through.
+type Proxy struct {
+	// Type is the type of the proxy, sidecar or router.
+	Type model.NodeType
+	// Namespace is the namespace of the proxy.
+	Namespace string
+	// Labels are the labels of the proxy workload. They select the Sidecar, Gateway and policies applied.
+	Labels map[string]string
+	// IP is the IP address of the proxy. Inbound listeners are generated for the services whose endpoints
+	// have this IP address.
+	IP string
+	// IstioVersion is the version of the proxy, matched by the proxy version of EnvoyFilters. If not set, the
+	// version of the fake discovery server is used.
+	IstioVersion string
+}
+
+// Input is the config the proxy config is generated from.
+type Input struct {
+	// Config holds the Istio config and Kubernetes objects, as a multi-document YAML.
+	Config string
+	// Configs is additional Istio config.
+	Configs []config.Config
+	// KubernetesObjects are additional Kubernetes objects, such as the ones read from a cluster.
+	KubernetesObjects []runtime.Object
+	// MeshConfig is the mesh config. If not set, the default mesh config is used.
+	MeshConfig *meshconfig.MeshConfig
+}
+
+// Output is the result of a simulated request.
+type Output struct {
+	Listener    string `json:"listener,omitempty"`
+	FilterChain string `json:"filterChain,omitempty"`
+	RouteConfig string `json:"routeConfig,omitempty"`
+	VirtualHost string `json:"virtualHost,omitempty"`
+	Route       string `json:"route,omitempty"`
+	Cluster     string `json:"cluster,omitempty"`
+	// DownstreamTLS is the TLS mode the matched filter chain accepts: plaintext, tls or mtls.
+	DownstreamTLS string `json:"downstreamTLS,omitempty"`
+	// UpstreamTLS is the TLS mode used to connect to the endpoints of the cluster: plaintext, tls, mtls or
+	// auto-mtls, when mTLS is only used for the endpoints with an Istio proxy.
+	UpstreamTLS string `json:"upstreamTLS,omitempty"`
+	// Error is set if the request is not routed to a cluster.
+	Error string `json:"error,omitempty"`
+}
+
+// Config is the xDS config generated for a proxy.
+type Config struct {
+	Listeners []*listener.Listener
+	Clusters  []*cluster.Cluster
+	Routes    []*route.RouteConfiguration
+}
+
+// Run generates the config of the proxy from the input, and simulates the call against it.
+func Run(in Input, proxy Proxy, call simulation.Call) (*Output, error) {
+	var out *Output
+	err := withSimulation(in, proxy, func(sim *simulation.Simulation) {
+		out = toOutput(sim, sim.Run(call))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Generate generates the config of the proxy from the input.
+func Generate(in Input, proxy Proxy) (*Config, error) {
+	var out *Config
+	err := withSimulation(in, proxy, func(sim *simulation.Simulation) {
+		out = &Config{Listeners: sim.Listeners, Clusters: sim.Clusters, Routes: sim.Routes}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// withSimulation generates the config of the proxy from the input with a fake discovery server, and calls f
+// with the simulation of the proxy. The failures of the generation are returned as an error.
+func withSimulation(in Input, proxy Proxy, f func(sim *simulation.Simulation)) error {
+	objects, err := kubernetesObjects(in.Config)
+	if err != nil {
+		return err
+	}
+	objects = append(objects, in.KubernetesObjects...)
+
+	err = test.Wrap(func(t test.Failer) {
+		s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{
+			Configs:           in.Configs,
+			ConfigString:      in.Config,
+			KubernetesObjects: objects,
+			MeshConfig:        in.MeshConfig,
+		})
+		f(simulation.NewSimulation(t, s, s.SetupProxy(toModelProxy(proxy))))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to generate the proxy config: %v", err)
+	}
+	return nil
+}
+
+func toModelProxy(p Proxy) *model.Proxy {
+	mp := &model.Proxy{
+		Type:            p.Type,
+		ConfigNamespace: p.Namespace,
+		Labels:          p.Labels,
+		Metadata: &model.NodeMetadata{
+			Labels:       p.Labels,
+			Namespace:    p.Namespace,
+			IstioVersion: p.IstioVersion,
+		},
+	}
+	if p.IP != "" {
+		mp.IPAddresses = []string{p.IP}
+	}
+	return mp
+}
+
+// kubernetesObjects decodes the Kubernetes objects of a multi-document YAML. Other documents, such as
+// Istio config, are skipped.
+func kubernetesObjects(config string) ([]runtime.Object, error) {
+	var objects []runtime.Object
+	decode := scheme.Codecs.UniversalDeserializer().Decode
+	for _, doc := range strings.Split(config, "\n---") {
+		if strings.TrimSpace(doc) == "" {
+			continue
+		}
+		o, _, err := decode([]byte(doc), nil, nil)
+		if err != nil {
+			if runtime.IsNotRegisteredError(err) || runtime.IsMissingKind(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to decode kubernetes object: %v", err)
+		}
+		objects = append(objects, o)
+	}
+	return objects, nil
+}
+
+func toOutput(sim *simulation.Simulation, r simulation.Result) *Output {
+	out := &Output{
+		Listener:    r.ListenerMatched,
+		FilterChain: r.FilterChainMatched,
+		RouteConfig: r.RouteConfigMatched,
+		VirtualHost: r.VirtualHostMatched,
+		Route:       r.RouteMatched,
+		Cluster:     r.ClusterMatched,
+	}
+	if r.Error != nil {
+		out.Error = r.Error.Error()
+	}
+	if fc := findFilterChain(sim.Listeners, r.ListenerMatched, r.FilterChainMatched); fc != nil {
+		out.DownstreamTLS = downstreamTLS(fc)
+	}
+	for _, c := range sim.Clusters {
+		if c.Name == r.ClusterMatched {
+			out.UpstreamTLS = upstreamTLS(c)
+			break
+		}
+	}
+	return out
+}
+
+func findFilterChain(listeners []*listener.Listener, listenerName, filterChainName string) *listener.FilterChain {
+	for _, l := range listeners {
+		if l.Name != listenerName {
+			continue
+		}
+		if l.DefaultFilterChain != nil && l.DefaultFilterChain.Name == filterChainName {
+			return l.DefaultFilterChain
+		}
+		for _, fc := range l.FilterChains {
+			if fc.Name == filterChainName {
+				return fc
+			}
+		}
+	}
+	return nil
+}
+
+func downstreamTLS(fc *listener.FilterChain) string {
+	if fc.TransportSocket == nil {
+		return string(simulation.Plaintext)
+	}
+	ctx := &tls.DownstreamTlsContext{}
+	if err := fc.TransportSocket.GetTypedConfig().UnmarshalTo(ctx); err != nil {
+		return string(simulation.TLS)
+	}
+	if ctx.GetRequireClientCertificate().GetValue() {
+		return string(simulation.MTLS)
+	}
+	return string(simulation.TLS)
+}
+
+func upstreamTLS(c *cluster.Cluster) string {
+	for _, m := range c.TransportSocketMatches {
+		if m.Name == "tlsMode-"+model.IstioMutualTLSModeLabel {
+			return "auto-mtls"
+		}
+	}
+	if c.TransportSocket == nil || c.TransportSocket.GetTypedConfig() == nil {
+		return string(simulation.Plaintext)
+	}
+	ctx := &tls.UpstreamTlsContext{}
+	if err := c.TransportSocket.GetTypedConfig().UnmarshalTo(ctx); err != nil {
+		return string(simulation.Plaintext)
+	}
+	if len(ctx.GetCommonTlsContext().GetTlsCertificateSdsSecretConfigs()) > 0 ||
+		len(ctx.GetCommonTlsContext().GetTlsCertificates()) > 0 {
+		return string(simulation.MTLS)
+	}
+	return string(simulation.TLS)
+}
+
+// Print writes the output in a human readable form.
+func (o *Output) Print(w io.Writer) {
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "%-15s%s\n", name+":", value)
+		}
+	}
+	field("Listener", o.Listener)
+	field("Filter chain", o.FilterChain)
+	field("Downstream TLS", o.DownstreamTLS)
+	field("Route config", o.RouteConfig)
+	field("Virtual host", o.VirtualHost)
+	field("Route", o.Route)
+	field("Cluster", o.Cluster)
+	field("Upstream TLS", o.UpstreamTLS)
+	field("Error", o.Error)
+}
+
+// PrintJSON writes the output as JSON.
+func (o *Output) PrintJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// ClusterSnapshot reads the Istio config and the Kubernetes objects the proxy config is generated from
+// out of a cluster. The Istio config is returned as a multi-document YAML.
+func ClusterSnapshot(ctx context.Context, client kube.Client) (string, []runtime.Object, error) {
+	var docs []string
+	for _, s := range collections.PilotGatewayAPI.All() {
+		gvr := s.Resource().GroupVersionResource()
+		l, err := client.Dynamic().Resource(gvr).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				// The CRD is not installed
+				continue
+			}
+			return "", nil, fmt.Errorf("failed to list %v: %v", gvr, err)
+		}
+		for _, item := range l.Items {
+			b, err := yaml.Marshal(item.Object)
+			if err != nil {
+				return "", nil, err
+			}
+			docs = append(docs, string(b))
+		}
+	}
+
+	var objects []runtime.Object
+	namespaces, err := client.Kube().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	for i := range namespaces.Items {
+		objects = append(objects, &namespaces.Items[i])
+	}
+	services, err := client.Kube().CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	for i := range services.Items {
+		objects = append(objects, &services.Items[i])
+	}
+	pods, err := client.Kube().CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	for i := range pods.Items {
+		objects = append(objects, &pods.Items[i])
+	}
+	endpoints, err := client.Kube().CoreV1().Endpoints(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+	for i := range endpoints.Items {
+		objects = append(objects, &endpoints.Items[i])
+	}
+	return strings.Join(docs, "---\n"), objects, nil
+}
@@ -0,0 +1,176 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulate
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/simulation"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const testConfig = `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: default
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  addresses:
+  - 1.2.3.4
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 2.3.4.5
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: vs
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  http:
+  - match:
+    - uri:
+        prefix: /admin
+    fault:
+      abort:
+        httpStatus: 403
+        percentage:
+          value: 100
+    route:
+    - destination:
+        host: example.com
+    name: admin
+  - route:
+    - destination:
+        host: example.com
+`
+
+func TestRun(t *testing.T) {
+	proxy := Proxy{Type: model.SidecarProxy, Namespace: "default"}
+	out, err := Run(Input{Config: testConfig}, proxy, simulation.Call{
+		Port:       80,
+		HostHeader: "example.com",
+		Protocol:   simulation.HTTP,
+		CallMode:   simulation.CallModeOutbound,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, out.Cluster, "outbound|80||example.com")
+	assert.Equal(t, out.VirtualHost, "example.com:80")
+	assert.Equal(t, out.UpstreamTLS, "auto-mtls")
+	assert.Equal(t, out.Error, "")
+
+	out, err = Run(Input{Config: testConfig}, proxy, simulation.Call{
+		Port:       80,
+		Path:       "/admin",
+		HostHeader: "example.com",
+		Protocol:   simulation.HTTP,
+		CallMode:   simulation.CallModeOutbound,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, out.Route, "admin")
+
+	out, err = Run(Input{Config: testConfig}, proxy, simulation.Call{
+		Port:       80,
+		HostHeader: "unknown.com",
+		Protocol:   simulation.HTTP,
+		CallMode:   simulation.CallModeOutbound,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, out.Cluster, "PassthroughCluster")
+	assert.Equal(t, out.UpstreamTLS, "plaintext")
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, out.PrintJSON(buf))
+	got := &Output{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), got))
+	assert.Equal(t, got, out)
+}
+
+func TestRunExpectations(t *testing.T) {
+	proxy := Proxy{Type: model.SidecarProxy, Namespace: "default"}
+	expect := func(cluster string) []simulation.Expect {
+		return []simulation.Expect{{
+			Name: "example",
+			Call: simulation.Call{
+				Port:       80,
+				HostHeader: "example.com",
+				Protocol:   simulation.HTTP,
+				CallMode:   simulation.CallModeOutbound,
+			},
+			Result: simulation.Result{ClusterMatched: cluster},
+		}}
+	}
+	// Simulations created by istioctl are not backed by a *testing.T, mismatches are returned as errors.
+	assert.NoError(t, withSimulation(Input{Config: testConfig}, proxy, func(sim *simulation.Simulation) {
+		sim.RunExpectations(expect("outbound|80||example.com"))
+	}))
+	assert.Error(t, withSimulation(Input{Config: testConfig}, proxy, func(sim *simulation.Simulation) {
+		sim.RunExpectations(expect("PassthroughCluster"))
+	}))
+}
+
+func TestGenerate(t *testing.T) {
+	out, err := Generate(Input{Config: testConfig}, Proxy{Type: model.SidecarProxy, Namespace: "default"})
+	assert.NoError(t, err)
+	clusters := map[string]bool{}
+	for _, c := range out.Clusters {
+		clusters[c.Name] = true
+	}
+	assert.Equal(t, clusters["outbound|80||example.com"], true)
+	routes := map[string]bool{}
+	for _, r := range out.Routes {
+		routes[r.Name] = true
+	}
+	assert.Equal(t, routes["80"], true)
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	_, err := Run(Input{Config: `
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: vs
+  namespace: default
+spec:
+  http:
+  - route:
+    - destination:
+        host: example.com
+`}, Proxy{Type: model.SidecarProxy, Namespace: "default"}, simulation.Call{Port: 80})
+	assert.Error(t, err)
+}
+
+func TestKubernetesObjects(t *testing.T) {
+	objects, err := kubernetesObjects(testConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, len(objects), 1)
+}
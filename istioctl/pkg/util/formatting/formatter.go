@@ -30,13 +30,14 @@ import (
 
 // Formatting options for Messages
 const (
-	LogFormat  = "log"
-	JSONFormat = "json"
-	YAMLFormat = "yaml"
+	LogFormat   = "log"
+	JSONFormat  = "json"
+	YAMLFormat  = "yaml"
+	SARIFFormat = "sarif"
 )
 
 var (
-	MsgOutputFormatKeys = []string{LogFormat, JSONFormat, YAMLFormat}
+	MsgOutputFormatKeys = []string{LogFormat, JSONFormat, YAMLFormat, SARIFFormat}
 	MsgOutputFormats    = make(map[string]bool)
 	termEnvVar          = env.Register("TERM", "", "Specifies terminal type.  Use 'dumb' to suppress color output")
 )
@@ -56,11 +57,48 @@ func Print(ms diag.Messages, format string, colorize bool) (string, error) {
 		return printJSON(ms)
 	case YAMLFormat:
 		return printYAML(ms)
+	case SARIFFormat:
+		return printSARIF(ms)
 	default:
 		return "", fmt.Errorf("invalid format, expected one of %v but got %q", MsgOutputFormatKeys, format)
 	}
 }
 
+// PrintDiff prints the differences between the messages of two analyses in the specified format with color options
+func PrintDiff(diffs []diag.MessageDiff, format string, colorize bool) (string, error) {
+	switch format {
+	case LogFormat:
+		return printDiffLog(diffs, colorize), nil
+	case JSONFormat:
+		jsonOutput, err := json.MarshalIndent(diffs, "", "\t")
+		return string(jsonOutput), err
+	case YAMLFormat:
+		yamlOutput, err := yaml.Marshal(diffs)
+		return string(yamlOutput), err
+	case SARIFFormat:
+		return printDiffSARIF(diffs)
+	default:
+		return "", fmt.Errorf("invalid format, expected one of %v but got %q", MsgOutputFormatKeys, format)
+	}
+}
+
+var diffPrefixes = map[diag.DiffStatus]string{
+	diag.DiffNew:      "+",
+	diag.DiffResolved: "-",
+	diag.DiffChanged:  "~",
+}
+
+func printDiffLog(diffs []diag.MessageDiff, colorize bool) string {
+	var logOutput []string
+	for _, d := range diffs {
+		logOutput = append(logOutput, diffPrefixes[d.Status]+" "+render(d.Message, colorize))
+		if d.Previous != nil {
+			logOutput = append(logOutput, "  was: "+render(*d.Previous, colorize))
+		}
+	}
+	return strings.Join(logOutput, "\n")
+}
+
 func printLog(ms diag.Messages, colorize bool) string {
 	var logOutput []string
 	for _, m := range ms {
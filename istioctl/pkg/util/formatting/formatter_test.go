@@ -15,11 +15,14 @@
 package formatting
 
 import (
+	"encoding/json"
 	"testing"
 
 	. "github.com/onsi/gomega"
 
 	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/legacy/source/kube"
+	"istio.io/istio/pkg/config/resource"
 	"istio.io/istio/pkg/url"
 )
 
@@ -152,3 +155,106 @@ func TestFormatter_PrintEmpty(t *testing.T) {
 	yamlOutput, _ := Print(msgs, YAMLFormat, false)
 	g.Expect(yamlOutput).To(Equal("[]\n"))
 }
+
+func TestFormatter_PrintSARIF(t *testing.T) {
+	g := NewWithT(t)
+
+	firstMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		&resource.Instance{
+			Origin: &kube.Origin{
+				Kind:     "SoapBubble",
+				FullName: resource.NewFullName("default", "bubble"),
+				Ref:      &kube.Position{Filename: "bubbles.yaml", Line: 3},
+			},
+		},
+		"the bubble is too big",
+	)
+	firstMsg.Line = 7
+	secondMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Info, "C1", "Collapse danger: %v"),
+		nil,
+		"the castle is too old",
+	)
+
+	output, err := Print(diag.Messages{firstMsg, secondMsg}, SARIFFormat, false)
+	g.Expect(err).To(BeNil())
+
+	log := sarifLog{}
+	g.Expect(json.Unmarshal([]byte(output), &log)).To(Succeed())
+	g.Expect(log.Version).To(Equal("2.1.0"))
+	g.Expect(log.Runs).To(HaveLen(1))
+	g.Expect(log.Runs[0].Tool.Driver.Rules).To(Equal([]sarifRule{
+		{ID: "B1", HelpURI: url.ConfigAnalysis + "/b1/"},
+		{ID: "C1", HelpURI: url.ConfigAnalysis + "/c1/"},
+	}))
+	g.Expect(log.Runs[0].Results).To(Equal([]sarifResult{
+		{
+			RuleID:  "B1",
+			Level:   "error",
+			Message: sarifMessage{Text: "Explosion accident: the bubble is too big"},
+			Locations: []sarifLocation{{
+				PhysicalLocation: &sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: "bubbles.yaml"},
+					Region:           &sarifRegion{StartLine: 7},
+				},
+				LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "SoapBubble default/bubble"}},
+			}},
+		},
+		{
+			RuleID:  "C1",
+			Level:   "note",
+			Message: sarifMessage{Text: "Collapse danger: the castle is too old"},
+		},
+	}))
+}
+
+func TestFormatter_PrintDiff(t *testing.T) {
+	g := NewWithT(t)
+
+	newMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Error, "B1", "Explosion accident: %v"),
+		diag.MockResource("SoapBubble"),
+		"the bubble is too big",
+	)
+	resolvedMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Warning, "C1", "Collapse danger: %v"),
+		diag.MockResource("GrandCastle"),
+		"the castle is too old",
+	)
+	changedMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Warning, "C1", "Collapse danger: %v"),
+		diag.MockResource("SandCastle"),
+		"the tide is high",
+	)
+	previousMsg := diag.NewMessage(
+		diag.NewMessageType(diag.Warning, "C1", "Collapse danger: %v"),
+		diag.MockResource("SandCastle"),
+		"the tide is rising",
+	)
+	diffs := []diag.MessageDiff{
+		{Status: diag.DiffNew, Message: newMsg},
+		{Status: diag.DiffChanged, Message: changedMsg, Previous: &previousMsg},
+		{Status: diag.DiffResolved, Message: resolvedMsg},
+	}
+
+	output, _ := PrintDiff(diffs, LogFormat, false)
+	g.Expect(output).To(Equal(
+		"+ Error [B1] (SoapBubble) Explosion accident: the bubble is too big\n" +
+			"~ Warning [C1] (SandCastle) Collapse danger: the tide is high\n" +
+			"  was: Warning [C1] (SandCastle) Collapse danger: the tide is rising\n" +
+			"- Warning [C1] (GrandCastle) Collapse danger: the castle is too old",
+	))
+
+	output, _ = PrintDiff(diffs, SARIFFormat, false)
+	log := sarifLog{}
+	g.Expect(json.Unmarshal([]byte(output), &log)).To(Succeed())
+	var states []string
+	for _, r := range log.Runs[0].Results {
+		states = append(states, r.BaselineState)
+	}
+	g.Expect(states).To(Equal([]string{"new", "updated", "absent"}))
+
+	jsonOutput, _ := PrintDiff([]diag.MessageDiff{}, JSONFormat, false)
+	g.Expect(jsonOutput).To(Equal("[]"))
+}
@@ -0,0 +1,178 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package formatting
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/legacy/source/kube"
+	"istio.io/istio/pkg/url"
+)
+
+// The subset of the SARIF 2.1.0 format (https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html)
+// used to report analysis messages as code review annotations.
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID      string `json:"id"`
+	HelpURI string `json:"helpUri"`
+}
+
+type sarifResult struct {
+	RuleID        string          `json:"ruleId"`
+	Level         string          `json:"level"`
+	Message       sarifMessage    `json:"message"`
+	Locations     []sarifLocation `json:"locations,omitempty"`
+	BaselineState string          `json:"baselineState,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+var (
+	sarifLevels = map[diag.Level]string{
+		diag.Info:    "note",
+		diag.Warning: "warning",
+		diag.Error:   "error",
+	}
+	// sarifBaselineStates maps the status of a message difference to the SARIF baseline state of the result.
+	sarifBaselineStates = map[diag.DiffStatus]string{
+		diag.DiffNew:      "new",
+		diag.DiffChanged:  "updated",
+		diag.DiffResolved: "absent",
+	}
+)
+
+func printSARIF(ms diag.Messages) (string, error) {
+	results := make([]sarifResult, 0, len(ms))
+	for _, m := range ms {
+		results = append(results, toSARIFResult(m))
+	}
+	return marshalSARIF(ms, results)
+}
+
+func printDiffSARIF(diffs []diag.MessageDiff) (string, error) {
+	ms := make(diag.Messages, 0, len(diffs))
+	results := make([]sarifResult, 0, len(diffs))
+	for _, d := range diffs {
+		r := toSARIFResult(d.Message)
+		r.BaselineState = sarifBaselineStates[d.Status]
+		ms = append(ms, d.Message)
+		results = append(results, r)
+	}
+	return marshalSARIF(ms, results)
+}
+
+func marshalSARIF(ms diag.Messages, results []sarifResult) (string, error) {
+	rules := []sarifRule{}
+	seen := map[string]bool{}
+	for _, m := range ms {
+		code := m.Type.Code()
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		rules = append(rules, sarifRule{ID: code, HelpURI: fmt.Sprintf("%s/%s/", url.ConfigAnalysis, strings.ToLower(code))})
+	}
+	out, err := json.MarshalIndent(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "istioctl analyze",
+				InformationURI: url.ConfigAnalysis,
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}, "", "\t")
+	return string(out), err
+}
+
+func toSARIFResult(m diag.Message) sarifResult {
+	r := sarifResult{
+		RuleID:  m.Type.Code(),
+		Level:   sarifLevels[m.Type.Level()],
+		Message: sarifMessage{Text: fmt.Sprintf(m.Type.Template(), m.Parameters...)},
+	}
+	if m.Resource == nil {
+		return r
+	}
+	loc := sarifLocation{
+		LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: m.Resource.Origin.FriendlyName()}},
+	}
+	if p, ok := m.Resource.Origin.Reference().(*kube.Position); ok && p.Filename != "" {
+		loc.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: p.Filename}}
+		line := p.Line
+		if m.Line != 0 {
+			line = m.Line
+		}
+		if line > 0 {
+			loc.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+		}
+	}
+	r.Locations = []sarifLocation{loc}
+	return r
+}
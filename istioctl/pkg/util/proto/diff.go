@@ -0,0 +1,140 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proto
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// FieldDiff is a field whose value differs between two messages. Before is nil for an added field, and After
+// is nil for a removed field.
+type FieldDiff struct {
+	// Path is the JSON path of the field, e.g. filterChains[name=inbound].filters[0].name. The elements of
+	// a list whose elements all have a unique name are identified by their name rather than their index.
+	Path   string `json:"path"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// Diff returns the fields whose values differ between the messages, sorted by path. The messages are compared
+// using their canonical JSON encoding.
+func Diff(before, after proto.Message) ([]FieldDiff, error) {
+	b, err := protomarshal.ToJSONMap(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := protomarshal.ToJSONMap(after)
+	if err != nil {
+		return nil, err
+	}
+	diffs := diffValues("", b, a, nil)
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs, nil
+}
+
+func diffValues(path string, before, after any, diffs []FieldDiff) []FieldDiff {
+	switch b := before.(type) {
+	case map[string]any:
+		if a, ok := after.(map[string]any); ok {
+			return diffMaps(path, b, a, diffs)
+		}
+	case []any:
+		if a, ok := after.([]any); ok {
+			return diffLists(path, b, a, diffs)
+		}
+	}
+	if !reflect.DeepEqual(before, after) {
+		diffs = append(diffs, FieldDiff{Path: path, Before: before, After: after})
+	}
+	return diffs
+}
+
+func diffMaps(path string, before, after map[string]any, diffs []FieldDiff) []FieldDiff {
+	join := func(k string) string {
+		if path == "" {
+			return k
+		}
+		return path + "." + k
+	}
+	for k, b := range before {
+		diffs = diffValues(join(k), b, after[k], diffs)
+	}
+	for k, a := range after {
+		if _, f := before[k]; !f {
+			diffs = append(diffs, FieldDiff{Path: join(k), After: a})
+		}
+	}
+	return diffs
+}
+
+func diffLists(path string, before, after []any, diffs []FieldDiff) []FieldDiff {
+	bn, bok := elementNames(before)
+	an, aok := elementNames(after)
+	if bok && aok {
+		afterByName := map[string]any{}
+		for i, n := range an {
+			afterByName[n] = after[i]
+		}
+		beforeByName := map[string]any{}
+		for i, n := range bn {
+			beforeByName[n] = before[i]
+			diffs = diffValues(fmt.Sprintf("%s[name=%s]", path, n), before[i], afterByName[n], diffs)
+		}
+		for i, n := range an {
+			if _, f := beforeByName[n]; !f {
+				diffs = append(diffs, FieldDiff{Path: fmt.Sprintf("%s[name=%s]", path, n), After: after[i]})
+			}
+		}
+		return diffs
+	}
+	for i := 0; i < len(before) || i < len(after); i++ {
+		var b, a any
+		if i < len(before) {
+			b = before[i]
+		}
+		if i < len(after) {
+			a = after[i]
+		}
+		diffs = diffValues(fmt.Sprintf("%s[%d]", path, i), b, a, diffs)
+	}
+	return diffs
+}
+
+// elementNames returns the names of the elements of the list, if they all have a unique name.
+func elementNames(l []any) ([]string, bool) {
+	names := make([]string, 0, len(l))
+	seen := map[string]bool{}
+	for _, e := range l {
+		m, ok := e.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		n, ok := m["name"].(string)
+		if !ok || n == "" || seen[n] {
+			return nil, false
+		}
+		seen[n] = true
+		names = append(names, n)
+	}
+	return names, true
+}
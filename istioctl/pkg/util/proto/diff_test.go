@@ -0,0 +1,60 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proto
+
+import (
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := &listener.Listener{
+		Name: "l",
+		FilterChains: []*listener.FilterChain{
+			{Name: "a", Filters: []*listener.Filter{{Name: "f1"}, {Name: "f2"}}},
+			{Name: "b"},
+		},
+	}
+	after := &listener.Listener{
+		Name: "l",
+		FilterChains: []*listener.FilterChain{
+			{Name: "c"},
+			{Name: "a", Filters: []*listener.Filter{{Name: "f1"}}},
+		},
+		StatPrefix: "stats",
+	}
+	diffs, err := Diff(before, after)
+	assert.NoError(t, err)
+	assert.Equal(t, diffs, []FieldDiff{
+		{Path: "filterChains[name=a].filters[name=f2]", Before: map[string]any{"name": "f2"}},
+		{Path: "filterChains[name=b]", Before: map[string]any{"name": "b"}},
+		{Path: "filterChains[name=c]", After: map[string]any{"name": "c"}},
+		{Path: "statPrefix", After: "stats"},
+	})
+
+	diffs, err = Diff(&cluster.Cluster{Name: "c", ConnectTimeout: durationpb.New(1)},
+		&cluster.Cluster{Name: "c", ConnectTimeout: durationpb.New(2)})
+	assert.NoError(t, err)
+	assert.Equal(t, diffs, []FieldDiff{{Path: "connectTimeout", Before: "0.000000001s", After: "0.000000002s"}})
+
+	diffs, err = Diff(before, before)
+	assert.NoError(t, err)
+	assert.Equal(t, len(diffs), 0)
+}
@@ -0,0 +1,209 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/istioctl/pkg/util/configdump"
+	protodiff "istio.io/istio/istioctl/pkg/util/proto"
+)
+
+// Types of the resources compared by the DumpComparator, in the order they are printed.
+const (
+	ListenerType = "Listener"
+	RouteType    = "RouteConfiguration"
+	ClusterType  = "Cluster"
+)
+
+var resourceTypes = []string{ListenerType, RouteType, ClusterType}
+
+// ResourceDiff is a listener, route or cluster that differs between two config dumps.
+type ResourceDiff struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Action is added, removed or modified.
+	Action string `json:"action"`
+	// Fields are the fields changed in a modified resource.
+	Fields []protodiff.FieldDiff `json:"fields,omitempty"`
+}
+
+// DumpComparator diffs the dynamic listeners, routes and clusters of two Envoy config dumps, e.g. of two pods or
+// of a pod at two points in time. The resources are compared field by field, so that the ordering of the
+// resources and their versions, nonces and update times are ignored.
+type DumpComparator struct {
+	before, after         *configdump.Wrapper
+	beforeName, afterName string
+	w                     io.Writer
+}
+
+// NewDumpComparator is a DumpComparator constructor. The names identify the config dumps in the output.
+func NewDumpComparator(w io.Writer, beforeName string, before []byte, afterName string, after []byte) (*DumpComparator, error) {
+	c := &DumpComparator{beforeName: beforeName, afterName: afterName, w: w}
+	c.before = &configdump.Wrapper{}
+	if err := json.Unmarshal(before, c.before); err != nil {
+		return nil, fmt.Errorf("failed to parse the config dump of %s: %v", beforeName, err)
+	}
+	c.after = &configdump.Wrapper{}
+	if err := json.Unmarshal(after, c.after); err != nil {
+		return nil, fmt.Errorf("failed to parse the config dump of %s: %v", afterName, err)
+	}
+	return c, nil
+}
+
+// Diff returns the resources that differ between the config dumps, sorted by type and name.
+func (c *DumpComparator) Diff() ([]ResourceDiff, error) {
+	before, err := dumpResources(c.before)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", c.beforeName, err)
+	}
+	after, err := dumpResources(c.after)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", c.afterName, err)
+	}
+	var diffs []ResourceDiff
+	for _, typ := range resourceTypes {
+		b, a := before[typ], after[typ]
+		names := make([]string, 0, len(b)+len(a))
+		for n := range b {
+			names = append(names, n)
+		}
+		for n := range a {
+			if _, f := b[n]; !f {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			d := ResourceDiff{Type: typ, Name: n}
+			switch {
+			case b[n] == nil:
+				d.Action = "added"
+			case a[n] == nil:
+				d.Action = "removed"
+			case proto.Equal(b[n], a[n]):
+				continue
+			default:
+				d.Action = "modified"
+				if d.Fields, err = protodiff.Diff(b[n], a[n]); err != nil {
+					return nil, fmt.Errorf("failed to compare %s %s: %v", typ, n, err)
+				}
+			}
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs, nil
+}
+
+// Print writes the diffs grouped by resource type.
+func (c *DumpComparator) Print(diffs []ResourceDiff) {
+	if len(diffs) == 0 {
+		fmt.Fprintf(c.w, "No differences between the listeners, routes and clusters of %s and %s\n", c.beforeName, c.afterName)
+		return
+	}
+	fmt.Fprintf(c.w, "--- %s\n+++ %s\n", c.beforeName, c.afterName)
+	typ := ""
+	for _, d := range diffs {
+		if d.Type != typ {
+			typ = d.Type
+			fmt.Fprintf(c.w, "%ss:\n", typ)
+		}
+		fmt.Fprintf(c.w, "  %s %s\n", d.Action, d.Name)
+		for _, f := range d.Fields {
+			fmt.Fprintf(c.w, "    %s: %s -> %s\n", f.Path, fieldValue(f.Before), fieldValue(f.After))
+		}
+	}
+}
+
+// PrintJSON writes the diffs as JSON.
+func (c *DumpComparator) PrintJSON(diffs []ResourceDiff) error {
+	if diffs == nil {
+		diffs = []ResourceDiff{}
+	}
+	b, err := json.MarshalIndent(diffs, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.w, string(b))
+	return err
+}
+
+// fieldValue returns the value of a field for the summary output. Objects and lists are elided, they are
+// part of the JSON output.
+func fieldValue(v any) string {
+	switch v.(type) {
+	case nil:
+		return "<none>"
+	case map[string]any:
+		return "{...}"
+	case []any:
+		return "[...]"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// dumpResources returns the dynamic listeners, routes and clusters of the config dump by type and name.
+func dumpResources(w *configdump.Wrapper) (map[string]map[string]proto.Message, error) {
+	out := map[string]map[string]proto.Message{}
+	for _, typ := range resourceTypes {
+		out[typ] = map[string]proto.Message{}
+	}
+	listeners, err := w.GetDynamicListenerDump(true)
+	if err != nil {
+		return nil, err
+	}
+	for _, dl := range listeners.DynamicListeners {
+		l := &listener.Listener{}
+		if err := dl.ActiveState.Listener.UnmarshalTo(l); err != nil {
+			return nil, err
+		}
+		out[ListenerType][l.Name] = l
+	}
+	routes, err := w.GetDynamicRouteDump(true)
+	if err != nil {
+		return nil, err
+	}
+	for _, dr := range routes.DynamicRouteConfigs {
+		r := &route.RouteConfiguration{}
+		if err := dr.RouteConfig.UnmarshalTo(r); err != nil {
+			return nil, err
+		}
+		out[RouteType][r.Name] = r
+	}
+	clusters, err := w.GetDynamicClusterDump(true)
+	if err != nil {
+		return nil, err
+	}
+	for _, dc := range clusters.DynamicActiveClusters {
+		cl := &cluster.Cluster{}
+		if err := dc.Cluster.UnmarshalTo(cl); err != nil {
+			return nil, err
+		}
+		out[ClusterType][cl.Name] = cl
+	}
+	return out, nil
+}
@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compare
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	protodiff "istio.io/istio/istioctl/pkg/util/proto"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func configDump(version, clusters, routes string) []byte {
+	return []byte(fmt.Sprintf(`{"configs": [
+  {"@type": "type.googleapis.com/envoy.admin.v3.ListenersConfigDump", "dynamic_listeners": [
+    {"name": "virtualOutbound", "active_state": {"version_info": %[1]q, "last_updated": "2022-12-01T10:00:00Z",
+      "listener": {"@type": "type.googleapis.com/envoy.config.listener.v3.Listener", "name": "virtualOutbound"}}}]},
+  {"@type": "type.googleapis.com/envoy.admin.v3.ClustersConfigDump", "dynamic_active_clusters": [%[2]s]},
+  {"@type": "type.googleapis.com/envoy.admin.v3.RoutesConfigDump", "dynamic_route_configs": [%[3]s]}
+]}`, version, clusters, routes))
+}
+
+func dumpCluster(version, name, timeout string) string {
+	return fmt.Sprintf(`{"version_info": %q, "cluster": {"@type": "type.googleapis.com/envoy.config.cluster.v3.Cluster",
+  "name": %q, "connect_timeout": %q}}`, version, name, timeout)
+}
+
+func dumpRoute(name string, vhosts ...string) string {
+	vh := ""
+	for i, v := range vhosts {
+		if i > 0 {
+			vh += ","
+		}
+		vh += fmt.Sprintf(`{"name": %q, "domains": ["*"]}`, v)
+	}
+	return fmt.Sprintf(`{"route_config": {"@type": "type.googleapis.com/envoy.config.route.v3.RouteConfiguration",
+  "name": %q, "virtual_hosts": [%s]}}`, name, vh)
+}
+
+func TestDumpComparator(t *testing.T) {
+	before := configDump("2022-12-01T10:00:00Z/1",
+		dumpCluster("1", "outbound|80||a.default.svc.cluster.local", "10s")+","+
+			dumpCluster("1", "outbound|80||b.default.svc.cluster.local", "10s"),
+		dumpRoute("80", "a", "b"))
+	after := configDump("2022-12-01T11:00:00Z/2",
+		dumpCluster("2", "outbound|80||c.default.svc.cluster.local", "10s")+","+
+			dumpCluster("2", "outbound|80||a.default.svc.cluster.local", "5s"),
+		dumpRoute("80", "b", "a"))
+
+	out := &bytes.Buffer{}
+	c, err := NewDumpComparator(out, "before", before, "after", after)
+	assert.NoError(t, err)
+	diffs, err := c.Diff()
+	assert.NoError(t, err)
+	// The versions, update times and orderings of the resources are ignored
+	assert.Equal(t, diffs, []ResourceDiff{
+		{
+			Type:   ClusterType,
+			Name:   "outbound|80||a.default.svc.cluster.local",
+			Action: "modified",
+			Fields: []protodiff.FieldDiff{{Path: "connectTimeout", Before: "10s", After: "5s"}},
+		},
+		{Type: ClusterType, Name: "outbound|80||b.default.svc.cluster.local", Action: "removed"},
+		{Type: ClusterType, Name: "outbound|80||c.default.svc.cluster.local", Action: "added"},
+	})
+
+	c.Print(diffs)
+	assert.Equal(t, out.String(), `--- before
++++ after
+Clusters:
+  modified outbound|80||a.default.svc.cluster.local
+    connectTimeout: "10s" -> "5s"
+  removed outbound|80||b.default.svc.cluster.local
+  added outbound|80||c.default.svc.cluster.local
+`)
+
+	c, err = NewDumpComparator(out, "before", before, "after", before)
+	assert.NoError(t, err)
+	diffs, err = c.Diff()
+	assert.NoError(t, err)
+	assert.Equal(t, len(diffs), 0)
+}
@@ -22,6 +22,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/bootstrap/platform"
+	dnsClient "istio.io/istio/pkg/dns/client"
 	istioagent "istio.io/istio/pkg/istio-agent"
 	"istio.io/istio/pkg/util/sets"
 	"istio.io/istio/pkg/wasm"
@@ -64,6 +65,19 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 		ProxyNamespace:              PodNamespaceVar.Get(),
 		ProxyDomain:                 proxy.DNSDomain,
 		IstiodSAN:                   istiodSAN.Get(),
+		XdsSnapshotPath:             xdsSnapshotPath,
+		XdsSnapshotMaxAge:           xdsSnapshotMaxAge,
+		DNSCache: dnsClient.CacheConfig{
+			Size:           dnsCacheSize,
+			MaxNegativeTTL: dnsCacheMaxNegativeTTL,
+		},
+		DNSUpstreamTrustBundle: dnsUpstreamTrustBundle,
+		DNSUpstreamFallback:    dnsClient.FallbackPolicy(dnsUpstreamFallback),
+	}
+	for _, upstream := range strings.Split(dnsUpstreams, ",") {
+		if upstream = strings.TrimSpace(upstream); upstream != "" {
+			o.DNSUpstreams = append(o.DNSUpstreams, upstream)
+		}
 	}
 	extractXDSHeadersFromEnv(o)
 	return o
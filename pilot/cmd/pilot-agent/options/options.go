@@ -20,6 +20,8 @@ import (
 
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
 	"istio.io/istio/pkg/config/constants"
+	dnsClient "istio.io/istio/pkg/dns/client"
+	istioagent "istio.io/istio/pkg/istio-agent"
 	"istio.io/istio/pkg/jwt"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/wasm"
@@ -101,6 +103,37 @@ var (
 	DNSForwardParallel = env.Register("DNS_FORWARD_PARALLEL", false,
 		"If set to true, agent will send parallel DNS queries to all upstream nameservers")
 
+	dnsCacheSize = env.Register("DNS_PROXY_CACHE_SIZE", 0,
+		"The maximum number of responses of the upstream nameservers cached by the DNS proxy, until their TTL "+
+			"expires. If set to 0, the responses are not cached").Get()
+
+	dnsCacheMaxNegativeTTL = env.Register("DNS_PROXY_CACHE_MAX_NEGATIVE_TTL", 30*time.Second,
+		"The maximum time NXDOMAIN and empty responses of the upstream nameservers are cached by the DNS proxy. "+
+			"If set to 0, negative responses are not cached").Get()
+
+	dnsUpstreams = env.Register("DNS_PROXY_UPSTREAMS", "",
+		"Comma separated list of DNS-over-TLS (tls://<host>[:<port>]) or DNS-over-HTTPS (https://<host>[:<port>]/<path>) "+
+			"resolvers the DNS proxy forwards the requests for hosts outside the mesh to, in order, instead of the "+
+			"nameservers of resolv.conf. Hosts in the cluster domain or a search domain of resolv.conf are still "+
+			"resolved by the nameservers of resolv.conf").Get()
+
+	dnsUpstreamTrustBundle = env.Register("DNS_PROXY_UPSTREAM_TRUST_BUNDLE", istioagent.DNSUpstreamSystemTrustBundle,
+		"The trust bundle the certificates of DNS_PROXY_UPSTREAMS are verified with: SYSTEM for the trust bundle of "+
+			"the system, or MESH for the trust bundle of the mesh").Get()
+
+	dnsUpstreamFallback = env.Register("DNS_PROXY_UPSTREAM_FALLBACK", string(dnsClient.FallbackNone),
+		"The policy when none of DNS_PROXY_UPSTREAMS answer: NONE to answer SERVFAIL, or PLAINTEXT to forward the "+
+			"requests to the nameservers of resolv.conf").Get()
+
+	xdsSnapshotPath = env.Register("XDS_SNAPSHOT_PATH", "",
+		"If set, the agent persists the last xDS config accepted by Envoy to this file, and serves it to Envoy "+
+			"and the DNS proxy while istiod is unreachable. The file should be on a volume that survives restarts of "+
+			"the proxy container, such as an emptyDir. Not supported with ISTIO_DELTA_XDS").Get()
+
+	xdsSnapshotMaxAge = env.Register("XDS_SNAPSHOT_MAX_AGE", 24*time.Hour,
+		"The maximum age of the xDS config persisted with XDS_SNAPSHOT_PATH that is served while istiod is "+
+			"unreachable").Get()
+
 	// Ability of istio-agent to retrieve proxyConfig via XDS for dynamic configuration updates
 	enableProxyConfigXdsEnv = env.Register("PROXY_CONFIG_XDS_AGENT", false,
 		"If set to true, agent retrieves dynamic proxy-config updates via xds channel").Get()
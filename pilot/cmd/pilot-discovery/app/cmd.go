@@ -24,6 +24,7 @@ import (
 
 	"istio.io/istio/pilot/pkg/bootstrap"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/serviceregistry/consul"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/cmd"
 	"istio.io/istio/pkg/config/constants"
@@ -129,8 +130,8 @@ func addFlags(c *cobra.Command) {
 	// Process commandline args.
 	c.PersistentFlags().StringSliceVar(&serverArgs.RegistryOptions.Registries, "registries",
 		[]string{string(provider.Kubernetes)},
-		fmt.Sprintf("Comma separated list of platform service registries to read from (choose one or more from {%s, %s})",
-			provider.Kubernetes, provider.Mock))
+		fmt.Sprintf("Comma separated list of platform service registries to read from (choose one or more from {%s, %s, %s, %s})",
+			provider.Kubernetes, provider.Consul, provider.Polling, provider.Mock))
 	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.ClusterRegistriesNamespace, "clusterRegistriesNamespace",
 		serverArgs.RegistryOptions.ClusterRegistriesNamespace, "Namespace for ConfigMap which stores clusters configs")
 	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.KubeConfig, "kubeconfig", "",
@@ -154,6 +155,18 @@ func addFlags(c *cobra.Command) {
 	c.PersistentFlags().StringToStringVar(&serverArgs.RegistryOptions.KubeOptions.ClusterAliases, "clusterAliases", map[string]string{},
 		"Alias names for clusters")
 
+	// Consul registry options
+	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.ConsulOptions.ServerAddress, "consulserverURL", "",
+		"URL of the Consul HTTP API, used by the Consul registry")
+	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.ConsulOptions.Datacenter, "consulDatacenter", "",
+		"Consul datacenter to read services from. Defaults to the datacenter of the Consul agent")
+	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.ConsulOptions.Namespace, "consulNamespace", consul.DefaultNamespace,
+		"Namespace the services read from Consul are placed in")
+
+	// Polling registry options
+	c.PersistentFlags().StringVar(&serverArgs.RegistryOptions.PollingConfigFile, "pollingRegistryConfig", "",
+		"Path of a YAML file listing the registries polled over HTTP, used by the Polling registry")
+
 	// using address, so it can be configured as localhost:.. (possibly UDS in future)
 	c.PersistentFlags().StringVar(&serverArgs.ServerOptions.HTTPAddr, "httpAddr", ":8080",
 		"Discovery service HTTP address")
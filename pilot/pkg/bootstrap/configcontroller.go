@@ -17,6 +17,9 @@ package bootstrap
 import (
 	"fmt"
 	"net/url"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/autoregistration"
@@ -25,6 +28,7 @@ import (
 	"istio.io/istio/pilot/pkg/config/kube/gateway"
 	"istio.io/istio/pilot/pkg/config/kube/ingress"
 	ingressv1 "istio.io/istio/pilot/pkg/config/kube/ingressv1"
+	remoteconfig "istio.io/istio/pilot/pkg/config/kube/remote"
 	"istio.io/istio/pilot/pkg/config/memory"
 	configmonitor "istio.io/istio/pilot/pkg/config/monitor"
 	"istio.io/istio/pilot/pkg/features"
@@ -32,6 +36,8 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/status/distribution"
 	"istio.io/istio/pkg/adsc"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/analysis/incluster"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -48,9 +54,15 @@ const (
 	File ConfigSourceAddressScheme = "fs"
 	// xds://ADDRESS - load XDS-over-MCP sources
 	// example xds://127.0.0.1:49133
+	// The namespaces, namespaceSelector and kinds query parameters restrict the config read from the source.
+	// example xds://127.0.0.1:49133?namespaces=istio-config,default&kinds=VirtualService,DestinationRule
+	// The delta=true query parameter uses the incremental xDS protocol.
 	XDS ConfigSourceAddressScheme = "xds"
 	// k8s:// - load in-cluster k8s controller
 	// example k8s://
+	// k8s:///CLUSTER - load config from a remote cluster, using the remote secret of the cluster. The namespaces
+	// query parameter restricts the namespaces config is read from.
+	// example k8s:///cluster-2?namespaces=istio-config,default
 	Kubernetes ConfigSourceAddressScheme = "k8s"
 )
 
@@ -240,6 +252,10 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 			}
 			s.ConfigStores = append(s.ConfigStores, configController)
 		case XDS:
+			filter, err := xdsConfigFilter(srcAddress)
+			if err != nil {
+				return fmt.Errorf("invalid config URL %s: %v", configSource.Address, err)
+			}
 			xdsMCP, err := adsc.New(srcAddress.Host, &adsc.Config{
 				Namespace: args.Namespace,
 				Workload:  args.PodName,
@@ -249,13 +265,14 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 					// To reduce transported data if upstream server supports. Especially for custom servers.
 					IstioRevision: args.Revision,
 				}.ToStruct(),
-				InitialDiscoveryRequests: adsc.ConfigInitialRequests(),
+				InitialDiscoveryRequests: adsc.FilteredConfigInitialRequests(filter),
+				ConfigFilter:             filter,
+				Delta:                    srcAddress.Query().Get("delta") == "true",
 			})
 			if err != nil {
 				return fmt.Errorf("failed to dial XDS %s %v", configSource.Address, err)
 			}
 			store := memory.Make(collections.Pilot)
-			// TODO: enable namespace filter for memory controller
 			configController := memory.NewController(store)
 			configController.RegisterHasSyncedHandler(xdsMCP.HasSynced)
 			xdsMCP.Store = configController
@@ -273,10 +290,8 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 					return err2
 				}
 				log.Warn("Started K8S config")
-			} else {
-				log.Warnf("Not implemented, ignore: %v", configSource.Address)
-				// TODO: handle k8s:// scheme for remote cluster. Use same mechanism as service registry,
-				// using the cluster name as key to match a secret.
+			} else if err := s.initRemoteConfigSource(args, configSource.Address, srcAddress); err != nil {
+				return err
 			}
 		default:
 			log.Warnf("Ignoring unsupported config source: %v", configSource.Address)
@@ -285,6 +300,80 @@ func (s *Server) initConfigSources(args *PilotArgs) (err error) {
 	return nil
 }
 
+// xdsConfigFilter returns the filter of an XDS config source, from the query parameters of its address:
+// namespaces is a comma separated list of namespaces, namespaceSelector a label selector matched against the
+// kubernetes.io/metadata.name label of the namespaces, and kinds a comma separated list of config kinds.
+func xdsConfigFilter(srcAddress *url.URL) (*adsc.ConfigFilter, error) {
+	query := srcAddress.Query()
+	if query.Get("namespaces") == "" && query.Get("namespaceSelector") == "" && query.Get("kinds") == "" {
+		return nil, nil
+	}
+	filter := &adsc.ConfigFilter{}
+	if ns := query.Get("namespaces"); ns != "" {
+		filter.Namespaces = strings.Split(ns, ",")
+	}
+	if selector := query.Get("namespaceSelector"); selector != "" {
+		ls, err := metav1.ParseToLabelSelector(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespaceSelector: %v", err)
+		}
+		filter.NamespaceSelectors = []*metav1.LabelSelector{ls}
+	}
+	if kinds := query.Get("kinds"); kinds != "" {
+		byKind := map[string]config.GroupVersionKind{}
+		for _, s := range collections.Pilot.All() {
+			byKind[s.Resource().Kind()] = s.Resource().GroupVersionKind()
+		}
+		for _, kind := range strings.Split(kinds, ",") {
+			k, f := byKind[kind]
+			if !f {
+				return nil, fmt.Errorf("unknown kind %s", kind)
+			}
+			filter.Kinds = append(filter.Kinds, k)
+		}
+	}
+	return filter, nil
+}
+
+// initRemoteConfigSource creates a config store reading config from a remote cluster. The cluster is matched by
+// name with the clusters of the remote secrets.
+func (s *Server) initRemoteConfigSource(args *PilotArgs, address string, srcAddress *url.URL) error {
+	if s.multiclusterController == nil {
+		return fmt.Errorf("config source %s requires a Kubernetes cluster to read remote secrets from", address)
+	}
+	clusterID := cluster.ID(strings.Trim(srcAddress.Path, "/"))
+	if clusterID == "" {
+		return fmt.Errorf("invalid k8s config URL %s, contains no cluster", address)
+	}
+	var namespaces []string
+	if ns := srcAddress.Query().Get("namespaces"); ns != "" {
+		namespaces = strings.Split(ns, ",")
+	}
+	source := remoteconfig.NewController(remoteconfig.Options{
+		Address:        address,
+		ClusterID:      clusterID,
+		Namespaces:     namespaces,
+		Revision:       args.Revision,
+		DomainSuffix:   args.RegistryOptions.KubeOptions.DomainSuffix,
+		ClustersSynced: s.multiclusterController.HasSynced,
+		SyncTimeout:    features.RemoteClusterTimeout,
+	})
+	s.multiclusterController.AddHandler(source)
+	s.ConfigStores = append(s.ConfigStores, source)
+	s.remoteConfigSources = append(s.remoteConfigSources, source)
+	s.XDSServer.ListConfigSources = s.listRemoteConfigSources
+	log.Infof("Started remote K8S config source %s for cluster %s", address, clusterID)
+	return nil
+}
+
+func (s *Server) listRemoteConfigSources() []cluster.ConfigSourceDebugInfo {
+	out := make([]cluster.ConfigSourceDebugInfo, 0, len(s.remoteConfigSources))
+	for _, source := range s.remoteConfigSources {
+		out = append(out, source.DebugInfo())
+	}
+	return out
+}
+
 // initInprocessAnalysisController spins up an instance of Galley which serves no purpose other than
 // running Analyzers for status updates.  The Status Updater will eventually need to allow input from istiod
 // to support config distribution status as well.
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/serviceregistry/consul"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/keepalive"
@@ -38,6 +39,10 @@ type RegistryOptions struct {
 
 	// Kubernetes controller options
 	KubeOptions kubecontroller.Options
+	// Consul controller options
+	ConsulOptions consul.Options
+	// PollingConfigFile is the path of the config of the polling registries
+	PollingConfigFile string
 	// ClusterRegistriesNamespace specifies where the multi-cluster secret resides
 	ClusterRegistriesNamespace string
 	KubeConfig                 string
@@ -38,6 +38,7 @@ import (
 	"k8s.io/client-go/rest"
 
 	"istio.io/api/security/v1beta1"
+	remoteconfig "istio.io/istio/pilot/pkg/config/kube/remote"
 	kubecredentials "istio.io/istio/pilot/pkg/credentials/kube"
 	"istio.io/istio/pilot/pkg/features"
 	istiogrpc "istio.io/istio/pilot/pkg/grpc"
@@ -114,6 +115,8 @@ type Server struct {
 	configController       model.ConfigStoreController
 	ConfigStores           []model.ConfigStoreController
 	serviceEntryController *serviceentry.Controller
+	// remoteConfigSources are the config sources reading config from remote clusters.
+	remoteConfigSources []*remoteconfig.Controller
 
 	httpServer       *http.Server // debug, monitoring and readiness Server.
 	httpAddr         string
@@ -426,6 +429,13 @@ func (s *Server) Start(stop <-chan struct{}) error {
 	if !s.waitForCacheSync(stop) {
 		return fmt.Errorf("failed to sync cache")
 	}
+	if features.XDSCachePersistDir != "" {
+		// Warm the XDS cache before proxies (re)connect, so they do not all need to be generated from scratch.
+		if err := s.XDSServer.LoadXdsCache(features.XDSCachePersistDir); err != nil {
+			log.Warnf("failed to load xds cache: %v", err)
+		}
+		go s.XDSServer.PersistXdsCache(features.XDSCachePersistDir, features.XDSCachePersistInterval, stop)
+	}
 	// Inform Discovery Server so that it can start accepting connections.
 	s.XDSServer.CachesSynced()
 
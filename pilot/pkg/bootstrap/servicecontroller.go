@@ -17,8 +17,11 @@ package bootstrap
 import (
 	"fmt"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
+	"istio.io/istio/pilot/pkg/serviceregistry/consul"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pilot/pkg/serviceregistry/polling"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
 	"istio.io/pkg/log"
@@ -52,6 +55,14 @@ func (s *Server) initServiceControllers(args *PilotArgs) error {
 			if err := s.initKubeRegistry(args); err != nil {
 				return err
 			}
+		case provider.Consul:
+			if err := s.initConsulRegistry(args); err != nil {
+				return err
+			}
+		case provider.Polling:
+			if err := s.initPollingRegistries(args); err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("service registry %s is not supported", r)
 		}
@@ -90,3 +101,45 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 
 	return
 }
+
+// initConsulRegistry creates the service controller backed by the Consul catalog
+func (s *Server) initConsulRegistry(args *PilotArgs) error {
+	opts := args.RegistryOptions.ConsulOptions
+	if opts.ServerAddress == "" {
+		return fmt.Errorf("%s registry requires --consulserverURL to be set", provider.Consul)
+	}
+	opts.ClusterID = s.clusterID
+	opts.Token = features.ConsulToken
+	opts.XDSUpdater = s.XDSServer
+	controller, err := consul.NewController(opts)
+	if err != nil {
+		return fmt.Errorf("failed to create %s registry: %v", provider.Consul, err)
+	}
+	s.ServiceController().AddRegistry(controller)
+	return nil
+}
+
+// initPollingRegistries creates a service controller for each registry configured in the polling registry config
+func (s *Server) initPollingRegistries(args *PilotArgs) error {
+	if args.RegistryOptions.PollingConfigFile == "" {
+		return fmt.Errorf("%s registry requires --pollingRegistryConfig to be set", provider.Polling)
+	}
+	configs, err := polling.LoadConfigs(args.RegistryOptions.PollingConfigFile)
+	if err != nil {
+		return err
+	}
+	for _, cfg := range configs {
+		opts, err := cfg.Options()
+		if err != nil {
+			return err
+		}
+		opts.ClusterID = s.clusterID
+		opts.XDSUpdater = s.XDSServer
+		controller, err := polling.NewController(opts)
+		if err != nil {
+			return fmt.Errorf("failed to create %s registry: %v", provider.Polling, err)
+		}
+		s.ServiceController().AddRegistry(controller)
+	}
+	return nil
+}
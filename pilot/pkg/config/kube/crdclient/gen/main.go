@@ -154,6 +154,7 @@ var (
 		"requestauthentications":        "RequestAuthentications",
 		"gatewayclasses":                "GatewayClasses",
 		"httproutes":                    "HTTPRoutes",
+		"grpcroutes":                    "GRPCRoutes",
 		"tcproutes":                     "TCPRoutes",
 		"tlsroutes":                     "TLSRoutes",
 		"referencepolicies":             "ReferencePolicies",
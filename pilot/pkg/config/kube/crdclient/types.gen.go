@@ -125,6 +125,11 @@ func create(ic versionedclient.Interface, sc gatewayapiclient.Interface, cfg con
 			ObjectMeta: objMeta,
 			Spec:       *(cfg.Spec.(*telemetryv1alpha1.Telemetry)),
 		}, metav1.CreateOptions{})
+	case collections.K8SGatewayApiV1Alpha2Grpcroutes.Resource().GroupVersionKind():
+		return sc.GatewayV1alpha2().GRPCRoutes(cfg.Namespace).Create(context.TODO(), &gatewayv1alpha2.GRPCRoute{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*gatewayv1alpha2.GRPCRouteSpec)),
+		}, metav1.CreateOptions{})
 	case collections.K8SGatewayApiV1Alpha2Referencegrants.Resource().GroupVersionKind():
 		return sc.GatewayV1alpha2().ReferenceGrants(cfg.Namespace).Create(context.TODO(), &gatewayv1alpha2.ReferenceGrant{
 			ObjectMeta: objMeta,
@@ -232,6 +237,11 @@ func update(ic versionedclient.Interface, sc gatewayapiclient.Interface, cfg con
 			ObjectMeta: objMeta,
 			Spec:       *(cfg.Spec.(*telemetryv1alpha1.Telemetry)),
 		}, metav1.UpdateOptions{})
+	case collections.K8SGatewayApiV1Alpha2Grpcroutes.Resource().GroupVersionKind():
+		return sc.GatewayV1alpha2().GRPCRoutes(cfg.Namespace).Update(context.TODO(), &gatewayv1alpha2.GRPCRoute{
+			ObjectMeta: objMeta,
+			Spec:       *(cfg.Spec.(*gatewayv1alpha2.GRPCRouteSpec)),
+		}, metav1.UpdateOptions{})
 	case collections.K8SGatewayApiV1Alpha2Referencegrants.Resource().GroupVersionKind():
 		return sc.GatewayV1alpha2().ReferenceGrants(cfg.Namespace).Update(context.TODO(), &gatewayv1alpha2.ReferenceGrant{
 			ObjectMeta: objMeta,
@@ -354,6 +364,12 @@ func updateStatus(ic versionedclient.Interface, sc gatewayapiclient.Interface, c
 			Status:     *(cfg.Status.(*metav1alpha1.IstioStatus)),
 		}, metav1.UpdateOptions{})
 
+	case collections.K8SGatewayApiV1Alpha2Grpcroutes.Resource().GroupVersionKind():
+		return sc.GatewayV1alpha2().GRPCRoutes(cfg.Namespace).UpdateStatus(context.TODO(), &gatewayv1alpha2.GRPCRoute{
+			ObjectMeta: objMeta,
+			Status:     *(cfg.Status.(*gatewayv1alpha2.GRPCRouteStatus)),
+		}, metav1.UpdateOptions{})
+
 	case collections.K8SGatewayApiV1Alpha2Tcproutes.Resource().GroupVersionKind():
 		return sc.GatewayV1alpha2().TCPRoutes(cfg.Namespace).UpdateStatus(context.TODO(), &gatewayv1alpha2.TCPRoute{
 			ObjectMeta: objMeta,
@@ -604,6 +620,21 @@ func patch(ic versionedclient.Interface, sc gatewayapiclient.Interface, orig con
 		}
 		return ic.TelemetryV1alpha1().Telemetries(orig.Namespace).
 			Patch(context.TODO(), orig.Name, typ, patchBytes, metav1.PatchOptions{FieldManager: "pilot-discovery"})
+	case collections.K8SGatewayApiV1Alpha2Grpcroutes.Resource().GroupVersionKind():
+		oldRes := &gatewayv1alpha2.GRPCRoute{
+			ObjectMeta: origMeta,
+			Spec:       *(orig.Spec.(*gatewayv1alpha2.GRPCRouteSpec)),
+		}
+		modRes := &gatewayv1alpha2.GRPCRoute{
+			ObjectMeta: modMeta,
+			Spec:       *(mod.Spec.(*gatewayv1alpha2.GRPCRouteSpec)),
+		}
+		patchBytes, err := genPatchBytes(oldRes, modRes, typ)
+		if err != nil {
+			return nil, err
+		}
+		return sc.GatewayV1alpha2().GRPCRoutes(orig.Namespace).
+			Patch(context.TODO(), orig.Name, typ, patchBytes, metav1.PatchOptions{FieldManager: "pilot-discovery"})
 	case collections.K8SGatewayApiV1Alpha2Referencegrants.Resource().GroupVersionKind():
 		oldRes := &gatewayv1alpha2.ReferenceGrant{
 			ObjectMeta: origMeta,
@@ -733,6 +764,8 @@ func delete(ic versionedclient.Interface, sc gatewayapiclient.Interface, typ con
 		return ic.SecurityV1beta1().RequestAuthentications(namespace).Delete(context.TODO(), name, deleteOptions)
 	case collections.IstioTelemetryV1Alpha1Telemetries.Resource().GroupVersionKind():
 		return ic.TelemetryV1alpha1().Telemetries(namespace).Delete(context.TODO(), name, deleteOptions)
+	case collections.K8SGatewayApiV1Alpha2Grpcroutes.Resource().GroupVersionKind():
+		return sc.GatewayV1alpha2().GRPCRoutes(namespace).Delete(context.TODO(), name, deleteOptions)
 	case collections.K8SGatewayApiV1Alpha2Referencegrants.Resource().GroupVersionKind():
 		return sc.GatewayV1alpha2().ReferenceGrants(namespace).Delete(context.TODO(), name, deleteOptions)
 	case collections.K8SGatewayApiV1Alpha2Tcproutes.Resource().GroupVersionKind():
@@ -1017,6 +1050,25 @@ var translationMap = map[config.GroupVersionKind]func(r runtime.Object) config.C
 			Status: &obj.Status,
 		}
 	},
+	collections.K8SGatewayApiV1Alpha2Grpcroutes.Resource().GroupVersionKind(): func(r runtime.Object) config.Config {
+		obj := r.(*gatewayv1alpha2.GRPCRoute)
+		return config.Config{
+			Meta: config.Meta{
+				GroupVersionKind:  collections.K8SGatewayApiV1Alpha2Grpcroutes.Resource().GroupVersionKind(),
+				Name:              obj.Name,
+				Namespace:         obj.Namespace,
+				Labels:            obj.Labels,
+				Annotations:       obj.Annotations,
+				ResourceVersion:   obj.ResourceVersion,
+				CreationTimestamp: obj.CreationTimestamp.Time,
+				OwnerReferences:   obj.OwnerReferences,
+				UID:               string(obj.UID),
+				Generation:        obj.Generation,
+			},
+			Spec:   &obj.Spec,
+			Status: &obj.Status,
+		}
+	},
 	collections.K8SGatewayApiV1Alpha2Referencegrants.Resource().GroupVersionKind(): func(r runtime.Object) config.Config {
 		obj := r.(*gatewayv1alpha2.ReferenceGrant)
 		return config.Config{
@@ -182,6 +182,10 @@ func (c *Controller) Reconcile(ps *model.PushContext) error {
 	if err != nil {
 		return fmt.Errorf("failed to list type HTTPRoute: %v", err)
 	}
+	grpcRoute, err := c.cache.List(gvk.GRPCRoute, metav1.NamespaceAll)
+	if err != nil {
+		return fmt.Errorf("failed to list type GRPCRoute: %v", err)
+	}
 	tcpRoute, err := c.cache.List(gvk.TCPRoute, metav1.NamespaceAll)
 	if err != nil {
 		return fmt.Errorf("failed to list type TCPRoute: %v", err)
@@ -199,6 +203,7 @@ func (c *Controller) Reconcile(ps *model.PushContext) error {
 		GatewayClass:   deepCopyStatus(gatewayClass),
 		Gateway:        deepCopyStatus(gateway),
 		HTTPRoute:      deepCopyStatus(httpRoute),
+		GRPCRoute:      deepCopyStatus(grpcRoute),
 		TCPRoute:       deepCopyStatus(tcpRoute),
 		TLSRoute:       deepCopyStatus(tlsRoute),
 		ReferenceGrant: referenceGrant,
@@ -239,6 +244,7 @@ func (c *Controller) QueueStatusUpdates(r KubernetesResources) {
 	c.handleStatusUpdates(r.GatewayClass)
 	c.handleStatusUpdates(r.Gateway)
 	c.handleStatusUpdates(r.HTTPRoute)
+	c.handleStatusUpdates(r.GRPCRoute)
 	c.handleStatusUpdates(r.TCPRoute)
 	c.handleStatusUpdates(r.TLSRoute)
 }
@@ -388,6 +394,7 @@ func (kr KubernetesResources) hasResources() bool {
 	return len(kr.GatewayClass) > 0 ||
 		len(kr.Gateway) > 0 ||
 		len(kr.HTTPRoute) > 0 ||
+		len(kr.GRPCRoute) > 0 ||
 		len(kr.TCPRoute) > 0 ||
 		len(kr.TLSRoute) > 0 ||
 		len(kr.ReferenceGrant) > 0
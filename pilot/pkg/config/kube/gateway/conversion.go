@@ -17,6 +17,7 @@ package gateway
 import (
 	"fmt"
 	"net"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -51,6 +52,7 @@ type KubernetesResources struct {
 	GatewayClass   []config.Config
 	Gateway        []config.Config
 	HTTPRoute      []config.Config
+	GRPCRoute      []config.Config
 	TCPRoute       []config.Config
 	TLSRoute       []config.Config
 	ReferenceGrant []config.Config
@@ -120,15 +122,9 @@ type ConfigContext struct {
 // convertResources is the top level entrypoint to our conversion logic, computing the full state based
 // on KubernetesResources inputs.
 func convertResources(r KubernetesResources) OutputResources {
-	// sort HTTPRoutes by creation timestamp and namespace/name
-	sort.Slice(r.HTTPRoute, func(i, j int) bool {
-		if r.HTTPRoute[i].CreationTimestamp.Equal(r.HTTPRoute[j].CreationTimestamp) {
-			in := r.HTTPRoute[i].Namespace + "/" + r.HTTPRoute[i].Name
-			jn := r.HTTPRoute[j].Namespace + "/" + r.HTTPRoute[j].Name
-			return in < jn
-		}
-		return r.HTTPRoute[i].CreationTimestamp.Before(r.HTTPRoute[j].CreationTimestamp)
-	})
+	// sort HTTPRoutes and GRPCRoutes by creation timestamp and namespace/name
+	sortRoutesByCreationTime(r.HTTPRoute)
+	sortRoutesByCreationTime(r.GRPCRoute)
 	result := OutputResources{}
 	ctx := ConfigContext{
 		KubernetesResources: r,
@@ -155,6 +151,17 @@ func convertResources(r KubernetesResources) OutputResources {
 	return result
 }
 
+func sortRoutesByCreationTime(routes []config.Config) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].CreationTimestamp.Equal(routes[j].CreationTimestamp) {
+			in := routes[i].Namespace + "/" + routes[i].Name
+			jn := routes[j].Namespace + "/" + routes[j].Name
+			return in < jn
+		}
+		return routes[i].CreationTimestamp.Before(routes[j].CreationTimestamp)
+	})
+}
+
 type Grants struct {
 	AllowAll     bool
 	AllowedNames sets.String
@@ -185,6 +192,8 @@ func convertReferencePolicies(r KubernetesResources) AllowedReferences {
 				fromKey.Kind = gvk.KubernetesGateway
 			} else if string(from.Group) == gvk.HTTPRoute.Group && string(from.Kind) == gvk.HTTPRoute.Kind {
 				fromKey.Kind = gvk.HTTPRoute
+			} else if string(from.Group) == gvk.GRPCRoute.Group && string(from.Kind) == gvk.GRPCRoute.Kind {
+				fromKey.Kind = gvk.GRPCRoute
 			} else if string(from.Group) == gvk.TLSRoute.Group && string(from.Kind) == gvk.TLSRoute.Kind {
 				fromKey.Kind = gvk.TLSRoute
 			} else if string(from.Group) == gvk.TCPRoute.Group && string(from.Kind) == gvk.TCPRoute.Kind {
@@ -244,6 +253,9 @@ func convertVirtualService(r ConfigContext) []config.Config {
 	for _, obj := range r.HTTPRoute {
 		buildHTTPVirtualServices(r, obj, gatewayRoutes, meshRoutes)
 	}
+	for _, obj := range r.GRPCRoute {
+		buildGRPCVirtualServices(r, obj, gatewayRoutes, meshRoutes)
+	}
 	for _, vsByHost := range gatewayRoutes {
 		for _, vsConfig := range vsByHost {
 			result = append(result, *vsConfig)
@@ -328,7 +340,7 @@ func buildHTTPVirtualServices(
 			case k8s.HTTPRouteFilterRequestRedirect:
 				vs.Redirect = createRedirectFilter(filter.RequestRedirect)
 			case k8s.HTTPRouteFilterRequestMirror:
-				mirror, err := createMirrorFilter(ctx, filter.RequestMirror, ns)
+				mirror, err := createMirrorFilter(ctx, filter.RequestMirror, ns, gvk.HTTPRoute)
 				if err != nil {
 					return err
 				}
@@ -356,7 +368,7 @@ func buildHTTPVirtualServices(
 				Status: 500,
 			}
 		} else {
-			route, err := buildHTTPDestination(ctx, r.BackendRefs, ns, zero)
+			route, err := buildHTTPDestination(ctx, r.BackendRefs, ns, zero, gvk.HTTPRoute)
 			if err != nil {
 				if isInvalidBackend(err) {
 					invalidBackendErr = err
@@ -389,6 +401,23 @@ func buildHTTPVirtualServices(
 	}
 	reportError(invalidBackendErr)
 
+	addRoutesToVirtualServices(ctx, obj, obj.Name, parentRefs, hosts, httproutes, gatewayRoutes, meshRoutes)
+}
+
+// addRoutesToVirtualServices adds the HTTP routes generated from an HTTPRoute or GRPCRoute to the VirtualServices of
+// its parents. Routes of all objects are merged, into one VirtualService per gateway and host for gateway routes,
+// and per namespace and host for mesh routes.
+func addRoutesToVirtualServices(
+	ctx ConfigContext,
+	obj config.Config,
+	namePrefix string,
+	parentRefs []routeParentReference,
+	hosts []string,
+	httproutes []*istio.HTTPRoute,
+	gatewayRoutes map[string]map[string]*config.Config,
+	meshRoutes map[string]map[string]*config.Config,
+) {
+	ns := obj.Namespace
 	count := 0
 	for _, gw := range filteredReferences(parentRefs) {
 		// for gateway routes, build one VS per gateway+host
@@ -415,7 +444,7 @@ func buildHTTPVirtualServices(
 				cfg.Annotations[constants.InternalParentNames] = fmt.Sprintf("%s,%s/%s.%s",
 					cfg.Annotations[constants.InternalParentNames], obj.GroupVersionKind.Kind, obj.Name, obj.Namespace)
 			} else {
-				name := fmt.Sprintf("%s-%d-%s", obj.Name, count, constants.KubernetesGatewayName)
+				name := fmt.Sprintf("%s-%d-%s", namePrefix, count, constants.KubernetesGatewayName)
 				routeMap[routeKey][h] = &config.Config{
 					Meta: config.Meta{
 						CreationTimestamp: obj.CreationTimestamp,
@@ -449,6 +478,149 @@ func buildHTTPVirtualServices(
 	}
 }
 
+func buildGRPCVirtualServices(
+	ctx ConfigContext,
+	obj config.Config,
+	gatewayRoutes map[string]map[string]*config.Config,
+	meshRoutes map[string]map[string]*config.Config,
+) {
+	route := obj.Spec.(*k8s.GRPCRouteSpec)
+	ns := obj.Namespace
+	parentRefs := extractParentReferenceInfo(ctx.GatewayReferences, route.ParentRefs, route.Hostnames, gvk.GRPCRoute, ns)
+
+	reportError := func(routeErr *ConfigError) {
+		obj.Status.(*kstatus.WrappedStatus).Mutate(func(s config.Status) config.Status {
+			rs := s.(*k8s.GRPCRouteStatus)
+			rs.Parents = createRouteStatus(parentRefs, obj, rs.Parents, routeErr)
+			return rs
+		})
+	}
+
+	var invalidBackendErr *ConfigError
+	httproutes := []*istio.HTTPRoute{}
+	hosts := hostnameToStringList(route.Hostnames)
+	convertGRPCRoute := func(r k8s.GRPCRouteRule) *ConfigError {
+		vs := &istio.HTTPRoute{}
+		for _, match := range r.Matches {
+			uri, err := createGRPCURIMatch(match)
+			if err != nil {
+				return err
+			}
+			headers, err := createGRPCHeadersMatch(match)
+			if err != nil {
+				return err
+			}
+			vs.Match = append(vs.Match, &istio.HTTPMatchRequest{
+				Uri:     uri,
+				Headers: headers,
+			})
+		}
+		for _, filter := range r.Filters {
+			switch filter.Type {
+			case k8s.GRPCRouteFilterRequestHeaderModifier:
+				h := createHeadersFilter(filter.RequestHeaderModifier)
+				if h == nil {
+					continue
+				}
+				if vs.Headers == nil {
+					vs.Headers = &istio.Headers{}
+				}
+				vs.Headers.Request = h
+			case k8s.GRPCRouteFilterResponseHeaderModifier:
+				h := createHeadersFilter(filter.ResponseHeaderModifier)
+				if h == nil {
+					continue
+				}
+				if vs.Headers == nil {
+					vs.Headers = &istio.Headers{}
+				}
+				vs.Headers.Response = h
+			case k8s.GRPCRouteFilterRequestMirror:
+				mirror, err := createMirrorFilter(ctx, filter.RequestMirror, ns, gvk.GRPCRoute)
+				if err != nil {
+					return err
+				}
+				vs.Mirror = mirror
+			default:
+				return &ConfigError{
+					Reason:  InvalidFilter,
+					Message: fmt.Sprintf("unsupported filter type %q", filter.Type),
+				}
+			}
+		}
+
+		zero := true
+		for _, w := range r.BackendRefs {
+			if w.Weight == nil || (w.Weight != nil && int(*w.Weight) != 0) {
+				zero = false
+				break
+			}
+		}
+		if zero {
+			// The spec requires us to return 500 when there are no >0 weight backends
+			vs.DirectResponse = &istio.HTTPDirectResponse{
+				Status: 500,
+			}
+		} else {
+			route, err := buildHTTPDestination(ctx, grpcBackendRefsToHTTP(r.BackendRefs), ns, zero, gvk.GRPCRoute)
+			if err != nil {
+				if isInvalidBackend(err) {
+					invalidBackendErr = err
+				} else {
+					return err
+				}
+			}
+			vs.Route = route
+		}
+
+		httproutes = append(httproutes, vs)
+		return nil
+	}
+
+	for _, r := range route.Rules {
+		if len(r.Matches) > 1 {
+			// split the rule to make sure each rule has up to one match
+			matches := r.Matches
+			for _, m := range matches {
+				r.Matches = []k8s.GRPCRouteMatch{m}
+				if err := convertGRPCRoute(r); err != nil {
+					reportError(err)
+					return
+				}
+			}
+		} else if err := convertGRPCRoute(r); err != nil {
+			reportError(err)
+			return
+		}
+	}
+	reportError(invalidBackendErr)
+
+	addRoutesToVirtualServices(ctx, obj, obj.Name+"-grpc", parentRefs, hosts, httproutes, gatewayRoutes, meshRoutes)
+}
+
+// grpcBackendRefsToHTTP converts GRPCRoute backends to HTTPRoute backends. The filters of both routes share the same
+// types and fields, so unsupported filters are reported the same way.
+func grpcBackendRefsToHTTP(refs []k8s.GRPCBackendRef) []k8s.HTTPBackendRef {
+	if refs == nil {
+		return nil
+	}
+	res := make([]k8s.HTTPBackendRef, 0, len(refs))
+	for _, ref := range refs {
+		hr := k8s.HTTPBackendRef{BackendRef: ref.BackendRef}
+		for _, f := range ref.Filters {
+			hr.Filters = append(hr.Filters, k8s.HTTPRouteFilter{
+				Type:                   k8s.HTTPRouteFilterType(f.Type),
+				RequestHeaderModifier:  f.RequestHeaderModifier,
+				ResponseHeaderModifier: f.ResponseHeaderModifier,
+				RequestMirror:          f.RequestMirror,
+				ExtensionRef:           f.ExtensionRef,
+			})
+		}
+		res = append(res, hr)
+	}
+	return res
+}
+
 func routeMeta(obj config.Config) map[string]string {
 	m := parentMeta(obj, nil)
 	m[constants.InternalRouteSemantics] = constants.RouteSemanticsGateway
@@ -802,7 +974,7 @@ func buildTCPDestination(ctx ConfigContext, forwardTo []k8s.BackendRef, ns strin
 				}
 			}
 		}
-		dst, err := buildDestination(ctx, fwd, ns)
+		dst, err := buildDestination(ctx, fwd, ns, gvk.HTTPRoute)
 		if err != nil {
 			return nil, err
 		}
@@ -837,6 +1009,7 @@ func buildHTTPDestination(
 	forwardTo []k8s.HTTPBackendRef,
 	ns string,
 	totalZero bool,
+	k config.GroupVersionKind,
 ) ([]*istio.HTTPRouteDestination, *ConfigError) {
 	if forwardTo == nil {
 		return nil, nil
@@ -863,7 +1036,7 @@ func buildHTTPDestination(
 	var invalidBackendErr *ConfigError
 	res := []*istio.HTTPRouteDestination{}
 	for i, fwd := range action {
-		dst, err := buildDestination(ctx, fwd.BackendRef, ns)
+		dst, err := buildDestination(ctx, fwd.BackendRef, ns, k)
 		if err != nil {
 			if isInvalidBackend(err) {
 				invalidBackendErr = err
@@ -905,11 +1078,11 @@ func buildHTTPDestination(
 	return res, invalidBackendErr
 }
 
-func buildDestination(ctx ConfigContext, to k8s.BackendRef, ns string) (*istio.Destination, *ConfigError) {
+func buildDestination(ctx ConfigContext, to k8s.BackendRef, ns string, k config.GroupVersionKind) (*istio.Destination, *ConfigError) {
 	// check if the reference is allowed
 	refs := ctx.AllowedReferences
 	if toNs := to.Namespace; toNs != nil && string(*toNs) != ns {
-		if !refs.BackendAllowed(gvk.HTTPRoute, to.Name, *toNs, ns) {
+		if !refs.BackendAllowed(k, to.Name, *toNs, ns) {
 			return &istio.Destination{}, &ConfigError{
 				Reason:  InvalidDestinationPermit,
 				Message: fmt.Sprintf("backendRef %v/%v not accessible to a route in namespace %q (missing a ReferenceGrant?)", to.Name, *toNs, ns),
@@ -1008,7 +1181,9 @@ func headerListToMap(hl []k8s.HTTPHeader) map[string]string {
 	return res
 }
 
-func createMirrorFilter(ctx ConfigContext, filter *k8s.HTTPRequestMirrorFilter, ns string) (*istio.Destination, *ConfigError) {
+func createMirrorFilter(ctx ConfigContext, filter *k8s.HTTPRequestMirrorFilter, ns string,
+	k config.GroupVersionKind,
+) (*istio.Destination, *ConfigError) {
 	if filter == nil {
 		return nil, nil
 	}
@@ -1016,7 +1191,7 @@ func createMirrorFilter(ctx ConfigContext, filter *k8s.HTTPRequestMirrorFilter,
 	return buildDestination(ctx, k8s.BackendRef{
 		BackendObjectReference: filter.BackendRef,
 		Weight:                 &weightOne,
-	}, ns)
+	}, ns, k)
 }
 
 func createRewriteFilter(filter *k8s.HTTPURLRewriteFilter) *istio.HTTPRewrite {
@@ -1174,6 +1349,81 @@ func createURIMatch(match k8s.HTTPRouteMatch) (*istio.StringMatch, *ConfigError)
 	}
 }
 
+func createGRPCHeadersMatch(match k8s.GRPCRouteMatch) (map[string]*istio.StringMatch, *ConfigError) {
+	res := map[string]*istio.StringMatch{}
+	for _, header := range match.Headers {
+		tp := k8s.HeaderMatchExact
+		if header.Type != nil {
+			tp = *header.Type
+		}
+		switch tp {
+		case k8s.HeaderMatchExact:
+			res[string(header.Name)] = &istio.StringMatch{
+				MatchType: &istio.StringMatch_Exact{Exact: header.Value},
+			}
+		case k8s.HeaderMatchRegularExpression:
+			res[string(header.Name)] = &istio.StringMatch{
+				MatchType: &istio.StringMatch_Regex{Regex: header.Value},
+			}
+		default:
+			// Should never happen, unless a new field is added
+			return nil, &ConfigError{Reason: InvalidConfiguration, Message: fmt.Sprintf("unknown type: %q is not supported HeaderMatch type", tp)}
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, nil
+	}
+	return res, nil
+}
+
+// createGRPCURIMatch converts a gRPC method match to a match on the request path, which is /<service>/<method>
+// for gRPC requests.
+func createGRPCURIMatch(match k8s.GRPCRouteMatch) (*istio.StringMatch, *ConfigError) {
+	m := match.Method
+	if m == nil {
+		return nil, nil
+	}
+	tp := k8s.GRPCMethodMatchExact
+	if m.Type != nil {
+		tp = *m.Type
+	}
+	service := defaultIfNil(m.Service, "")
+	method := defaultIfNil(m.Method, "")
+	if service == "" && method == "" {
+		return nil, &ConfigError{Reason: InvalidConfiguration, Message: "gRPC match must have at least one of service and method"}
+	}
+	switch tp {
+	case k8s.GRPCMethodMatchExact:
+		if method == "" {
+			return &istio.StringMatch{
+				MatchType: &istio.StringMatch_Prefix{Prefix: fmt.Sprintf("/%s/", service)},
+			}, nil
+		}
+		if service == "" {
+			return &istio.StringMatch{
+				MatchType: &istio.StringMatch_Regex{Regex: fmt.Sprintf("/[^/]+/%s", regexp.QuoteMeta(method))},
+			}, nil
+		}
+		return &istio.StringMatch{
+			MatchType: &istio.StringMatch_Exact{Exact: fmt.Sprintf("/%s/%s", service, method)},
+		}, nil
+	case k8s.GRPCMethodMatchRegularExpression:
+		if service == "" {
+			service = "[^/]+"
+		}
+		if method == "" {
+			method = ".+"
+		}
+		return &istio.StringMatch{
+			MatchType: &istio.StringMatch_Regex{Regex: fmt.Sprintf("/%s/%s", service, method)},
+		}, nil
+	default:
+		// Should never happen, unless a new field is added
+		return nil, &ConfigError{Reason: InvalidConfiguration, Message: fmt.Sprintf("unknown type: %q is not supported Method match type", tp)}
+	}
+}
+
 // getGatewayClass finds all gateway class that are owned by Istio
 func getGatewayClasses(r KubernetesResources) map[string]struct{} {
 	classes := map[string]struct{}{}
@@ -1476,6 +1726,7 @@ func convertGateways(r ConfigContext) ([]config.Config, map[parentKey]map[k8s.Se
 			// Mesh has no configurable AllowedKinds, so allow all supported
 			AllowedKinds: []k8s.RouteGroupKind{
 				{Group: (*k8s.Group)(StrPointer(gvk.HTTPRoute.Group)), Kind: k8s.Kind(gvk.HTTPRoute.Kind)},
+				{Group: (*k8s.Group)(StrPointer(gvk.GRPCRoute.Group)), Kind: k8s.Kind(gvk.GRPCRoute.Kind)},
 				{Group: (*k8s.Group)(StrPointer(gvk.TCPRoute.Group)), Kind: k8s.Kind(gvk.TCPRoute.Kind)},
 				{Group: (*k8s.Group)(StrPointer(gvk.TLSRoute.Group)), Kind: k8s.Kind(gvk.TLSRoute.Kind)},
 			},
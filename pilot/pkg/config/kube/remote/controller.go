@@ -0,0 +1,420 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remote provides a config store reading Istio config from a remote cluster.
+package remote
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/istio/pilot/pkg/config/kube/crdclient"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/multicluster"
+	"istio.io/istio/pkg/util/sets"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("remoteconfig", "remote cluster config store", 0)
+
+var errorUnsupported = errors.New("unsupported operation: remote config sources are read-only")
+
+// Sync statuses of a remote config source, matching the statuses of remote clusters.
+const (
+	StatusPending = "pending"
+	StatusSyncing = "syncing"
+	StatusSynced  = "synced"
+	StatusTimeout = "timeout"
+)
+
+// Options stores the configurable attributes of a Controller.
+type Options struct {
+	// Address of the config source, for debugging.
+	Address string
+	// ClusterID of the remote cluster, matching the cluster of a remote secret.
+	ClusterID cluster.ID
+	// Namespaces to read config from. All namespaces are read if empty.
+	Namespaces []string
+	// Revision and DomainSuffix are passed to the CRD client.
+	Revision     string
+	DomainSuffix string
+	// ClustersSynced returns true once the secret controller has processed the secrets present at startup.
+	// Until then, a missing cluster may still be added.
+	ClustersSynced func() bool
+	// SyncTimeout is the time after which the source is reported as synced even if the cluster did not sync.
+	// Zero disables the timeout.
+	SyncTimeout time.Duration
+}
+
+// Controller is a read-only config store for the Istio config of a remote cluster. It is registered as a
+// multicluster.ClusterHandler, and reads config once the secret for its cluster is added. Until then, and after
+// the secret is removed, the store is empty.
+type Controller struct {
+	opts       Options
+	namespaces sets.String
+
+	mu       sync.RWMutex
+	client   *crdclient.Client
+	handlers map[config.GroupVersionKind][]model.EventHandler
+	// pending is the client replacing client after an update of the cluster, until it syncs. Its events are
+	// only forwarded to the handlers once pendingActive is set, when it replaces client.
+	pending       *crdclient.Client
+	pendingActive *atomic.Bool
+	// syncTimeout is set when the current client did not sync within SyncTimeout.
+	syncTimeout *atomic.Bool
+
+	started *atomic.Bool
+}
+
+var (
+	_ model.ConfigStoreController = &Controller{}
+	_ multicluster.ClusterHandler = &Controller{}
+)
+
+// NewController creates a config store for a remote cluster.
+func NewController(opts Options) *Controller {
+	c := &Controller{
+		opts:        opts,
+		handlers:    map[config.GroupVersionKind][]model.EventHandler{},
+		syncTimeout: atomic.NewBool(false),
+		started:     atomic.NewBool(false),
+	}
+	if len(opts.Namespaces) > 0 {
+		c.namespaces = sets.New(opts.Namespaces...)
+	}
+	return c
+}
+
+// namespaceFilter filters the objects of the informers of the remote cluster.
+func (c *Controller) namespaceFilter(obj any) bool {
+	if c.namespaces == nil {
+		return true
+	}
+	object, ok := obj.(metav1.Object)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return false
+		}
+		if object, ok = tombstone.Obj.(metav1.Object); !ok {
+			return false
+		}
+	}
+	return c.namespaces.Contains(object.GetNamespace())
+}
+
+// newClient creates a client reading the config of the cluster.
+func (c *Controller) newClient(cluster *multicluster.Cluster) (*crdclient.Client, error) {
+	return crdclient.NewForSchemas(cluster.Client, crdclient.Option{
+		Revision:         c.opts.Revision,
+		DomainSuffix:     c.opts.DomainSuffix,
+		Identifier:       "remote-crd-controller-" + string(cluster.ID),
+		NamespacesFilter: c.namespaceFilter,
+	}, collections.Pilot)
+}
+
+// registerHandler registers the handler on the client. If active is set, events are dropped until it is true.
+func registerHandler(client *crdclient.Client, kind config.GroupVersionKind, h model.EventHandler, active *atomic.Bool) {
+	if active == nil {
+		client.RegisterEventHandler(kind, h)
+		return
+	}
+	client.RegisterEventHandler(kind, func(old config.Config, cur config.Config, event model.Event) {
+		if active.Load() {
+			h(old, cur, event)
+		}
+	})
+}
+
+// ClusterAdded starts reading config from the cluster, if it is the cluster of this source.
+func (c *Controller) ClusterAdded(cluster *multicluster.Cluster, stop <-chan struct{}) error {
+	if cluster.ID != c.opts.ClusterID {
+		return nil
+	}
+	client, err := c.newClient(cluster)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	old := c.client
+	c.client = client
+	c.pending, c.pendingActive = nil, nil
+	for kind, handlers := range c.handlers {
+		for _, h := range handlers {
+			registerHandler(client, kind, h, nil)
+		}
+	}
+	timeout := atomic.NewBool(false)
+	c.syncTimeout = timeout
+	c.mu.Unlock()
+
+	if old != nil {
+		c.notifyReplaced(old, nil)
+	}
+	log.Infof("reading config source %s from cluster %s", c.opts.Address, cluster.ID)
+	if c.opts.SyncTimeout > 0 {
+		time.AfterFunc(c.opts.SyncTimeout, func() {
+			if !client.HasSynced() {
+				log.Errorf("config source %s failed to sync after %v", c.opts.Address, c.opts.SyncTimeout)
+				timeout.Store(true)
+			}
+		})
+	}
+	// The informers of the client are started by the cluster, once all handlers are added.
+	go client.Run(stop)
+	return nil
+}
+
+// ClusterUpdated replaces the client of the cluster. The current client keeps serving the config until the new
+// one has synced, so that a rotation of the secret does not remove the config of the cluster in between.
+func (c *Controller) ClusterUpdated(cluster *multicluster.Cluster, stop <-chan struct{}) error {
+	if cluster.ID != c.opts.ClusterID {
+		return nil
+	}
+	if c.current() == nil {
+		return c.ClusterAdded(cluster, stop)
+	}
+	client, err := c.newClient(cluster)
+	if err != nil {
+		return err
+	}
+	active := atomic.NewBool(false)
+	c.mu.Lock()
+	c.pending, c.pendingActive = client, active
+	for kind, handlers := range c.handlers {
+		for _, h := range handlers {
+			registerHandler(client, kind, h, active)
+		}
+	}
+	c.mu.Unlock()
+
+	log.Infof("updating config source %s from cluster %s", c.opts.Address, cluster.ID)
+	go client.Run(stop)
+	go c.switchClient(client, active, stop)
+	return nil
+}
+
+// switchClient replaces the current client with the updated client once it has synced, and notifies the
+// handlers of the differences between the two.
+func (c *Controller) switchClient(client *crdclient.Client, active *atomic.Bool, stop <-chan struct{}) {
+	if !kube.WaitForCacheSync(stop, client.HasSynced) {
+		return
+	}
+	c.mu.Lock()
+	if c.pending != client {
+		// The cluster was updated or deleted again in the meantime.
+		c.mu.Unlock()
+		return
+	}
+	old := c.client
+	c.client = client
+	c.pending, c.pendingActive = nil, nil
+	c.syncTimeout = atomic.NewBool(false)
+	active.Store(true)
+	c.mu.Unlock()
+	log.Infof("config source %s switched to the updated cluster", c.opts.Address)
+	c.notifyReplaced(old, client)
+}
+
+// ClusterDeleted removes all the config read from the cluster.
+func (c *Controller) ClusterDeleted(clusterID cluster.ID) error {
+	if clusterID != c.opts.ClusterID {
+		return nil
+	}
+	c.mu.Lock()
+	old := c.client
+	c.client = nil
+	c.pending, c.pendingActive = nil, nil
+	c.mu.Unlock()
+	if old != nil {
+		log.Infof("removing config source %s, cluster %s was deleted", c.opts.Address, clusterID)
+		c.notifyReplaced(old, nil)
+	}
+	return nil
+}
+
+// notifyReplaced sends the events for the configs that changed between a client and the client that replaced
+// it. If cur is nil, delete events are sent for all the configs of old.
+func (c *Controller) notifyReplaced(old, cur *crdclient.Client) {
+	c.mu.RLock()
+	handlers := make(map[config.GroupVersionKind][]model.EventHandler, len(c.handlers))
+	for kind, h := range c.handlers {
+		handlers[kind] = h
+	}
+	c.mu.RUnlock()
+	notify := func(hs []model.EventHandler, prev, cfg config.Config, event model.Event) {
+		for _, h := range hs {
+			h(prev, cfg, event)
+		}
+	}
+	for kind, hs := range handlers {
+		configs, err := old.List(kind, model.NamespaceAll)
+		if err != nil {
+			continue
+		}
+		removed := make(map[string]config.Config, len(configs))
+		for _, cfg := range configs {
+			removed[cfg.Namespace+"/"+cfg.Name] = cfg
+		}
+		if cur != nil {
+			current, err := cur.List(kind, model.NamespaceAll)
+			if err != nil {
+				continue
+			}
+			for _, cfg := range current {
+				key := cfg.Namespace + "/" + cfg.Name
+				prev, f := removed[key]
+				delete(removed, key)
+				switch {
+				case !f:
+					notify(hs, config.Config{}, cfg, model.EventAdd)
+				case prev.ResourceVersion != cfg.ResourceVersion:
+					notify(hs, prev, cfg, model.EventUpdate)
+				}
+			}
+		}
+		for _, cfg := range removed {
+			notify(hs, config.Config{}, cfg, model.EventDelete)
+		}
+	}
+}
+
+// SyncStatus returns the sync status of the source: pending until the cluster is added, then syncing until its
+// config is synced, or timeout if it did not sync in time.
+func (c *Controller) SyncStatus() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch {
+	case c.client == nil:
+		return StatusPending
+	case c.client.HasSynced():
+		return StatusSynced
+	case c.syncTimeout.Load():
+		return StatusTimeout
+	default:
+		return StatusSyncing
+	}
+}
+
+// DebugInfo returns debug information about the source.
+func (c *Controller) DebugInfo() cluster.ConfigSourceDebugInfo {
+	namespaces := append([]string(nil), c.opts.Namespaces...)
+	sort.Strings(namespaces)
+	return cluster.ConfigSourceDebugInfo{
+		Address:    c.opts.Address,
+		ClusterID:  c.opts.ClusterID,
+		Namespaces: namespaces,
+		SyncStatus: c.SyncStatus(),
+	}
+}
+
+func (c *Controller) current() *crdclient.Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.client
+}
+
+func (c *Controller) Schemas() collection.Schemas {
+	return collections.Pilot
+}
+
+func (c *Controller) Get(typ config.GroupVersionKind, name, namespace string) *config.Config {
+	client := c.current()
+	if client == nil {
+		return nil
+	}
+	return client.Get(typ, name, namespace)
+}
+
+func (c *Controller) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+	client := c.current()
+	if client == nil {
+		return nil, nil
+	}
+	return client.List(typ, namespace)
+}
+
+func (c *Controller) Create(config.Config) (string, error) {
+	return "", errorUnsupported
+}
+
+func (c *Controller) Update(config.Config) (string, error) {
+	return "", errorUnsupported
+}
+
+func (c *Controller) UpdateStatus(config.Config) (string, error) {
+	return "", errorUnsupported
+}
+
+func (c *Controller) Patch(config.Config, config.PatchFunc) (string, error) {
+	return "", errorUnsupported
+}
+
+func (c *Controller) Delete(config.GroupVersionKind, string, string, *string) error {
+	return errorUnsupported
+}
+
+func (c *Controller) RegisterEventHandler(kind config.GroupVersionKind, handler model.EventHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[kind] = append(c.handlers[kind], handler)
+	if c.client != nil {
+		c.client.RegisterEventHandler(kind, handler)
+	}
+	if c.pending != nil {
+		registerHandler(c.pending, kind, handler, c.pendingActive)
+	}
+}
+
+// Run marks the store as started. The client of the cluster is run when the cluster is added.
+func (c *Controller) Run(stop <-chan struct{}) {
+	c.started.Store(true)
+	<-stop
+}
+
+func (c *Controller) SetWatchErrorHandler(handler func(r *cache.Reflector, err error)) error {
+	if client := c.current(); client != nil {
+		return client.SetWatchErrorHandler(handler)
+	}
+	return nil
+}
+
+func (c *Controller) HasStarted() bool {
+	return c.started.Load()
+}
+
+// HasSynced returns true once the config of the cluster is synced. If the cluster is not known once the
+// clusters present at startup are processed, or does not sync in time, the source is considered synced so that
+// it does not block istiod from becoming ready.
+func (c *Controller) HasSynced() bool {
+	switch c.SyncStatus() {
+	case StatusPending:
+		return c.opts.ClustersSynced == nil || c.opts.ClustersSynced()
+	case StatusSyncing:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package remote
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metadatafake "k8s.io/client-go/metadata/fake"
+
+	"istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/multicluster"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func createCRD(t test.Failer, client kube.Client) {
+	t.Helper()
+	r := collections.IstioNetworkingV1Alpha3Virtualservices.Resource()
+	crd := &v1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("%s.%s", r.Plural(), r.Group()),
+		},
+	}
+	if _, err := client.Ext().ApiextensionsV1().CustomResourceDefinitions().Create(context.TODO(), crd, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// Metadata client fake is not kept in sync, so update that as well
+	fmc := client.Metadata().(*metadatafake.FakeMetadataClient)
+	fmd := fmc.Resource(collections.K8SApiextensionsK8SIoV1Customresourcedefinitions.Resource().GroupVersionResource()).(metadatafake.MetadataClient)
+	if _, err := fmd.CreateFake(&metav1.PartialObjectMetadata{ObjectMeta: crd.ObjectMeta}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func createVirtualService(t test.Failer, client kube.Client, name, namespace string) {
+	t.Helper()
+	vs := &clientnetworking.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec:       v1alpha3.VirtualService{Hosts: []string{name}},
+	}
+	if _, err := client.Istio().NetworkingV1alpha3().VirtualServices(namespace).Create(context.TODO(), vs, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func names(configs []config.Config) []string {
+	out := make([]string, 0, len(configs))
+	for _, c := range configs {
+		out = append(out, c.Namespace+"/"+c.Name)
+	}
+	return out
+}
+
+func TestController(t *testing.T) {
+	stop := test.NewStop(t)
+	remote := kube.NewFakeClient()
+	createCRD(t, remote)
+	createVirtualService(t, remote, "a", "istio-config")
+	createVirtualService(t, remote, "b", "other")
+
+	c := NewController(Options{
+		Address:        "k8s:///remote?namespaces=istio-config",
+		ClusterID:      "remote",
+		Namespaces:     []string{"istio-config"},
+		ClustersSynced: func() bool { return true },
+	})
+	events := make(chan model.Event, 10)
+	c.RegisterEventHandler(gvk.VirtualService, func(_ config.Config, _ config.Config, e model.Event) {
+		events <- e
+	})
+	go c.Run(stop)
+
+	// The source does not block readiness if its cluster is not known.
+	assert.Equal(t, c.SyncStatus(), StatusPending)
+	assert.Equal(t, c.HasSynced(), true)
+	configs, err := c.List(gvk.VirtualService, model.NamespaceAll)
+	assert.NoError(t, err)
+	assert.Equal(t, len(configs), 0)
+
+	// Other clusters are ignored.
+	assert.NoError(t, c.ClusterAdded(&multicluster.Cluster{ID: "other", Client: kube.NewFakeClient()}, stop))
+	assert.Equal(t, c.SyncStatus(), StatusPending)
+
+	assert.NoError(t, c.ClusterAdded(&multicluster.Cluster{ID: "remote", Client: remote}, stop))
+	remote.RunAndWait(stop)
+	retry.UntilOrFail(t, c.HasSynced, retry.Timeout(5*time.Second))
+	assert.Equal(t, c.SyncStatus(), StatusSynced)
+	assert.Equal(t, <-events, model.EventAdd)
+
+	configs, err = c.List(gvk.VirtualService, model.NamespaceAll)
+	assert.NoError(t, err)
+	assert.Equal(t, names(configs), []string{"istio-config/a"})
+	assert.Equal(t, c.Get(gvk.VirtualService, "b", "other") == nil, true)
+
+	_, err = c.Create(configs[0])
+	assert.Error(t, err)
+
+	assert.NoError(t, c.ClusterDeleted("remote"))
+	assert.Equal(t, <-events, model.EventDelete)
+	assert.Equal(t, c.SyncStatus(), StatusPending)
+	configs, err = c.List(gvk.VirtualService, model.NamespaceAll)
+	assert.NoError(t, err)
+	assert.Equal(t, len(configs), 0)
+	assert.Equal(t, c.DebugInfo().Namespaces, []string{"istio-config"})
+}
+
+func TestControllerUpdate(t *testing.T) {
+	stop := test.NewStop(t)
+	remote := kube.NewFakeClient()
+	createCRD(t, remote)
+	createVirtualService(t, remote, "a", "istio-config")
+	createVirtualService(t, remote, "c", "istio-config")
+
+	c := NewController(Options{
+		Address:        "k8s:///remote",
+		ClusterID:      "remote",
+		ClustersSynced: func() bool { return true },
+	})
+	events := make(chan string, 10)
+	c.RegisterEventHandler(gvk.VirtualService, func(_ config.Config, cfg config.Config, e model.Event) {
+		events <- e.String() + " " + cfg.Namespace + "/" + cfg.Name
+	})
+	go c.Run(stop)
+
+	assert.NoError(t, c.ClusterAdded(&multicluster.Cluster{ID: "remote", Client: remote}, stop))
+	remote.RunAndWait(stop)
+	retry.UntilOrFail(t, c.HasSynced, retry.Timeout(5*time.Second))
+	<-events
+	<-events
+
+	// The rotated secret gives a new client. The config of the old client is served until it syncs, and only
+	// the differences are notified.
+	updated := kube.NewFakeClient()
+	createCRD(t, updated)
+	createVirtualService(t, updated, "a", "istio-config")
+	createVirtualService(t, updated, "b", "istio-config")
+	assert.NoError(t, c.ClusterUpdated(&multicluster.Cluster{ID: "remote", Client: updated}, stop))
+	configs, err := c.List(gvk.VirtualService, model.NamespaceAll)
+	assert.NoError(t, err)
+	assert.Equal(t, len(configs), 2)
+	updated.RunAndWait(stop)
+
+	assert.Equal(t, <-events, "add istio-config/b")
+	assert.Equal(t, <-events, "delete istio-config/c")
+	configs, err = c.List(gvk.VirtualService, model.NamespaceAll)
+	assert.NoError(t, err)
+	assert.Equal(t, len(configs), 2)
+	assert.Equal(t, c.Get(gvk.VirtualService, "b", "istio-config") != nil, true)
+	assert.Equal(t, c.SyncStatus(), StatusSynced)
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event %s", e)
+	default:
+	}
+}
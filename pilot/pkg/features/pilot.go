@@ -15,6 +15,7 @@
 package features
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
@@ -73,6 +74,30 @@ var (
 		"Limits the number of concurrent pushes allowed. On larger machines this can be increased for faster pushes",
 	).Get()
 
+	PushQueueWeights = func() map[string]int {
+		v := env.Register(
+			"PILOT_PUSH_QUEUE_WEIGHTS",
+			"router=4,sidecar=1",
+			"Comma separated list of <proxy type>=<weight> pairs. When proxies of several types are waiting in the push queue, "+
+				"they are dequeued in proportion to these weights, so by default gateways are pushed before sidecars. "+
+				"Proxy types that are not listed have a weight of 1.",
+		).Get()
+		weights := map[string]int{}
+		for _, pair := range strings.Split(v, ",") {
+			if pair == "" {
+				continue
+			}
+			nodeType, weight, _ := strings.Cut(pair, "=")
+			w, err := strconv.Atoi(weight)
+			if err != nil || w < 1 {
+				log.Warnf("ignoring invalid PILOT_PUSH_QUEUE_WEIGHTS entry %q", pair)
+				continue
+			}
+			weights[strings.TrimSpace(nodeType)] = w
+		}
+		return weights
+	}()
+
 	RequestLimit = env.Register(
 		"PILOT_MAX_REQUESTS_PER_SECOND",
 		25.0,
@@ -98,6 +123,26 @@ var (
 			"for this time, we'll trigger a push.",
 	).Get()
 
+	EnableAdaptiveDebounce = env.Register(
+		"PILOT_ENABLE_ADAPTIVE_DEBOUNCE",
+		false,
+		"If enabled, the quiet period used for debouncing is adjusted based on recent push duration, push queue depth "+
+			"and config event rate, between PILOT_ADAPTIVE_DEBOUNCE_MIN and PILOT_ADAPTIVE_DEBOUNCE_MAX. "+
+			"PILOT_DEBOUNCE_AFTER is used as the initial quiet period, and PILOT_DEBOUNCE_MAX still bounds the total delay.",
+	).Get()
+
+	AdaptiveDebounceMin = env.Register(
+		"PILOT_ADAPTIVE_DEBOUNCE_MIN",
+		50*time.Millisecond,
+		"The shortest quiet period used for debouncing when adaptive debounce is enabled.",
+	).Get()
+
+	AdaptiveDebounceMax = env.Register(
+		"PILOT_ADAPTIVE_DEBOUNCE_MAX",
+		2*time.Second,
+		"The longest quiet period used for debouncing when adaptive debounce is enabled.",
+	).Get()
+
 	EnableEDSDebounce = env.Register(
 		"PILOT_ENABLE_EDS_DEBOUNCE",
 		true,
@@ -326,6 +371,21 @@ var (
 		return val
 	}()
 
+	EnableAnalysisEvents = env.Register(
+		"PILOT_ENABLE_ANALYSIS_EVENTS",
+		false,
+		"If analysis is enabled, pilot will also emit a Kubernetes Event on the affected object when an analysis "+
+			"message is first reported. Requires the RBAC rule to create and patch events, which the istiod chart "+
+			"grants when this is set in pilot.env.",
+	).Get()
+
+	EnableAnalysisMetrics = env.Register(
+		"PILOT_ENABLE_ANALYSIS_METRICS",
+		false,
+		"If analysis is enabled, pilot will also export the number of active analysis messages per code, "+
+			"namespace and level as the pilot_analysis_messages metric.",
+	).Get()
+
 	EnableStatus = env.Register(
 		"PILOT_ENABLE_STATUS",
 		false,
@@ -437,6 +497,12 @@ var (
 		return strings.Split(cidr, ",")
 	}()
 
+	ConsulToken = env.Register(
+		"CONSUL_HTTP_TOKEN",
+		"",
+		"The ACL token used by the Consul service registry to read the Consul catalog.",
+	).Get()
+
 	EnableServiceEntrySelectPods = env.Register("PILOT_ENABLE_SERVICEENTRY_SELECT_PODS", true,
 		"If enabled, service entries with selectors will select pods from the cluster. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
@@ -479,6 +545,13 @@ var (
 	XDSCacheMaxSize = env.Register("PILOT_XDS_CACHE_SIZE", 60000,
 		"The maximum number of cache entries for the XDS cache.").Get()
 
+	XDSCachePersistDir = env.Register("PILOT_XDS_CACHE_PERSIST_DIR", "",
+		"If set, Pilot will periodically persist the XDS cache to this directory and warm the cache from it on startup. "+
+			"Entries whose dependent configs changed in the meantime are not restored. Note: this depends on PILOT_ENABLE_XDS_CACHE.").Get()
+
+	XDSCachePersistInterval = env.Register("PILOT_XDS_CACHE_PERSIST_INTERVAL", 5*time.Minute,
+		"The interval at which the XDS cache is persisted, if PILOT_XDS_CACHE_PERSIST_DIR is set.").Get()
+
 	// Note: while this appears unused in the go code, this sets a default which is used in the injection template.
 	EnableLegacyFSGroupInjection = env.Register("ENABLE_LEGACY_FSGROUP_INJECTION", false,
 		"If true, Istiod will set the pod fsGroup to 1337 on injection. This is required for Kubernetes 1.18 and older "+
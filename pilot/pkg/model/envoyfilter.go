@@ -85,64 +85,74 @@ func convertToEnvoyFilterWrapper(local *config.Config) *EnvoyFilterWrapper {
 	}
 	out.Patches = make(map[networking.EnvoyFilter_ApplyTo][]*EnvoyFilterConfigPatchWrapper)
 	for _, cp := range localEnvoyFilter.ConfigPatches {
-		if cp.Patch == nil {
-			// Should be caught by validation, but sometimes its disabled and we don't want to crash
-			// as a result.
-			log.Debugf("envoyfilter %v/%v discarded due to missing patch", local.Namespace, local.Name)
+		cpw := ConvertEnvoyFilterConfigPatch(local.Name, local.Namespace, cp)
+		if cpw == nil {
 			continue
 		}
-		cpw := &EnvoyFilterConfigPatchWrapper{
-			Name:      local.Name,
-			Namespace: local.Namespace,
-			ApplyTo:   cp.ApplyTo,
-			Match:     cp.Match,
-			Operation: cp.Patch.Operation,
-		}
-		var err error
-		// Use non-strict building to avoid issues where EnvoyFilter is valid but meant
-		// for a different version of the API than we are built with
-		cpw.Value, err = xds.BuildXDSObjectFromStruct(cp.ApplyTo, cp.Patch.Value, false)
-		// There generally won't be an error here because validation catches mismatched types
-		// Should only happen in tests or without validation
-		if err != nil {
-			log.Errorf("failed to build envoy filter value: %v", err)
-		}
-		if cp.Match == nil {
-			// create a match all object
-			cpw.Match = &networking.EnvoyFilter_EnvoyConfigObjectMatch{Context: networking.EnvoyFilter_ANY}
-		} else if cp.Match.Proxy != nil && cp.Match.Proxy.ProxyVersion != "" {
-			// Attempt to convert regex to a simple prefix match for the common case of matching
-			// a standard Istio version. This field should likely be replaced with semver, but for now
-			// we can workaround the performance impact of regex
-			if prefix, f := wellKnownVersions[cp.Match.Proxy.ProxyVersion]; f {
-				cpw.ProxyPrefixMatch = prefix
-			} else {
-				// pre-compile the regex for proxy version if it exists
-				// ignore the error because validation catches invalid regular expressions.
-				cpw.ProxyVersionRegex, _ = regexp.Compile(cp.Match.Proxy.ProxyVersion)
-			}
-		}
-
 		if _, exists := out.Patches[cp.ApplyTo]; !exists {
 			out.Patches[cp.ApplyTo] = make([]*EnvoyFilterConfigPatchWrapper, 0)
 		}
-		if cpw.Operation == networking.EnvoyFilter_Patch_INSERT_AFTER ||
-			cpw.Operation == networking.EnvoyFilter_Patch_INSERT_BEFORE ||
-			cpw.Operation == networking.EnvoyFilter_Patch_INSERT_FIRST {
-			// insert_before, after or first is applicable for listener filter, network filter,
-			// http filter and http route, convert the rest to add
-			if cpw.ApplyTo != networking.EnvoyFilter_HTTP_FILTER &&
-				cpw.ApplyTo != networking.EnvoyFilter_NETWORK_FILTER &&
-				cpw.ApplyTo != networking.EnvoyFilter_HTTP_ROUTE &&
-				cpw.ApplyTo != networking.EnvoyFilter_LISTENER_FILTER {
-				cpw.Operation = networking.EnvoyFilter_Patch_ADD
-			}
-		}
 		out.Patches[cp.ApplyTo] = append(out.Patches[cp.ApplyTo], cpw)
 	}
 	return out
 }
 
+// ConvertEnvoyFilterConfigPatch converts a config patch of the EnvoyFilter with the given name and namespace to
+// an EnvoyFilterConfigPatchWrapper. It returns nil if the config patch has no patch.
+func ConvertEnvoyFilterConfigPatch(name, namespace string, cp *networking.EnvoyFilter_EnvoyConfigObjectPatch) *EnvoyFilterConfigPatchWrapper {
+	if cp.Patch == nil {
+		// Should be caught by validation, but sometimes its disabled and we don't want to crash
+		// as a result.
+		log.Debugf("envoyfilter %v/%v discarded due to missing patch", namespace, name)
+		return nil
+	}
+	cpw := &EnvoyFilterConfigPatchWrapper{
+		Name:      name,
+		Namespace: namespace,
+		ApplyTo:   cp.ApplyTo,
+		Match:     cp.Match,
+		Operation: cp.Patch.Operation,
+	}
+	var err error
+	// Use non-strict building to avoid issues where EnvoyFilter is valid but meant
+	// for a different version of the API than we are built with
+	cpw.Value, err = xds.BuildXDSObjectFromStruct(cp.ApplyTo, cp.Patch.Value, false)
+	// There generally won't be an error here because validation catches mismatched types
+	// Should only happen in tests or without validation
+	if err != nil {
+		log.Errorf("failed to build envoy filter value: %v", err)
+	}
+	if cp.Match == nil {
+		// create a match all object
+		cpw.Match = &networking.EnvoyFilter_EnvoyConfigObjectMatch{Context: networking.EnvoyFilter_ANY}
+	} else if cp.Match.Proxy != nil && cp.Match.Proxy.ProxyVersion != "" {
+		// Attempt to convert regex to a simple prefix match for the common case of matching
+		// a standard Istio version. This field should likely be replaced with semver, but for now
+		// we can workaround the performance impact of regex
+		if prefix, f := wellKnownVersions[cp.Match.Proxy.ProxyVersion]; f {
+			cpw.ProxyPrefixMatch = prefix
+		} else {
+			// pre-compile the regex for proxy version if it exists
+			// ignore the error because validation catches invalid regular expressions.
+			cpw.ProxyVersionRegex, _ = regexp.Compile(cp.Match.Proxy.ProxyVersion)
+		}
+	}
+
+	if cpw.Operation == networking.EnvoyFilter_Patch_INSERT_AFTER ||
+		cpw.Operation == networking.EnvoyFilter_Patch_INSERT_BEFORE ||
+		cpw.Operation == networking.EnvoyFilter_Patch_INSERT_FIRST {
+		// insert_before, after or first is applicable for listener filter, network filter,
+		// http filter and http route, convert the rest to add
+		if cpw.ApplyTo != networking.EnvoyFilter_HTTP_FILTER &&
+			cpw.ApplyTo != networking.EnvoyFilter_NETWORK_FILTER &&
+			cpw.ApplyTo != networking.EnvoyFilter_HTTP_ROUTE &&
+			cpw.ApplyTo != networking.EnvoyFilter_LISTENER_FILTER {
+			cpw.Operation = networking.EnvoyFilter_Patch_ADD
+		}
+	}
+	return cpw
+}
+
 func proxyMatch(proxy *Proxy, cp *EnvoyFilterConfigPatchWrapper) bool {
 	if cp.Match.Proxy == nil {
 		return true
@@ -206,3 +216,8 @@ func (cpw *EnvoyFilterConfigPatchWrapper) Key() string {
 	}
 	return cpw.Namespace + "/" + cpw.Name
 }
+
+// MatchesProxy returns true if the proxy version and metadata of the proxy match the patch.
+func (cpw *EnvoyFilterConfigPatchWrapper) MatchesProxy(proxy *Proxy) bool {
+	return proxyMatch(proxy, cpw)
+}
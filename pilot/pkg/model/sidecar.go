@@ -597,6 +597,14 @@ func (sc *SidecarScope) Services() []*Service {
 	return sc.services
 }
 
+// ServiceForHostname returns the service visible to a sidecar with the given hostname, or nil if there is none.
+func (sc *SidecarScope) ServiceForHostname(hostname host.Name) *Service {
+	if sc == nil {
+		return nil
+	}
+	return sc.servicesByHostname[hostname]
+}
+
 // Return filtered services through the hosts field in the egress portion of the Sidecar config.
 // Note that the returned service could be trimmed.
 func (ilw *IstioEgressListenerWrapper) selectServices(services []*Service, configNamespace string, hosts map[string][]host.Name) []*Service {
@@ -0,0 +1,184 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/istio/pkg/config/schema/kind"
+)
+
+// xdsCacheSnapshotFormat is the version of the on-disk snapshot format. It must be bumped whenever the
+// format changes, so that older snapshots are ignored rather than misread.
+const xdsCacheSnapshotFormat = 1
+
+// XdsCacheVersions describes the versions of the configs XDS cache entries may depend on.
+// A persisted entry is only restored if the versions of all its dependencies are unchanged.
+type XdsCacheVersions struct {
+	// Global identifies everything that is not tracked per entry, such as the istiod build and the mesh config.
+	// If it changes, none of the persisted entries are restored.
+	Global string
+	// Configs holds the version of each known config. Configs without a known version are never persisted.
+	Configs map[ConfigHash]string
+	// Types holds the version of all configs of a kind, for entries that depend on a whole type.
+	Types map[kind.Kind]string
+}
+
+// XdsCacheSnapshotter is implemented by XDS caches that can be persisted, so that they can be warmed on restart.
+type XdsCacheSnapshotter interface {
+	// WriteSnapshot writes all entries whose dependencies have a known version, returning the number written.
+	WriteSnapshot(w io.Writer, versions XdsCacheVersions) (int, error)
+	// ReadSnapshot adds the persisted entries whose dependencies did not change, returning the number restored.
+	ReadSnapshot(r io.Reader, versions XdsCacheVersions) (int, error)
+}
+
+type xdsCacheSnapshot struct {
+	Format  int                     `json:"format"`
+	Global  string                  `json:"global"`
+	Entries []xdsCacheSnapshotEntry `json:"entries"`
+}
+
+type xdsCacheSnapshotEntry struct {
+	Key              string                `json:"key"`
+	Value            []byte                `json:"value"`
+	DependentConfigs map[ConfigHash]string `json:"dependentConfigs,omitempty"`
+	DependentTypes   map[kind.Kind]string  `json:"dependentTypes,omitempty"`
+}
+
+var _ XdsCacheSnapshotter = &lruCache{}
+
+func (l *lruCache) WriteSnapshot(w io.Writer, versions XdsCacheVersions) (int, error) {
+	snapshot := xdsCacheSnapshot{Format: xdsCacheSnapshotFormat, Global: versions.Global}
+	l.mu.RLock()
+	for _, ik := range l.store.Keys() {
+		v, ok := l.store.Peek(ik)
+		if !ok {
+			continue
+		}
+		cv := v.(cacheValue)
+		if cv.value == nil {
+			continue
+		}
+		entry, ok := newXdsCacheSnapshotEntry(ik.(string), cv, versions)
+		if !ok {
+			continue
+		}
+		value, err := proto.Marshal(cv.value)
+		if err != nil {
+			l.mu.RUnlock()
+			return 0, fmt.Errorf("failed to marshal cache entry %v: %v", ik, err)
+		}
+		entry.Value = value
+		snapshot.Entries = append(snapshot.Entries, entry)
+	}
+	l.mu.RUnlock()
+
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		return 0, err
+	}
+	return len(snapshot.Entries), nil
+}
+
+// newXdsCacheSnapshotEntry records the current versions of the entry dependencies. Entries depending on configs
+// with an unknown version cannot be validated on restore, so they are skipped.
+func newXdsCacheSnapshotEntry(key string, cv cacheValue, versions XdsCacheVersions) (xdsCacheSnapshotEntry, bool) {
+	entry := xdsCacheSnapshotEntry{
+		Key:              key,
+		DependentConfigs: make(map[ConfigHash]string, len(cv.dependentConfigs)),
+		DependentTypes:   make(map[kind.Kind]string, len(cv.dependentTypes)),
+	}
+	for _, cfg := range cv.dependentConfigs {
+		v, f := versions.Configs[cfg]
+		if !f || v == "" {
+			return entry, false
+		}
+		entry.DependentConfigs[cfg] = v
+	}
+	for _, t := range cv.dependentTypes {
+		v, f := versions.Types[t]
+		if !f || v == "" {
+			return entry, false
+		}
+		entry.DependentTypes[t] = v
+	}
+	return entry, true
+}
+
+func (l *lruCache) ReadSnapshot(r io.Reader, versions XdsCacheVersions) (int, error) {
+	snapshot := xdsCacheSnapshot{}
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return 0, err
+	}
+	if snapshot.Format != xdsCacheSnapshotFormat {
+		return 0, fmt.Errorf("unsupported snapshot format %v", snapshot.Format)
+	}
+	if snapshot.Global != versions.Global {
+		// Built by a different istiod or for a different mesh config, nothing can be trusted.
+		return 0, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	restored := 0
+	for _, entry := range snapshot.Entries {
+		if !entry.upToDate(versions) {
+			continue
+		}
+		if _, f := l.store.Peek(entry.Key); f {
+			// Already generated since startup, which is always fresher.
+			continue
+		}
+		value := &discovery.Resource{}
+		if err := proto.Unmarshal(entry.Value, value); err != nil {
+			log.Warnf("skipping xds cache entry %v: %v", entry.Key, err)
+			continue
+		}
+		dependentConfigs := make([]ConfigHash, 0, len(entry.DependentConfigs))
+		for cfg := range entry.DependentConfigs {
+			dependentConfigs = append(dependentConfigs, cfg)
+		}
+		dependentTypes := make([]kind.Kind, 0, len(entry.DependentTypes))
+		for t := range entry.DependentTypes {
+			dependentTypes = append(dependentTypes, t)
+		}
+		// A zero token ensures any entry generated after startup replaces the restored one.
+		l.store.Add(entry.Key, cacheValue{value: value, dependentConfigs: dependentConfigs, dependentTypes: dependentTypes})
+		l.updateConfigIndex(entry.Key, dependentConfigs)
+		l.updateTypesIndex(entry.Key, dependentTypes)
+		restored++
+	}
+	size(l.store.Len())
+	return restored, nil
+}
+
+// upToDate returns true if none of the entry dependencies changed since it was persisted.
+func (e xdsCacheSnapshotEntry) upToDate(versions XdsCacheVersions) bool {
+	for cfg, v := range e.DependentConfigs {
+		if versions.Configs[cfg] != v {
+			return false
+		}
+	}
+	for t, v := range e.DependentTypes {
+		if versions.Types[t] != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func TestXdsCacheSnapshot(t *testing.T) {
+	svc := ConfigKey{Kind: kind.ServiceEntry, Name: "foo.com", Namespace: "default"}.HashCode()
+	dr := ConfigKey{Kind: kind.DestinationRule, Name: "foo", Namespace: "default"}.HashCode()
+	unversioned := ConfigKey{Kind: kind.ServiceEntry, Name: "bar.com", Namespace: "default"}.HashCode()
+	versions := XdsCacheVersions{
+		Global:  "1",
+		Configs: map[ConfigHash]string{svc: "1", dr: "1"},
+		Types:   map[kind.Kind]string{kind.PeerAuthentication: "1"},
+	}
+	entries := []*entry{
+		{key: "svc", dependentConfigs: []ConfigHash{svc}},
+		{key: "svc-dr", dependentConfigs: []ConfigHash{svc, dr}},
+		{key: "svc-pa", dependentConfigs: []ConfigHash{svc}, dependentTypes: []kind.Kind{kind.PeerAuthentication}},
+		{key: "unversioned", dependentConfigs: []ConfigHash{unversioned}},
+	}
+	req := &PushRequest{Start: time.Now()}
+
+	cache := NewXdsCache()
+	for _, e := range entries {
+		cache.Add(e, req, &discovery.Resource{Name: e.key})
+	}
+	buf := &bytes.Buffer{}
+	written, err := cache.(XdsCacheSnapshotter).WriteSnapshot(buf, versions)
+	assert.NoError(t, err)
+	// The entry depending on a config without a version cannot be validated, so it is not persisted
+	assert.Equal(t, written, 3)
+	snapshot := buf.Bytes()
+
+	restore := func(versions XdsCacheVersions) XdsCache {
+		t.Helper()
+		c := NewXdsCache()
+		_, err := c.(XdsCacheSnapshotter).ReadSnapshot(bytes.NewReader(snapshot), versions)
+		assert.NoError(t, err)
+		return c
+	}
+	keys := func(c XdsCache) []string {
+		k := c.Keys()
+		sort.Strings(k)
+		return k
+	}
+
+	t.Run("unchanged", func(t *testing.T) {
+		c := restore(versions)
+		assert.Equal(t, keys(c), []string{"svc", "svc-dr", "svc-pa"})
+		res, f := c.Get(entries[0])
+		assert.Equal(t, f, true)
+		assert.Equal(t, res.Name, "svc")
+		// Restored entries are indexed, so they are invalidated like any other entry
+		c.Clear(sets.New(ConfigKey{Kind: kind.DestinationRule, Name: "foo", Namespace: "default"}))
+		assert.Equal(t, keys(c), []string{"svc", "svc-pa"})
+	})
+	t.Run("config changed", func(t *testing.T) {
+		c := restore(XdsCacheVersions{
+			Global:  "1",
+			Configs: map[ConfigHash]string{svc: "1", dr: "2"},
+			Types:   map[kind.Kind]string{kind.PeerAuthentication: "1"},
+		})
+		assert.Equal(t, keys(c), []string{"svc", "svc-pa"})
+	})
+	t.Run("config removed", func(t *testing.T) {
+		c := restore(XdsCacheVersions{
+			Global:  "1",
+			Configs: map[ConfigHash]string{dr: "1"},
+			Types:   map[kind.Kind]string{kind.PeerAuthentication: "1"},
+		})
+		assert.Equal(t, keys(c), []string{})
+	})
+	t.Run("type changed", func(t *testing.T) {
+		c := restore(XdsCacheVersions{
+			Global:  "1",
+			Configs: map[ConfigHash]string{svc: "1", dr: "1"},
+			Types:   map[kind.Kind]string{kind.PeerAuthentication: "2"},
+		})
+		assert.Equal(t, keys(c), []string{"svc", "svc-dr"})
+	})
+	t.Run("global changed", func(t *testing.T) {
+		c := restore(XdsCacheVersions{
+			Global:  "2",
+			Configs: map[ConfigHash]string{svc: "1", dr: "1"},
+			Types:   map[kind.Kind]string{kind.PeerAuthentication: "1"},
+		})
+		assert.Equal(t, keys(c), []string{})
+	})
+	t.Run("newer entries are kept", func(t *testing.T) {
+		c := NewLenientXdsCache()
+		c.Add(entries[0], req, &discovery.Resource{Name: "fresh"})
+		_, err := c.(XdsCacheSnapshotter).ReadSnapshot(bytes.NewReader(snapshot), versions)
+		assert.NoError(t, err)
+		res, _ := c.Get(entries[0])
+		assert.Equal(t, res.Name, "fresh")
+		// Restored entries never override later additions
+		c.Add(entries[1], &PushRequest{Start: time.Now()}, &discovery.Resource{Name: "regenerated"})
+		res, _ = c.Get(entries[1])
+		assert.Equal(t, res.Name, "regenerated")
+	})
+}
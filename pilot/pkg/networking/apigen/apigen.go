@@ -100,7 +100,11 @@ func (g *APIGenerator) Generate(proxy *model.Proxy, w *model.WatchedResource, re
 		log.Warnf("ADS: Error reading resource %s %v", w.TypeUrl, err)
 		return resp, model.DefaultXdsLogDetails, nil
 	}
+	names := newNameFilter(w.ResourceNames)
 	for _, c := range cfg {
+		if !names.matches(c.Namespace, c.Name) {
+			continue
+		}
 		// Right now model.Config is not a proto - until we change it, mcp.Resource.
 		// This also helps migrating MCP users.
 
@@ -127,6 +131,9 @@ func (g *APIGenerator) Generate(proxy *model.Proxy, w *model.WatchedResource, re
 				continue
 			}
 			c := serviceentry.ServiceToServiceEntry(s, proxy)
+			if !names.matches(c.Namespace, c.Name) {
+				continue
+			}
 			b, err := config.PilotConfigToResource(c)
 			if err != nil {
 				log.Warn("Resource error ", err, " ", c.Namespace, "/", c.Name)
@@ -141,3 +148,37 @@ func (g *APIGenerator) Generate(proxy *model.Proxy, w *model.WatchedResource, re
 
 	return resp, model.DefaultXdsLogDetails, nil
 }
+
+// nameFilter matches configs against the resource names requested by a client. Names are of the form
+// <namespace>/<name>, or <namespace>/* to match all the configs of a namespace. A nil filter matches all configs,
+// as clients not sending names watch all resources.
+type nameFilter struct {
+	names      map[string]struct{}
+	namespaces map[string]struct{}
+}
+
+func newNameFilter(resourceNames []string) *nameFilter {
+	if len(resourceNames) == 0 {
+		return nil
+	}
+	f := &nameFilter{names: map[string]struct{}{}, namespaces: map[string]struct{}{}}
+	for _, n := range resourceNames {
+		if strings.HasSuffix(n, "/*") {
+			f.namespaces[strings.TrimSuffix(n, "/*")] = struct{}{}
+		} else {
+			f.names[n] = struct{}{}
+		}
+	}
+	return f
+}
+
+func (f *nameFilter) matches(namespace, name string) bool {
+	if f == nil {
+		return true
+	}
+	if _, ok := f.namespaces[namespace]; ok {
+		return true
+	}
+	_, ok := f.names[namespace+"/"+name]
+	return ok
+}
@@ -32,6 +32,11 @@ type ConfigGenerator interface {
 	// once and shared across multiple invocations of this function.
 	BuildListeners(node *model.Proxy, push *model.PushContext) []*listener.Listener
 
+	// BuildDeltaListeners returns both a list of listeners that need to be pushed for a given proxy and a list of listeners
+	// that have been deleted and should be removed from a given proxy. This is Delta LDS output.
+	BuildDeltaListeners(proxy *model.Proxy, updates *model.PushRequest,
+		watched *model.WatchedResource) ([]*listener.Listener, []string, bool)
+
 	// BuildClusters returns the list of clusters for the given proxy. This is the CDS output
 	BuildClusters(node *model.Proxy, req *model.PushRequest) ([]*discovery.Resource, model.XdsLogDetails)
 
@@ -43,6 +48,11 @@ type ConfigGenerator interface {
 	// BuildHTTPRoutes returns the list of HTTP routes for the given proxy. This is the RDS output
 	BuildHTTPRoutes(node *model.Proxy, req *model.PushRequest, routeNames []string) ([]*discovery.Resource, model.XdsLogDetails)
 
+	// BuildDeltaHTTPRoutes returns both a list of routes that need to be pushed for a given proxy and a list of routes
+	// that have been deleted and should be removed from a given proxy. This is Delta RDS output.
+	BuildDeltaHTTPRoutes(proxy *model.Proxy, updates *model.PushRequest,
+		watched *model.WatchedResource) ([]*discovery.Resource, []string, model.XdsLogDetails, bool)
+
 	// BuildNameTable returns list of hostnames and the associated IPs
 	BuildNameTable(node *model.Proxy, push *model.PushContext) *dnsProto.NameTable
 
@@ -105,6 +105,42 @@ func (configgen *ConfigGeneratorImpl) BuildHTTPRoutes(
 	return routeConfigurations, model.XdsLogDetails{AdditionalInfo: fmt.Sprintf("cached:%v/%v", hit, hit+miss)}
 }
 
+// BuildDeltaHTTPRoutes generates the deltas (changed and removed) of the routes for a given proxy. Currently, only
+// service changes for sidecars are reflected with deltas, by rebuilding only the watched routes serving the ports of
+// the changed services. Otherwise, we fall back onto generating all watched routes.
+func (configgen *ConfigGeneratorImpl) BuildDeltaHTTPRoutes(proxy *model.Proxy, updates *model.PushRequest,
+	watched *model.WatchedResource,
+) ([]*discovery.Resource, []string, model.XdsLogDetails, bool) {
+	ports, ok := deltaServicePorts(proxy, updates)
+	if !ok {
+		routes, lg := configgen.BuildHTTPRoutes(proxy, updates, watched.ResourceNames)
+		return routes, nil, lg, false
+	}
+	routeNames := make([]string, 0)
+	for _, routeName := range watched.ResourceNames {
+		if routeServesPorts(routeName, ports) {
+			routeNames = append(routeNames, routeName)
+		}
+	}
+	if len(routeNames) == 0 {
+		return nil, nil, model.DefaultXdsLogDetails, true
+	}
+	// Sidecars always get a route configuration for each requested name, so nothing is removed here;
+	// routes go away when Envoy unsubscribes from them.
+	routes, lg := configgen.BuildHTTPRoutes(proxy, updates, routeNames)
+	return routes, nil, lg, true
+}
+
+// routeServesPorts returns true if the sidecar route, named <port> or <hostname>:<port>, may carry configuration
+// for one of the ports. Routes not bound to a port (http_proxy, unix domain sockets) are always considered affected.
+func routeServesPorts(routeName string, ports sets.Set[int]) bool {
+	port, err := strconv.Atoi(routeName[strings.LastIndexByte(routeName, ':')+1:])
+	if err != nil {
+		return true
+	}
+	return ports.Contains(port)
+}
+
 // buildSidecarInboundHTTPRouteConfig builds the route config with a single wildcard virtual host on the inbound path
 // TODO: trace decorators, inbound timeouts
 func buildSidecarInboundHTTPRouteConfig(lb *ListenerBuilder, cc inboundChainConfig) *route.RouteConfiguration {
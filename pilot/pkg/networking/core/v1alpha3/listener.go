@@ -46,6 +46,7 @@ import (
 	"istio.io/istio/pkg/proto"
 	secconst "istio.io/istio/pkg/security"
 	netutil "istio.io/istio/pkg/util/net"
+	"istio.io/istio/pkg/util/sets"
 	"istio.io/pkg/log"
 	"istio.io/pkg/monitoring"
 )
@@ -111,6 +112,97 @@ func (configgen *ConfigGeneratorImpl) BuildListeners(node *model.Proxy,
 	return builder.getListeners()
 }
 
+// BuildDeltaListeners generates the deltas (changed and removed) of the listeners for a given proxy. All listeners are
+// still built, but when only services changed for a sidecar, just the listeners serving the ports of those services
+// (or listeners the proxy does not have yet) are returned as changed. Otherwise, we fall back onto returning everything.
+func (configgen *ConfigGeneratorImpl) BuildDeltaListeners(proxy *model.Proxy, updates *model.PushRequest,
+	watched *model.WatchedResource,
+) ([]*listener.Listener, []string, bool) {
+	listeners := configgen.BuildListeners(proxy, updates.Push)
+	// Without the names of the listeners the proxy currently has, we cannot compute removals.
+	if len(watched.ResourceNames) == 0 {
+		return listeners, nil, false
+	}
+	ports, ok := deltaServicePorts(proxy, updates)
+	if !ok {
+		return listeners, nil, false
+	}
+
+	known := sets.New(watched.ResourceNames...)
+	generated := sets.NewWithLength[string](len(listeners))
+	changed := make([]*listener.Listener, 0)
+	for _, l := range listeners {
+		generated.Insert(l.Name)
+		if !known.Contains(l.Name) || listenerServesPorts(l.Name, ports) {
+			changed = append(changed, l)
+		}
+	}
+	var deleted []string
+	for _, name := range watched.ResourceNames {
+		if !generated.Contains(name) {
+			deleted = append(deleted, name)
+		}
+	}
+	return changed, deleted, true
+}
+
+// deltaServicePorts returns the ports of the services changed by the request, which bound the listeners and routes
+// of a sidecar that need to be regenerated. Both the current and the previous version of each service are considered
+// so that removed services and ports are accounted for. It returns false if deltas cannot be used for the request.
+func deltaServicePorts(proxy *model.Proxy, updates *model.PushRequest) (sets.Set[int], bool) {
+	if proxy.Type != model.SidecarProxy || !shouldUseDelta(updates) {
+		return nil, false
+	}
+	// The previous scope tells us which services the proxy was configured with. If it was computed for the same push,
+	// the proxy state was refreshed out of band (for example on a request) and we no longer know the prior services.
+	if proxy.PrevSidecarScope == nil || proxy.SidecarScope == nil || proxy.PrevSidecarScope.Version == proxy.SidecarScope.Version {
+		return nil, false
+	}
+	for _, el := range proxy.SidecarScope.EgressListeners {
+		if el.IstioListener != nil && el.IstioListener.Port != nil {
+			// Services imported by an egress listener are served on the listener port rather than their own ports.
+			return nil, false
+		}
+	}
+	ports := sets.New[int]()
+	for key := range updates.ConfigsUpdated {
+		hostname := host.Name(key.Name)
+		for _, si := range proxy.ServiceInstances {
+			if si.Service.Hostname == hostname {
+				// Inbound listeners are keyed by the target port of the instances, not the service port.
+				return nil, false
+			}
+		}
+		for _, svc := range []*model.Service{proxy.SidecarScope.ServiceForHostname(hostname), proxy.PrevSidecarScope.ServiceForHostname(hostname)} {
+			if svc == nil {
+				continue
+			}
+			for _, port := range svc.Ports {
+				ports.Insert(port.Port)
+			}
+		}
+	}
+	return ports, true
+}
+
+// listenerServesPorts returns true if the listener, named <bind>_<port>, may carry configuration for one of the ports.
+// Listeners whose name does not carry a port are conservatively considered affected, except for the virtual listeners
+// which do not depend on outbound services.
+func listenerServesPorts(name string, ports sets.Set[int]) bool {
+	if name == model.VirtualOutboundListenerName || name == model.VirtualInboundListenerName {
+		return false
+	}
+	i := strings.LastIndexByte(name, '_')
+	if i == -1 {
+		return true
+	}
+	port, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return true
+	}
+	return ports.Contains(port)
+}
+
 func BuildListenerTLSContext(serverTLSSettings *networking.ServerTLSSettings,
 	proxy *model.Proxy, transportProtocol istionetworking.TransportProtocol, gatewayTCPServerWithTerminatingTLS bool,
 ) *auth.DownstreamTlsContext {
@@ -194,6 +194,8 @@ func addInternalParents(dependences []model.ConfigHash, cfg config.Config) []mod
 			switch kn[0] {
 			case kind.HTTPRoute.String():
 				k = kind.HTTPRoute
+			case kind.GRPCRoute.String():
+				k = kind.GRPCRoute
 			case kind.TCPRoute.String():
 				k = kind.TCPRoute
 			case kind.TLSRoute.String():
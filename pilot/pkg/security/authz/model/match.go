@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// MatchesAll returns true if the rule matches every request, i.e. it has no condition in its from, to and when.
+func (m *Model) MatchesAll() bool {
+	return anyEmpty(m.permissions) && anyEmpty(m.principals)
+}
+
+// Covers returns true if every request matched by the other rule is also matched by this rule. The result is
+// conservative: false is returned when the containment cannot be decided, e.g. for overlapping wildcards.
+func (m *Model) Covers(other *Model) bool {
+	return listsCover(m.permissions, other.permissions) && listsCover(m.principals, other.principals)
+}
+
+func anyEmpty(lists []ruleList) bool {
+	for _, l := range lists {
+		if len(l.rules) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// listsCover returns true if every rule list of others is covered by one of the rule lists.
+func listsCover(lists, others []ruleList) bool {
+	for _, o := range others {
+		covered := false
+		for _, l := range lists {
+			if l.covers(o) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// covers returns true if every request matched by the other rule list is matched by this rule list, i.e. each
+// condition of this list is implied by a condition on the same attribute in the other list.
+func (p ruleList) covers(other ruleList) bool {
+	for _, r := range p.rules {
+		implied := false
+		for _, o := range other.rules {
+			if o.key == r.key && r.covers(o) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			return false
+		}
+	}
+	return true
+}
+
+// covers returns true if every value matched by the other rule is matched by this rule.
+func (r rule) covers(other *rule) bool {
+	if len(r.values) > 0 {
+		if len(other.values) == 0 {
+			return false
+		}
+		for _, v := range other.values {
+			if !valueCoveredByAny(r.key, v, r.values) {
+				return false
+			}
+		}
+	}
+	for _, notValue := range r.notValues {
+		// The other rule must exclude at least the values excluded by this rule
+		if !excludes(other, notValue) {
+			return false
+		}
+	}
+	return true
+}
+
+// excludes returns true if no value matched by the rule is matched by the pattern.
+func excludes(r *rule, pattern string) bool {
+	for _, n := range r.notValues {
+		if valueCovers(r.key, n, pattern) {
+			return true
+		}
+	}
+	if len(r.values) == 0 {
+		return false
+	}
+	for _, v := range r.values {
+		if mayOverlap(r.key, pattern, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func valueCoveredByAny(key, value string, patterns []string) bool {
+	for _, p := range patterns {
+		if valueCovers(key, p, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueCovers returns true if every value matched by the value (possibly a pattern) is matched by the pattern.
+func valueCovers(key, pattern, value string) bool {
+	if pattern == value {
+		return true
+	}
+	switch key {
+	case attrSrcIP, attrRemoteIP, attrDestIP:
+		return cidrCovers(pattern, value)
+	}
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		prefix := strings.TrimSuffix(pattern, "*")
+		return !strings.HasPrefix(value, "*") && strings.HasPrefix(value, prefix)
+	}
+	if strings.HasPrefix(pattern, "*") {
+		suffix := strings.TrimPrefix(pattern, "*")
+		return !strings.HasSuffix(value, "*") && strings.HasSuffix(value, suffix)
+	}
+	return false
+}
+
+// mayOverlap returns false if no value is matched by both the pattern and the value.
+func mayOverlap(key, pattern, value string) bool {
+	switch key {
+	case attrSrcIP, attrRemoteIP, attrDestIP:
+		p, err := parsePrefix(pattern)
+		if err != nil {
+			return true
+		}
+		v, err := parsePrefix(value)
+		if err != nil {
+			return true
+		}
+		return p.Overlaps(v)
+	}
+	if strings.HasPrefix(value, "*") || strings.HasSuffix(value, "*") {
+		return true
+	}
+	return valueCovers(key, pattern, value)
+}
+
+func cidrCovers(pattern, value string) bool {
+	p, err := parsePrefix(pattern)
+	if err != nil {
+		return false
+	}
+	v, err := parsePrefix(value)
+	if err != nil {
+		return false
+	}
+	return p.Bits() <= v.Bits() && p.Contains(v.Addr())
+}
+
+func parsePrefix(v string) (netip.Prefix, error) {
+	if strings.Contains(v, "/") {
+		p, err := netip.ParsePrefix(v)
+		return p.Masked(), err
+	}
+	a, err := netip.ParseAddr(v)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
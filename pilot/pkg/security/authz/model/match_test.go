@@ -0,0 +1,159 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestModel_MatchesAll(t *testing.T) {
+	cases := []struct {
+		name string
+		rule string
+		want bool
+	}{
+		{
+			name: "empty",
+			rule: `{}`,
+			want: true,
+		},
+		{
+			name: "empty-source",
+			rule: `
+from:
+- source: {}
+to:
+- operation: {}
+`,
+			want: true,
+		},
+		{
+			name: "operation",
+			rule: `
+to:
+- operation:
+    methods: ["GET"]
+`,
+			want: false,
+		},
+		{
+			name: "when",
+			rule: `
+when:
+- key: request.headers[foo]
+  values: ["bar"]
+`,
+			want: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := New(yamlRule(t, tc.rule))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.MatchesAll(); got != tc.want {
+				t.Errorf("got %v but want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestModel_Covers(t *testing.T) {
+	cases := []struct {
+		name  string
+		rule  string
+		other string
+		want  bool
+	}{
+		{
+			name:  "empty-covers-all",
+			rule:  `{}`,
+			other: `{from: [{source: {namespaces: ["foo"]}}]}`,
+			want:  true,
+		},
+		{
+			name:  "rule-does-not-cover-empty",
+			rule:  `{from: [{source: {namespaces: ["foo"]}}]}`,
+			other: `{}`,
+			want:  false,
+		},
+		{
+			name:  "prefix-path",
+			rule:  `{to: [{operation: {paths: ["/api/*"]}}]}`,
+			other: `{to: [{operation: {paths: ["/api/v1/*", "/api/v2"], methods: ["GET"]}}]}`,
+			want:  true,
+		},
+		{
+			name:  "suffix-path",
+			rule:  `{to: [{operation: {paths: ["*/admin"]}}]}`,
+			other: `{to: [{operation: {paths: ["/api/*"]}}]}`,
+			want:  false,
+		},
+		{
+			name:  "not-values",
+			rule:  `{from: [{source: {notNamespaces: ["foo"]}}]}`,
+			other: `{from: [{source: {namespaces: ["bar"]}}]}`,
+			want:  true,
+		},
+		{
+			name:  "not-values-overlap",
+			rule:  `{from: [{source: {notNamespaces: ["foo"]}}]}`,
+			other: `{from: [{source: {namespaces: ["f*"]}}]}`,
+			want:  false,
+		},
+		{
+			name:  "cidr",
+			rule:  `{from: [{source: {ipBlocks: ["10.0.0.0/8"]}}]}`,
+			other: `{from: [{source: {ipBlocks: ["10.1.0.0/16", "10.2.3.4"]}}]}`,
+			want:  true,
+		},
+		{
+			name:  "cidr-wider",
+			rule:  `{from: [{source: {ipBlocks: ["10.1.0.0/16"]}}]}`,
+			other: `{from: [{source: {ipBlocks: ["10.0.0.0/8"]}}]}`,
+			want:  false,
+		},
+		{
+			name:  "multiple-from",
+			rule:  `{from: [{source: {namespaces: ["foo"]}}, {source: {principals: ["*"]}}]}`,
+			other: `{from: [{source: {namespaces: ["foo"]}}, {source: {principals: ["td/ns/bar/sa/baz"]}}]}`,
+			want:  true,
+		},
+		{
+			name:  "when",
+			rule:  `{when: [{key: "request.headers[foo]", values: ["bar*"]}]}`,
+			other: `{when: [{key: "request.headers[foo]", values: ["barbaz"]}, {key: source.namespace, values: ["foo"]}]}`,
+			want:  true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := New(yamlRule(t, tc.rule))
+			if err != nil {
+				t.Fatal(err)
+			}
+			other, err := New(yamlRule(t, tc.other))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Covers(other); got != tc.want {
+				t.Errorf("got %v but want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -81,6 +81,19 @@ func (t Bundle) ReplaceTrustDomainAliases(principals []string) []string {
 	return principalsIncludingAliases
 }
 
+// MatchTrustDomain returns false if the principal enforces a trust domain that is neither the local trust domain,
+// one of its aliases nor "cluster.local". Such a principal never matches the identity of a workload in the mesh.
+func (t Bundle) MatchTrustDomain(principal string) bool {
+	if !isTrustDomainBeingEnforced(principal) {
+		return true
+	}
+	trustDomainFromPrincipal, err := getTrustDomainFromSpiffeIdentity(principal)
+	if err != nil {
+		return true
+	}
+	return stringMatch(trustDomainFromPrincipal, t.TrustDomains) || trustDomainFromPrincipal == constants.DefaultClusterLocalDomain
+}
+
 // replaceTrustDomains replace the given principal's trust domain with the trust domains from the
 // trustDomains list and return the new principals.
 func (t Bundle) replaceTrustDomains(principal, trustDomainFromPrincipal string) []string {
@@ -217,3 +217,27 @@ func TestIsTrustDomainBeingEnforced(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchTrustDomain(t *testing.T) {
+	bundle := NewBundle("td1", []string{"td2", "*-td"})
+	cases := []struct {
+		principal string
+		want      bool
+	}{
+		{principal: "td1/ns/foo/sa/bar", want: true},
+		{principal: "td2/ns/foo/sa/bar", want: true},
+		{principal: "old-td/ns/foo/sa/bar", want: true},
+		{principal: "cluster.local/ns/foo/sa/bar", want: true},
+		{principal: "*/ns/foo/sa/bar", want: true},
+		{principal: "td*/ns/foo/sa/bar", want: true},
+		{principal: "sa/bar", want: true},
+		{principal: "td3/ns/foo/sa/bar", want: false},
+	}
+
+	for _, c := range cases {
+		got := bundle.MatchTrustDomain(c.principal)
+		if got != c.want {
+			t.Errorf("%s: expect %v, but got %v", c.principal, c.want, got)
+		}
+	}
+}
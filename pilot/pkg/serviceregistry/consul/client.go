@@ -0,0 +1,156 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// indexHeader holds the index of the returned data, used for blocking queries.
+	indexHeader = "X-Consul-Index"
+	tokenHeader = "X-Consul-Token"
+)
+
+// serviceEntry is a service instance, as returned by the Consul health endpoint.
+type serviceEntry struct {
+	Node    catalogNode
+	Service agentService
+	Checks  []healthCheck
+}
+
+type catalogNode struct {
+	Node       string
+	Address    string
+	Datacenter string
+}
+
+type agentService struct {
+	ID      string
+	Service string
+	Tags    []string
+	Address string
+	Port    int
+	Meta    map[string]string
+}
+
+type healthCheck struct {
+	CheckID   string
+	ServiceID string
+	Status    string
+}
+
+// catalogClient is a minimal client for the Consul catalog HTTP API, supporting blocking queries.
+// See https://developer.hashicorp.com/consul/api-docs/features/blocking.
+type catalogClient struct {
+	address    string
+	token      string
+	datacenter string
+	waitTime   time.Duration
+	client     *http.Client
+}
+
+func newCatalogClient(address, token, datacenter string, waitTime time.Duration) (*catalogClient, error) {
+	if !strings.Contains(address, "://") {
+		address = "http://" + address
+	}
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid consul address %q: %v", address, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid consul address %q: missing host", address)
+	}
+	return &catalogClient{
+		address:    strings.TrimSuffix(u.String(), "/"),
+		token:      token,
+		datacenter: datacenter,
+		waitTime:   waitTime,
+		// Blocking queries are held by the server for up to the wait time, plus some jitter.
+		client: &http.Client{Timeout: waitTime + waitTime/16 + 10*time.Second},
+	}, nil
+}
+
+// services returns the tags of all services in the catalog, keyed by service name. If index is set, the call
+// blocks until the catalog changes past index or the wait time expires.
+func (c *catalogClient) services(ctx context.Context, index uint64) (map[string][]string, uint64, error) {
+	out := map[string][]string{}
+	idx, err := c.get(ctx, "/v1/catalog/services", index, &out)
+	return out, idx, err
+}
+
+// serviceInstances returns all instances of the service, along with their health checks. If index is set,
+// the call blocks until the service changes past index or the wait time expires.
+func (c *catalogClient) serviceInstances(ctx context.Context, service string, index uint64) ([]serviceEntry, uint64, error) {
+	var out []serviceEntry
+	idx, err := c.get(ctx, "/v1/health/service/"+url.PathEscape(service), index, &out)
+	return out, idx, err
+}
+
+func (c *catalogClient) get(ctx context.Context, path string, index uint64, out any) (uint64, error) {
+	query := url.Values{}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", c.waitTime.String())
+	}
+	if c.datacenter != "" {
+		query.Set("dc", c.datacenter)
+	}
+	u := c.address + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.token != "" {
+		req.Header.Set(tokenHeader, c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return 0, fmt.Errorf("GET %s: unexpected status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("GET %s: %v", path, err)
+	}
+	idx, err := strconv.ParseUint(resp.Header.Get(indexHeader), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("GET %s: invalid %s header: %v", path, indexHeader, err)
+	}
+	return idx, nil
+}
+
+// nextIndex returns the index to use for the next blocking query, following the Consul recommendations:
+// the index must be reset if it goes backwards, and must never be zero.
+func nextIndex(prev, cur uint64) uint64 {
+	if cur < prev || cur == 0 {
+		return 1
+	}
+	return cur
+}
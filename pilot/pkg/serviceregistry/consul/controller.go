@@ -0,0 +1,298 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pilot/pkg/serviceregistry/util/servicestore"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("consul", "consul service registry controller", 0)
+
+const (
+	// DefaultNamespace is the namespace Consul services are placed in, unless configured otherwise.
+	DefaultNamespace = "consul"
+
+	defaultWaitTime      = 5 * time.Minute
+	defaultRetryInterval = 5 * time.Second
+)
+
+// Options stores the configurable attributes of a Controller.
+type Options struct {
+	// ServerAddress is the address of the Consul HTTP API, such as http://127.0.0.1:8500.
+	ServerAddress string
+	// Token is the ACL token used to read the catalog, if any.
+	Token string
+	// Datacenter to read the catalog from. Defaults to the datacenter of the Consul agent.
+	Datacenter string
+	// ClusterID identifies this registry.
+	ClusterID cluster.ID
+	// Namespace the Consul services are placed in, which determines the visibility of the services and the
+	// configs that apply to them. Defaults to DefaultNamespace.
+	Namespace string
+	// XDSUpdater will push changes to the xDS server.
+	XDSUpdater model.XDSUpdater
+	// WaitTime is the maximum time blocking queries are held by Consul.
+	WaitTime time.Duration
+	// RetryInterval is the time to wait after a failed query.
+	RetryInterval time.Duration
+}
+
+// Controller is a service registry backed by the Consul catalog. Each Consul service is watched with blocking
+// queries, and converted to a service named <name>.service.consul. Instance tags of the form key=value are
+// converted to labels, and critical health checks mark the endpoint unhealthy.
+type Controller struct {
+	opts   Options
+	client *catalogClient
+
+	handlers model.ControllerHandlers
+	model.NetworkGatewaysHandler
+
+	store *servicestore.Store
+
+	mutex sync.Mutex
+	// watches cancels the watch of each Consul service, keyed by service name.
+	watches map[string]context.CancelFunc
+
+	synced *atomic.Bool
+}
+
+var _ serviceregistry.Instance = &Controller{}
+
+// NewController creates a new Consul service registry.
+func NewController(opts Options) (*Controller, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = DefaultNamespace
+	}
+	if opts.WaitTime <= 0 {
+		opts.WaitTime = defaultWaitTime
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = defaultRetryInterval
+	}
+	client, err := newCatalogClient(opts.ServerAddress, opts.Token, opts.Datacenter, opts.WaitTime)
+	if err != nil {
+		return nil, err
+	}
+	return &Controller{
+		opts:    opts,
+		client:  client,
+		store:   servicestore.New(),
+		watches: map[string]context.CancelFunc{},
+		synced:  atomic.NewBool(false),
+	}, nil
+}
+
+func (c *Controller) Provider() provider.ID {
+	return provider.Consul
+}
+
+func (c *Controller) Cluster() cluster.ID {
+	return c.opts.ClusterID
+}
+
+// AppendServiceHandler implements a service catalog operation
+func (c *Controller) AppendServiceHandler(f func(*model.Service, model.Event)) {
+	c.handlers.AppendServiceHandler(f)
+}
+
+// AppendWorkloadHandler is a no-op, Consul instances are only exposed as service instances.
+func (c *Controller) AppendWorkloadHandler(func(*model.WorkloadInstance, model.Event)) {}
+
+// HasSynced returns true once the catalog has been listed, and all the services it contained fetched.
+func (c *Controller) HasSynced() bool {
+	return c.synced.Load()
+}
+
+// Run watches the Consul catalog until stop is closed.
+func (c *Controller) Run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+	c.watchCatalog(ctx)
+}
+
+// watchCatalog watches the list of services in the catalog, and starts or stops watching each of them.
+func (c *Controller) watchCatalog(ctx context.Context) {
+	var index uint64
+	for {
+		services, idx, err := c.client.services(ctx, index)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warnf("failed to list consul services: %v", err)
+			if !sleep(ctx, c.opts.RetryInterval) {
+				return
+			}
+			continue
+		}
+		if index == 0 || idx != index {
+			c.syncServices(ctx, services)
+		}
+		index = nextIndex(index, idx)
+		if !c.synced.Load() {
+			log.Infof("consul registry synced with %d services", len(services))
+			c.synced.Store(true)
+		}
+	}
+}
+
+// syncServices starts watching new services, and removes the services no longer in the catalog. New services
+// are fetched before returning, so that the registry is complete once it is marked as synced.
+func (c *Controller) syncServices(ctx context.Context, services map[string][]string) {
+	initial := &sync.WaitGroup{}
+	var removed []string
+	c.mutex.Lock()
+	for name := range services {
+		if _, f := c.watches[name]; f {
+			continue
+		}
+		watchCtx, cancel := context.WithCancel(ctx)
+		c.watches[name] = cancel
+		initial.Add(1)
+		go c.watchService(watchCtx, name, initial)
+	}
+	for name, cancel := range c.watches {
+		if _, f := services[name]; !f {
+			cancel()
+			delete(c.watches, name)
+			removed = append(removed, name)
+		}
+	}
+	c.mutex.Unlock()
+
+	for _, name := range removed {
+		c.updateService(ctx, name, nil)
+	}
+	initial.Wait()
+}
+
+// watchService watches the instances of a service until ctx is canceled. initial is marked done after the
+// first attempt to fetch the service.
+func (c *Controller) watchService(ctx context.Context, name string, initial *sync.WaitGroup) {
+	initialDone := func() {
+		if initial != nil {
+			initial.Done()
+			initial = nil
+		}
+	}
+	defer initialDone()
+	var index uint64
+	for {
+		entries, idx, err := c.client.serviceInstances(ctx, name, index)
+		if err != nil {
+			initialDone()
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warnf("failed to fetch consul service %s: %v", name, err)
+			if !sleep(ctx, c.opts.RetryInterval) {
+				return
+			}
+			continue
+		}
+		if index == 0 || idx != index {
+			c.updateService(ctx, name, entries)
+		}
+		initialDone()
+		index = nextIndex(index, idx)
+	}
+}
+
+// updateService stores the latest instances of a service, and notifies the xDS server of any change.
+// Unchanged services and endpoints trigger no push.
+func (c *Controller) updateService(ctx context.Context, name string, entries []serviceEntry) {
+	change := c.store.Update(serviceHostname(name), func(prev *model.Service) (*model.Service, []*model.IstioEndpoint, bool) {
+		if ctx.Err() != nil {
+			// The service was removed from the catalog while it was being fetched.
+			return nil, nil, false
+		}
+		creationTime := time.Now()
+		if prev != nil {
+			creationTime = prev.CreationTime
+		}
+		svc, endpoints := convertService(name, entries, c.opts.ClusterID, c.opts.Namespace, creationTime)
+		return svc, endpoints, true
+	})
+	if change == nil {
+		return
+	}
+	if change.ServiceChanged {
+		log.Infof("consul service %s %v", name, change.Event())
+	}
+	change.Notify(model.ShardKeyFromRegistry(c), c.opts.XDSUpdater, &c.handlers)
+}
+
+// Services implements a service catalog operation
+func (c *Controller) Services() []*model.Service {
+	return c.store.Services()
+}
+
+// GetService implements a service catalog operation
+func (c *Controller) GetService(hostname host.Name) *model.Service {
+	return c.store.GetService(hostname)
+}
+
+// InstancesByPort implements a service catalog operation
+func (c *Controller) InstancesByPort(svc *model.Service, port int, lbls labels.Instance) []*model.ServiceInstance {
+	return c.store.InstancesByPort(svc, port, lbls)
+}
+
+// GetProxyServiceInstances lists service instances co-located with a given proxy
+func (c *Controller) GetProxyServiceInstances(node *model.Proxy) []*model.ServiceInstance {
+	return c.store.GetProxyServiceInstances(node)
+}
+
+func (c *Controller) GetProxyWorkloadLabels(proxy *model.Proxy) labels.Instance {
+	for _, instance := range c.GetProxyServiceInstances(proxy) {
+		return instance.Endpoint.Labels
+	}
+	return nil
+}
+
+func (c *Controller) NetworkGateways() []model.NetworkGateway {
+	return nil
+}
+
+func (c *Controller) MCSServices() []model.MCSServiceInfo {
+	return nil
+}
+
+// sleep waits for d, returning false if ctx is canceled first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
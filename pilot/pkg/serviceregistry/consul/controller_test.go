@@ -0,0 +1,176 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func setupController(t *testing.T) (*FakeServer, *Controller, *kubecontroller.FakeXdsUpdater) {
+	t.Helper()
+	server := NewFakeServer(t)
+	fx := kubecontroller.NewFakeXDS()
+	c, err := NewController(Options{
+		ServerAddress: server.Address(),
+		ClusterID:     "consul",
+		XDSUpdater:    fx,
+		WaitTime:      time.Second,
+		RetryInterval: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	return server, c, fx
+}
+
+func runController(t *testing.T, c *Controller) {
+	t.Helper()
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go c.Run(stop)
+	retry.UntilOrFail(t, c.HasSynced, retry.Timeout(5*time.Second))
+}
+
+func expectNoEvents(t *testing.T, fx *kubecontroller.FakeXdsUpdater) {
+	t.Helper()
+	select {
+	case e := <-fx.Events:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestController(t *testing.T) {
+	server, c, fx := setupController(t)
+	server.Register("web", FakeInstance{
+		ID: "web-1", Node: "vm-1", Address: "10.0.0.1", Datacenter: "dc1", Port: 8080,
+		Tags: []string{"version=v1", "primary"}, Meta: map[string]string{"protocol": "http"},
+	})
+	server.Register("web", FakeInstance{
+		ID: "web-2", Node: "vm-2", Address: "10.0.0.2", Datacenter: "dc1", Port: 8080,
+		Tags: []string{"version=v2"}, Meta: map[string]string{"protocol": "http"}, Health: "critical",
+	})
+	runController(t, c)
+
+	hostname := host.Name("web.service.consul")
+	fx.WaitOrFail(t, "service")
+
+	svc := c.GetService(hostname)
+	if svc == nil {
+		t.Fatalf("expected service %s, got %v", hostname, c.Services())
+	}
+	assert.Equal(t, svc.Attributes.Namespace, DefaultNamespace)
+	assert.Equal(t, svc.Ports, model.PortList{{Name: "http-8080", Port: 8080, Protocol: protocol.HTTP}})
+
+	instances := c.InstancesByPort(svc, 8080, labels.Instance{"version": "v1"})
+	assert.Equal(t, len(instances), 1)
+	assert.Equal(t, instances[0].Endpoint.Address, "10.0.0.1")
+	assert.Equal(t, instances[0].Endpoint.Labels, labels.Instance{"version": "v1"})
+	assert.Equal(t, instances[0].Endpoint.Locality.Label, "dc1")
+	assert.Equal(t, instances[0].Endpoint.HealthStatus, model.Healthy)
+
+	instances = c.InstancesByPort(svc, 8080, labels.Instance{"version": "v2"})
+	assert.Equal(t, len(instances), 1)
+	assert.Equal(t, instances[0].Endpoint.HealthStatus, model.UnHealthy)
+
+	proxyInstances := c.GetProxyServiceInstances(&model.Proxy{IPAddresses: []string{"10.0.0.2"}})
+	assert.Equal(t, len(proxyInstances), 1)
+	assert.Equal(t, proxyInstances[0].Service.Hostname, hostname)
+	assert.Equal(t, c.GetProxyWorkloadLabels(&model.Proxy{IPAddresses: []string{"10.0.0.2"}}), labels.Instance{"version": "v2"})
+
+	t.Run("endpoint change", func(t *testing.T) {
+		fx.Clear()
+		server.Register("web", FakeInstance{
+			ID: "web-3", Node: "vm-3", Address: "10.0.0.3", Port: 8080, Meta: map[string]string{"protocol": "http"},
+		})
+		ev := fx.WaitOrFail(t, "eds")
+		assert.Equal(t, ev.ID, string(hostname))
+		assert.Equal(t, len(ev.Endpoints), 3)
+	})
+
+	t.Run("unchanged", func(t *testing.T) {
+		fx.Clear()
+		server.Touch()
+		expectNoEvents(t, fx)
+	})
+
+	t.Run("health change", func(t *testing.T) {
+		fx.Clear()
+		server.Register("web", FakeInstance{
+			ID: "web-2", Node: "vm-2", Address: "10.0.0.2", Datacenter: "dc1", Port: 8080,
+			Tags: []string{"version=v2"}, Meta: map[string]string{"protocol": "http"},
+		})
+		fx.WaitOrFail(t, "eds")
+		instances := c.InstancesByPort(svc, 8080, labels.Instance{"version": "v2"})
+		assert.Equal(t, instances[0].Endpoint.HealthStatus, model.Healthy)
+	})
+
+	t.Run("port change", func(t *testing.T) {
+		fx.Clear()
+		server.Register("web", FakeInstance{ID: "web-4", Node: "vm-4", Address: "10.0.0.4", Port: 9090})
+		fx.WaitOrFail(t, "service")
+		assert.Equal(t, c.GetService(hostname).Ports, model.PortList{
+			{Name: "http-8080", Port: 8080, Protocol: protocol.HTTP},
+			{Name: "port-9090", Port: 9090, Protocol: protocol.Unsupported},
+		})
+	})
+
+	t.Run("service removed", func(t *testing.T) {
+		fx.Clear()
+		for _, id := range []string{"web-1", "web-2", "web-3", "web-4"} {
+			server.Deregister("web", id)
+		}
+		retry.UntilOrFail(t, func() bool {
+			return c.GetService(hostname) == nil
+		}, retry.Timeout(5*time.Second))
+		assert.Equal(t, len(c.Services()), 0)
+		assert.Equal(t, len(c.GetProxyServiceInstances(&model.Proxy{IPAddresses: []string{"10.0.0.2"}})), 0)
+	})
+}
+
+func TestControllerRetries(t *testing.T) {
+	c, err := NewController(Options{
+		ServerAddress: "127.0.0.1:1",
+		XDSUpdater:    kubecontroller.NewFakeXDS(),
+		RetryInterval: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.Run(stop)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, c.HasSynced(), false)
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not stop")
+	}
+}
+
+func TestConvertLabels(t *testing.T) {
+	assert.Equal(t, convertLabels([]string{"version=v1", "primary", "=empty", "env=prod=1"}),
+		labels.Instance{"version": "v1", "env": "prod=1"})
+}
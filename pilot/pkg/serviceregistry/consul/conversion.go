@@ -0,0 +1,147 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pilot/pkg/serviceregistry/util/servicestore"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+const (
+	// protocolMeta is the service metadata key holding the protocol of the service port.
+	protocolMeta = "protocol"
+
+	// Consul health check statuses.
+	checkCritical    = "critical"
+	checkMaintenance = "maintenance"
+)
+
+// serviceHostname returns the hostname of a Consul service, matching the Consul DNS interface.
+func serviceHostname(name string) host.Name {
+	return host.Name(fmt.Sprintf("%s.service.consul", name))
+}
+
+// convertLabels converts Consul tags of the form key=value to labels. Other tags are ignored.
+func convertLabels(tags []string) labels.Instance {
+	out := make(labels.Instance, len(tags))
+	for _, tag := range tags {
+		k, v, f := strings.Cut(tag, "=")
+		if !f || k == "" {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// convertHealth maps the health checks of an instance to a health status. The instance is unhealthy if any of
+// the service or node checks is critical, which includes maintenance mode.
+func convertHealth(checks []healthCheck) model.HealthStatus {
+	for _, check := range checks {
+		if check.Status == checkCritical || check.Status == checkMaintenance {
+			return model.UnHealthy
+		}
+	}
+	return model.Healthy
+}
+
+// convertService converts the instances of a Consul service. It returns a nil service if there are no instances.
+func convertService(name string, entries []serviceEntry, clusterID cluster.ID, namespace string,
+	creationTime time.Time,
+) (*model.Service, []*model.IstioEndpoint) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	entries = append([]serviceEntry(nil), entries...)
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Node.Node == entries[j].Node.Node {
+			return entries[i].Service.ID < entries[j].Service.ID
+		}
+		return entries[i].Node.Node < entries[j].Node.Node
+	})
+
+	ports := map[int]*model.Port{}
+	endpoints := make([]*model.IstioEndpoint, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Service.Port <= 0 {
+			continue
+		}
+		port, f := ports[entry.Service.Port]
+		if !f {
+			proto := protocol.Parse(entry.Service.Meta[protocolMeta])
+			port = &model.Port{
+				Name:     servicestore.PortName(entry.Service.Port, proto),
+				Port:     entry.Service.Port,
+				Protocol: proto,
+			}
+			ports[entry.Service.Port] = port
+		}
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+		lbls := convertLabels(entry.Service.Tags)
+		endpoints = append(endpoints, &model.IstioEndpoint{
+			Labels:          lbls,
+			Address:         address,
+			ServicePortName: port.Name,
+			Locality: model.Locality{
+				Label:     entry.Node.Datacenter,
+				ClusterID: clusterID,
+			},
+			EndpointPort: uint32(entry.Service.Port),
+			TLSMode:      model.GetTLSModeFromEndpointLabels(lbls),
+			Namespace:    namespace,
+			WorkloadName: entry.Service.ID,
+			HealthStatus: convertHealth(entry.Checks),
+		})
+	}
+	if len(ports) == 0 {
+		return nil, nil
+	}
+
+	portList := make(model.PortList, 0, len(ports))
+	for _, port := range ports {
+		portList = append(portList, port)
+	}
+	sort.Slice(portList, func(i, j int) bool {
+		return portList[i].Port < portList[j].Port
+	})
+
+	svc := &model.Service{
+		Hostname:       serviceHostname(name),
+		Ports:          portList,
+		DefaultAddress: constants.UnspecifiedIP,
+		Resolution:     model.ClientSideLB,
+		CreationTime:   creationTime,
+		Attributes: model.ServiceAttributes{
+			ServiceRegistry: provider.Consul,
+			Name:            name,
+			Namespace:       namespace,
+		},
+	}
+	return svc, endpoints
+}
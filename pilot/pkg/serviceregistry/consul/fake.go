@@ -0,0 +1,234 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consul
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/test"
+)
+
+// FakeInstance is a service instance registered in a FakeServer.
+type FakeInstance struct {
+	ID         string
+	Node       string
+	Address    string
+	Datacenter string
+	Port       int
+	Tags       []string
+	Meta       map[string]string
+	// Health is the status of the instance health check: passing, warning, critical or maintenance.
+	Health string
+}
+
+// FakeServer is an in-process fake of the Consul catalog HTTP API, supporting blocking queries.
+type FakeServer struct {
+	server *httptest.Server
+
+	mu sync.Mutex
+	// index is bumped on every change. changed is closed and replaced on every change, to wake blocking queries.
+	index    uint64
+	changed  chan struct{}
+	services map[string][]FakeInstance
+	// requests counts the requests served, by path.
+	requests map[string]int
+	stop     chan struct{}
+}
+
+// NewFakeServer starts a fake Consul server, which is stopped when the test ends.
+func NewFakeServer(t test.Failer) *FakeServer {
+	f := &FakeServer{
+		index:    1,
+		changed:  make(chan struct{}),
+		services: map[string][]FakeInstance{},
+		requests: map[string]int{},
+		stop:     make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/catalog/services", f.handleServices)
+	mux.HandleFunc("/v1/health/service/", f.handleHealthService)
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// Address returns the address of the fake Consul HTTP API.
+func (f *FakeServer) Address() string {
+	return f.server.URL
+}
+
+// Close stops the server, releasing any blocking query.
+func (f *FakeServer) Close() {
+	f.mu.Lock()
+	select {
+	case <-f.stop:
+	default:
+		close(f.stop)
+	}
+	f.mu.Unlock()
+	f.server.Close()
+}
+
+// Register adds an instance to a service, replacing any instance with the same ID.
+func (f *FakeServer) Register(service string, instance FakeInstance) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	instances := f.services[service]
+	for i, existing := range instances {
+		if existing.ID == instance.ID {
+			instances[i] = instance
+			f.bumpLocked()
+			return
+		}
+	}
+	f.services[service] = append(instances, instance)
+	f.bumpLocked()
+}
+
+// Deregister removes an instance of a service. The service is removed with its last instance.
+func (f *FakeServer) Deregister(service, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	instances := f.services[service]
+	for i, existing := range instances {
+		if existing.ID == id {
+			instances = append(instances[:i:i], instances[i+1:]...)
+			break
+		}
+	}
+	if len(instances) == 0 {
+		delete(f.services, service)
+	} else {
+		f.services[service] = instances
+	}
+	f.bumpLocked()
+}
+
+// Touch bumps the index without changing any data, as Consul does for unrelated changes.
+func (f *FakeServer) Touch() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bumpLocked()
+}
+
+// Requests returns the number of requests served for the given path.
+func (f *FakeServer) Requests(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+func (f *FakeServer) bumpLocked() {
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// block implements blocking queries: it waits until the index moves past the requested one, or the wait time
+// expires.
+func (f *FakeServer) block(r *http.Request) {
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	if index == 0 {
+		return
+	}
+	wait, err := time.ParseDuration(r.URL.Query().Get("wait"))
+	if err != nil {
+		wait = 5 * time.Minute
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		f.mu.Lock()
+		current, changed := f.index, f.changed
+		f.mu.Unlock()
+		if current > index {
+			return
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return
+		case <-r.Context().Done():
+			return
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+func (f *FakeServer) handleServices(w http.ResponseWriter, r *http.Request) {
+	f.block(r)
+	f.mu.Lock()
+	f.requests[r.URL.Path]++
+	out := map[string][]string{}
+	for name, instances := range f.services {
+		tags := []string{}
+		for _, instance := range instances {
+			tags = append(tags, instance.Tags...)
+		}
+		out[name] = tags
+	}
+	index := f.index
+	f.mu.Unlock()
+	writeFakeResponse(w, index, out)
+}
+
+func (f *FakeServer) handleHealthService(w http.ResponseWriter, r *http.Request) {
+	f.block(r)
+	name := strings.TrimPrefix(r.URL.Path, "/v1/health/service/")
+	f.mu.Lock()
+	f.requests[r.URL.Path]++
+	out := make([]serviceEntry, 0, len(f.services[name]))
+	for _, instance := range f.services[name] {
+		health := instance.Health
+		if health == "" {
+			health = "passing"
+		}
+		out = append(out, serviceEntry{
+			Node: catalogNode{
+				Node:       instance.Node,
+				Address:    instance.Address,
+				Datacenter: instance.Datacenter,
+			},
+			Service: agentService{
+				ID:      instance.ID,
+				Service: name,
+				Tags:    instance.Tags,
+				Port:    instance.Port,
+				Meta:    instance.Meta,
+			},
+			Checks: []healthCheck{{CheckID: "service:" + instance.ID, ServiceID: instance.ID, Status: health}},
+		})
+	}
+	index := f.index
+	f.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Service.ID < out[j].Service.ID
+	})
+	writeFakeResponse(w, index, out)
+}
+
+func writeFakeResponse(w http.ResponseWriter, index uint64, body any) {
+	w.Header().Set(indexHeader, strconv.FormatUint(index, 10))
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,128 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polling
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// Config configures a polling registry backed by an HTTP JSON API. For example, a Nacos service is read with:
+//
+//	name: nacos
+//	namespace: nacos
+//	domainSuffix: nacos
+//	interval: 10s
+//	http:
+//	  urls: [http://nacos:8848/nacos/v1/ns/instance/list?serviceName=reviews]
+//	  items: hosts[]
+//	  serviceName: serviceName
+//	  serviceNamePattern: "@@(.*)$"
+//	  address: ip
+//	  port: port
+//	  metadata: metadata
+//	  health: healthy
+type Config struct {
+	// Name of the registry, which must be unique.
+	Name string `json:"name"`
+	// Namespace the services are placed in. Defaults to the name of the registry.
+	Namespace string `json:"namespace,omitempty"`
+	// DomainSuffix of the service hostnames. Defaults to the name of the registry.
+	DomainSuffix string `json:"domainSuffix,omitempty"`
+	// Interval between two polls, as a duration string. Defaults to 30s.
+	Interval string `json:"interval,omitempty"`
+	// Timeout of each request, as a duration string. Defaults to 10s.
+	Timeout string `json:"timeout,omitempty"`
+	// HTTP describes how to fetch the instances.
+	HTTP HTTPMapping `json:"http"`
+}
+
+// LoadConfigs reads a YAML list of registry configs.
+func LoadConfigs(path string) ([]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []Config
+	if err := yaml.UnmarshalStrict(data, &configs); err != nil {
+		return nil, fmt.Errorf("invalid polling registry config %s: %v", path, err)
+	}
+	names := map[string]bool{}
+	// The registries share a shard key, so the hostnames of their services must not collide.
+	domainSuffixes := map[string]string{}
+	for _, cfg := range configs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("invalid polling registry config %s: name is required", path)
+		}
+		if names[cfg.Name] {
+			return nil, fmt.Errorf("invalid polling registry config %s: duplicate registry %s", path, cfg.Name)
+		}
+		names[cfg.Name] = true
+		suffix := cfg.domainSuffix()
+		if other, f := domainSuffixes[suffix]; f {
+			return nil, fmt.Errorf("invalid polling registry config %s: registries %s and %s have the same domain suffix %s",
+				path, other, cfg.Name, suffix)
+		}
+		domainSuffixes[suffix] = cfg.Name
+	}
+	return configs, nil
+}
+
+// Options converts the config to controller options. The caller sets the cluster ID and the XDSUpdater.
+func (cfg Config) Options() (Options, error) {
+	interval, err := parseDuration(cfg.Interval, defaultInterval)
+	if err != nil {
+		return Options{}, fmt.Errorf("registry %s: invalid interval: %v", cfg.Name, err)
+	}
+	timeout, err := parseDuration(cfg.Timeout, defaultTimeout)
+	if err != nil {
+		return Options{}, fmt.Errorf("registry %s: invalid timeout: %v", cfg.Name, err)
+	}
+	fetcher, err := NewHTTPFetcher(cfg.HTTP, timeout)
+	if err != nil {
+		return Options{}, fmt.Errorf("registry %s: %v", cfg.Name, err)
+	}
+	opts := Options{
+		Name:         cfg.Name,
+		Namespace:    cfg.Namespace,
+		DomainSuffix: cfg.domainSuffix(),
+		Fetcher:      fetcher,
+		Interval:     interval,
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = cfg.Name
+	}
+	return opts, nil
+}
+
+// domainSuffix returns the domain suffix of the service hostnames, defaulting to the name of the registry.
+func (cfg Config) domainSuffix() string {
+	if cfg.DomainSuffix == "" {
+		return cfg.Name
+	}
+	return cfg.DomainSuffix
+}
+
+func parseDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	return time.ParseDuration(s)
+}
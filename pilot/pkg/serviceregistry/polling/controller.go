@@ -0,0 +1,223 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polling
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/atomic"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pilot/pkg/serviceregistry/util/servicestore"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("polling", "polling service registry controller", 0)
+
+const defaultInterval = 30 * time.Second
+
+// Options stores the configurable attributes of a Controller.
+type Options struct {
+	// Name identifies the registry in logs.
+	Name string
+	// ClusterID identifies this registry.
+	ClusterID cluster.ID
+	// Namespace the services are placed in, which determines the visibility of the services and the configs
+	// that apply to them.
+	Namespace string
+	// DomainSuffix is appended to the service names to build the service hostnames, i.e. <name>.<suffix>.
+	DomainSuffix string
+	// Fetcher lists the instances of the registry.
+	Fetcher Fetcher
+	// Interval between two fetches.
+	Interval time.Duration
+	// XDSUpdater will push changes to the xDS server.
+	XDSUpdater model.XDSUpdater
+}
+
+// Controller is a service registry periodically listing the instances of an external registry through a
+// Fetcher. Each poll is diffed against the previous one, so that only changed services trigger a push. A failed
+// poll keeps the previous instances.
+type Controller struct {
+	opts Options
+
+	handlers model.ControllerHandlers
+	model.NetworkGatewaysHandler
+
+	store *servicestore.Store
+
+	synced *atomic.Bool
+}
+
+var _ serviceregistry.Instance = &Controller{}
+
+// NewController creates a new polling service registry.
+func NewController(opts Options) (*Controller, error) {
+	if opts.Fetcher == nil {
+		return nil, fmt.Errorf("registry %s has no fetcher", opts.Name)
+	}
+	if opts.Namespace == "" || opts.DomainSuffix == "" {
+		return nil, fmt.Errorf("registry %s requires a namespace and a domain suffix", opts.Name)
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	return &Controller{
+		opts:   opts,
+		store:  servicestore.New(),
+		synced: atomic.NewBool(false),
+	}, nil
+}
+
+func (c *Controller) Provider() provider.ID {
+	return provider.Polling
+}
+
+func (c *Controller) Cluster() cluster.ID {
+	return c.opts.ClusterID
+}
+
+// AppendServiceHandler implements a service catalog operation
+func (c *Controller) AppendServiceHandler(f func(*model.Service, model.Event)) {
+	c.handlers.AppendServiceHandler(f)
+}
+
+// AppendWorkloadHandler is a no-op, instances are only exposed as service instances.
+func (c *Controller) AppendWorkloadHandler(func(*model.WorkloadInstance, model.Event)) {}
+
+// HasSynced returns true once the registry has been fetched successfully.
+func (c *Controller) HasSynced() bool {
+	return c.synced.Load()
+}
+
+// Run polls the registry until stop is closed.
+func (c *Controller) Run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stop
+		cancel()
+	}()
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		c.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the registry once, and applies the changes.
+func (c *Controller) poll(ctx context.Context) {
+	instances, err := c.opts.Fetcher.Fetch(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Warnf("failed to fetch registry %s: %v", c.opts.Name, err)
+		}
+		return
+	}
+	byService := groupByService(instances)
+
+	names := map[string]struct{}{}
+	for _, svc := range c.store.Services() {
+		names[svc.Attributes.Name] = struct{}{}
+	}
+	for name := range byService {
+		names[name] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		c.updateService(name, byService[name])
+	}
+
+	if !c.synced.Load() {
+		log.Infof("registry %s synced with %d services", c.opts.Name, len(byService))
+		c.synced.Store(true)
+	}
+}
+
+func (c *Controller) hostname(name string) host.Name {
+	return host.Name(name + "." + c.opts.DomainSuffix)
+}
+
+// updateService stores the latest instances of a service, and notifies the xDS server of any change.
+// Unchanged services and endpoints trigger no push.
+func (c *Controller) updateService(name string, instances []Instance) {
+	hostname := c.hostname(name)
+	change := c.store.Update(hostname, func(prev *model.Service) (*model.Service, []*model.IstioEndpoint, bool) {
+		creationTime := time.Now()
+		if prev != nil {
+			creationTime = prev.CreationTime
+		}
+		svc, endpoints := convertService(hostname, name, instances, c.opts.ClusterID, c.opts.Namespace, creationTime)
+		return svc, endpoints, true
+	})
+	if change == nil {
+		return
+	}
+	if change.ServiceChanged {
+		log.Infof("service %s %v in registry %s", hostname, change.Event(), c.opts.Name)
+	}
+	change.Notify(model.ShardKeyFromRegistry(c), c.opts.XDSUpdater, &c.handlers)
+}
+
+// Services implements a service catalog operation
+func (c *Controller) Services() []*model.Service {
+	return c.store.Services()
+}
+
+// GetService implements a service catalog operation
+func (c *Controller) GetService(hostname host.Name) *model.Service {
+	return c.store.GetService(hostname)
+}
+
+// InstancesByPort implements a service catalog operation
+func (c *Controller) InstancesByPort(svc *model.Service, port int, lbls labels.Instance) []*model.ServiceInstance {
+	return c.store.InstancesByPort(svc, port, lbls)
+}
+
+// GetProxyServiceInstances lists service instances co-located with a given proxy
+func (c *Controller) GetProxyServiceInstances(node *model.Proxy) []*model.ServiceInstance {
+	return c.store.GetProxyServiceInstances(node)
+}
+
+func (c *Controller) GetProxyWorkloadLabels(proxy *model.Proxy) labels.Instance {
+	for _, instance := range c.GetProxyServiceInstances(proxy) {
+		return instance.Endpoint.Labels
+	}
+	return nil
+}
+
+func (c *Controller) NetworkGateways() []model.NetworkGateway {
+	return nil
+}
+
+func (c *Controller) MCSServices() []model.MCSServiceInfo {
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polling
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+// fakeFetcher returns the instances it is set to, or an error.
+type fakeFetcher struct {
+	mu        sync.Mutex
+	instances []Instance
+	err       error
+}
+
+func (f *fakeFetcher) Fetch(context.Context) ([]Instance, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Instance(nil), f.instances...), f.err
+}
+
+func (f *fakeFetcher) set(instances []Instance, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.instances = instances
+	f.err = err
+}
+
+func expectNoEvents(t *testing.T, fx *kubecontroller.FakeXdsUpdater) {
+	t.Helper()
+	select {
+	case e := <-fx.Events:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestController(t *testing.T) {
+	fetcher := &fakeFetcher{}
+	fetcher.set([]Instance{
+		{Service: "reviews", Address: "10.0.0.2", Port: 9080, Protocol: "http", Labels: map[string]string{"version": "v2"}},
+		{Service: "reviews", Address: "10.0.0.1", Port: 9080, Protocol: "http", Labels: map[string]string{"version": "v1"}, Healthy: true},
+	}, nil)
+	fx := kubecontroller.NewFakeXDS()
+	c, err := NewController(Options{
+		Name:         "nacos",
+		ClusterID:    "cluster",
+		Namespace:    "nacos",
+		DomainSuffix: "nacos",
+		Fetcher:      fetcher,
+		Interval:     10 * time.Millisecond,
+		XDSUpdater:   fx,
+	})
+	assert.NoError(t, err)
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	go c.Run(stop)
+	retry.UntilOrFail(t, c.HasSynced, retry.Timeout(5*time.Second))
+
+	hostname := host.Name("reviews.nacos")
+	fx.WaitOrFail(t, "service")
+	svc := c.GetService(hostname)
+	if svc == nil {
+		t.Fatalf("expected service %s, got %v", hostname, c.Services())
+	}
+	assert.Equal(t, svc.Attributes.Namespace, "nacos")
+	assert.Equal(t, svc.Ports, model.PortList{{Name: "http-9080", Port: 9080, Protocol: protocol.HTTP}})
+
+	instances := c.InstancesByPort(svc, 9080, labels.Instance{"version": "v2"})
+	assert.Equal(t, len(instances), 1)
+	assert.Equal(t, instances[0].Endpoint.Address, "10.0.0.2")
+	assert.Equal(t, instances[0].Endpoint.HealthStatus, model.UnHealthy)
+	assert.Equal(t, c.GetProxyWorkloadLabels(&model.Proxy{IPAddresses: []string{"10.0.0.1"}}), labels.Instance{"version": "v1"})
+
+	t.Run("unchanged", func(t *testing.T) {
+		fx.Clear()
+		// The order of the instances does not matter.
+		fetcher.set([]Instance{
+			{Service: "reviews", Address: "10.0.0.1", Port: 9080, Protocol: "http", Labels: map[string]string{"version": "v1"}, Healthy: true},
+			{Service: "reviews", Address: "10.0.0.2", Port: 9080, Protocol: "http", Labels: map[string]string{"version": "v2"}},
+		}, nil)
+		expectNoEvents(t, fx)
+	})
+
+	t.Run("fetch failure", func(t *testing.T) {
+		fx.Clear()
+		fetcher.set(nil, fmt.Errorf("unavailable"))
+		expectNoEvents(t, fx)
+		assert.Equal(t, len(c.Services()), 1)
+	})
+
+	t.Run("endpoint change", func(t *testing.T) {
+		fx.Clear()
+		fetcher.set([]Instance{
+			{Service: "reviews", Address: "10.0.0.1", Port: 9080, Protocol: "http", Labels: map[string]string{"version": "v1"}, Healthy: true},
+			{Service: "reviews", Address: "10.0.0.2", Port: 9080, Protocol: "http", Labels: map[string]string{"version": "v2"}, Healthy: true},
+		}, nil)
+		ev := fx.WaitOrFail(t, "eds")
+		assert.Equal(t, ev.ID, string(hostname))
+		assert.Equal(t, len(ev.Endpoints), 2)
+		instances := c.InstancesByPort(svc, 9080, labels.Instance{"version": "v2"})
+		assert.Equal(t, instances[0].Endpoint.HealthStatus, model.Healthy)
+	})
+
+	t.Run("service added and removed", func(t *testing.T) {
+		fx.Clear()
+		fetcher.set([]Instance{
+			{Service: "ratings", Address: "10.0.0.3", Port: 8080, Healthy: true},
+		}, nil)
+		retry.UntilOrFail(t, func() bool {
+			return c.GetService(hostname) == nil && c.GetService("ratings.nacos") != nil
+		}, retry.Timeout(5*time.Second))
+		assert.Equal(t, len(c.Services()), 1)
+		assert.Equal(t, len(c.GetProxyServiceInstances(&model.Proxy{IPAddresses: []string{"10.0.0.1"}})), 0)
+	})
+}
+
+func TestLoadConfigs(t *testing.T) {
+	path := t.TempDir() + "/registries.yaml"
+	assert.NoError(t, os.WriteFile(path, []byte(`
+- name: nacos
+  interval: 10s
+  http:
+    urls: [http://nacos:8848/nacos/v1/ns/instance/list?serviceName=reviews]
+    items: hosts[]
+    serviceName: serviceName
+    serviceNamePattern: "@@(.*)$"
+    address: ip
+    port: port
+`), 0o644))
+	configs, err := LoadConfigs(path)
+	assert.NoError(t, err)
+	assert.Equal(t, len(configs), 1)
+	opts, err := configs[0].Options()
+	assert.NoError(t, err)
+	assert.Equal(t, opts.Namespace, "nacos")
+	assert.Equal(t, opts.DomainSuffix, "nacos")
+	assert.Equal(t, opts.Interval, 10*time.Second)
+
+	assert.NoError(t, os.WriteFile(path, []byte("- name: nacos\n- name: nacos\n"), 0o644))
+	_, err = LoadConfigs(path)
+	assert.Error(t, err)
+
+	// The domain suffix defaults to the name of the registry
+	assert.NoError(t, os.WriteFile(path, []byte("- name: nacos\n- name: eureka\n  domainSuffix: nacos\n"), 0o644))
+	_, err = LoadConfigs(path)
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polling
+
+import (
+	"sort"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pilot/pkg/serviceregistry/util/servicestore"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+// groupByService groups instances by service name. The instances of each service are sorted, so that the
+// conversion does not depend on the order the registry returned them in.
+func groupByService(instances []Instance) map[string][]Instance {
+	out := map[string][]Instance{}
+	for _, instance := range instances {
+		out[instance.Service] = append(out[instance.Service], instance)
+	}
+	for _, group := range out {
+		sort.SliceStable(group, func(i, j int) bool {
+			if group[i].Address == group[j].Address {
+				return group[i].Port < group[j].Port
+			}
+			return group[i].Address < group[j].Address
+		})
+	}
+	return out
+}
+
+// convertService converts the instances of a service. It returns a nil service if there are no instances.
+func convertService(hostname host.Name, name string, instances []Instance, clusterID cluster.ID, namespace string,
+	creationTime time.Time,
+) (*model.Service, []*model.IstioEndpoint) {
+	if len(instances) == 0 {
+		return nil, nil
+	}
+	ports := map[int]*model.Port{}
+	endpoints := make([]*model.IstioEndpoint, 0, len(instances))
+	for _, instance := range instances {
+		port, f := ports[instance.Port]
+		if !f {
+			proto := protocol.Parse(instance.Protocol)
+			port = &model.Port{
+				Name:     servicestore.PortName(instance.Port, proto),
+				Port:     instance.Port,
+				Protocol: proto,
+			}
+			ports[instance.Port] = port
+		}
+		lbls := labels.Instance(instance.Labels)
+		if lbls == nil {
+			lbls = labels.Instance{}
+		}
+		health := model.Healthy
+		if !instance.Healthy {
+			health = model.UnHealthy
+		}
+		endpoints = append(endpoints, &model.IstioEndpoint{
+			Labels:          lbls,
+			Address:         instance.Address,
+			ServicePortName: port.Name,
+			Locality: model.Locality{
+				ClusterID: clusterID,
+			},
+			EndpointPort: uint32(instance.Port),
+			TLSMode:      model.GetTLSModeFromEndpointLabels(lbls),
+			Namespace:    namespace,
+			WorkloadName: name,
+			HealthStatus: health,
+		})
+	}
+
+	portList := make(model.PortList, 0, len(ports))
+	for _, port := range ports {
+		portList = append(portList, port)
+	}
+	sort.Slice(portList, func(i, j int) bool {
+		return portList[i].Port < portList[j].Port
+	})
+
+	svc := &model.Service{
+		Hostname:       hostname,
+		Ports:          portList,
+		DefaultAddress: constants.UnspecifiedIP,
+		Resolution:     model.ClientSideLB,
+		CreationTime:   creationTime,
+		Attributes: model.ServiceAttributes{
+			ServiceRegistry: provider.Polling,
+			Name:            name,
+			Namespace:       namespace,
+		},
+	}
+	return svc, endpoints
+}
@@ -0,0 +1,304 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polling
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Instance is a service instance reported by a registry.
+type Instance struct {
+	// Service is the name of the service the instance belongs to.
+	Service  string
+	Address  string
+	Port     int
+	Protocol string
+	Labels   map[string]string
+	Healthy  bool
+}
+
+// Fetcher lists all the instances of a registry.
+type Fetcher interface {
+	Fetch(ctx context.Context) ([]Instance, error)
+}
+
+// FetcherFunc is a function implementing Fetcher.
+type FetcherFunc func(ctx context.Context) ([]Instance, error)
+
+func (f FetcherFunc) Fetch(ctx context.Context) ([]Instance, error) {
+	return f(ctx)
+}
+
+// HTTPMapping describes how to extract instances from JSON documents served over HTTP.
+//
+// Paths are dot separated field names, evaluated against the document. A field name followed by [] is expected
+// to be an array, and the rest of the path is evaluated against each of its elements.
+//
+// For example, Eureka instances are mapped with:
+//
+//	items: applications.application[].instance[]
+//	serviceName: app
+//	address: ipAddr
+//	port: port.$
+//	metadata: metadata
+//	health: status
+//	healthyValues: [UP]
+type HTTPMapping struct {
+	// URLs to fetch. The instances found in each of them are merged.
+	URLs []string `json:"urls"`
+	// Headers added to each request, for example for authentication.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Items is the path of the instances in the document.
+	Items string `json:"items"`
+	// ServiceName is the path of the service name, relative to an instance.
+	ServiceName string `json:"serviceName"`
+	// ServiceNamePattern, if set, is a regular expression applied to the service name. The first capture group
+	// is used as the service name, e.g. "@@(.*)$" for Nacos names of the form <group>@@<service>.
+	ServiceNamePattern string `json:"serviceNamePattern,omitempty"`
+	// Address is the path of the instance address, relative to an instance.
+	Address string `json:"address"`
+	// Port is the path of the instance port, relative to an instance.
+	Port string `json:"port"`
+	// Protocol, if set, is the path of the instance protocol, relative to an instance.
+	Protocol string `json:"protocol,omitempty"`
+	// Metadata, if set, is the path of an object holding the instance metadata, which is converted to labels.
+	Metadata string `json:"metadata,omitempty"`
+	// Health, if set, is the path of the instance health. Instances are considered healthy otherwise.
+	Health string `json:"health,omitempty"`
+	// HealthyValues are the values of Health for healthy instances. Defaults to "true", "UP" and "healthy".
+	HealthyValues []string `json:"healthyValues,omitempty"`
+}
+
+var defaultHealthyValues = []string{"true", "UP", "healthy"}
+
+// HTTPFetcher fetches instances from JSON documents served over HTTP.
+type HTTPFetcher struct {
+	mapping     HTTPMapping
+	namePattern *regexp.Regexp
+	healthy     map[string]bool
+	client      *http.Client
+}
+
+var _ Fetcher = &HTTPFetcher{}
+
+// NewHTTPFetcher validates the mapping and returns a fetcher using it.
+func NewHTTPFetcher(mapping HTTPMapping, timeout time.Duration) (*HTTPFetcher, error) {
+	if len(mapping.URLs) == 0 {
+		return nil, fmt.Errorf("at least one url is required")
+	}
+	if mapping.ServiceName == "" || mapping.Address == "" || mapping.Port == "" {
+		return nil, fmt.Errorf("serviceName, address and port paths are required")
+	}
+	f := &HTTPFetcher{
+		mapping: mapping,
+		healthy: map[string]bool{},
+		client:  &http.Client{Timeout: timeout},
+	}
+	if mapping.ServiceNamePattern != "" {
+		re, err := regexp.Compile(mapping.ServiceNamePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid serviceNamePattern: %v", err)
+		}
+		if re.NumSubexp() < 1 {
+			return nil, fmt.Errorf("serviceNamePattern %q must have a capture group", mapping.ServiceNamePattern)
+		}
+		f.namePattern = re
+	}
+	healthyValues := mapping.HealthyValues
+	if len(healthyValues) == 0 {
+		healthyValues = defaultHealthyValues
+	}
+	for _, v := range healthyValues {
+		f.healthy[v] = true
+	}
+	return f, nil
+}
+
+// Fetch implements Fetcher. It fails if any of the URLs cannot be fetched, so that a partial result never
+// removes instances.
+func (f *HTTPFetcher) Fetch(ctx context.Context) ([]Instance, error) {
+	var out []Instance
+	for _, u := range f.mapping.URLs {
+		doc, err := f.get(ctx, u)
+		if err != nil {
+			return nil, err
+		}
+		instances, err := f.convert(doc)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", u, err)
+		}
+		out = append(out, instances...)
+	}
+	return out, nil
+}
+
+func (f *HTTPFetcher) get(ctx context.Context, u string) (any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range f.mapping.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: unexpected status %d", u, resp.StatusCode)
+	}
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("GET %s: %v", u, err)
+	}
+	return doc, nil
+}
+
+func (f *HTTPFetcher) convert(doc any) ([]Instance, error) {
+	items := lookup(doc, f.mapping.Items)
+	out := make([]Instance, 0, len(items))
+	for _, item := range items {
+		name, ok := scalar(item, f.mapping.ServiceName)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("instance without service name at %q", f.mapping.ServiceName)
+		}
+		if f.namePattern != nil {
+			m := f.namePattern.FindStringSubmatch(name)
+			if m == nil {
+				continue
+			}
+			name = m[1]
+		}
+		address, ok := scalar(item, f.mapping.Address)
+		if !ok || address == "" {
+			return nil, fmt.Errorf("instance of %s without address at %q", name, f.mapping.Address)
+		}
+		portValue, _ := scalar(item, f.mapping.Port)
+		port, err := strconv.Atoi(portValue)
+		if err != nil || port <= 0 || port > 65535 {
+			return nil, fmt.Errorf("instance %s of %s has invalid port %q", address, name, portValue)
+		}
+		instance := Instance{
+			Service: name,
+			Address: address,
+			Port:    port,
+			Healthy: true,
+		}
+		if f.mapping.Protocol != "" {
+			instance.Protocol, _ = scalar(item, f.mapping.Protocol)
+		}
+		if f.mapping.Metadata != "" {
+			instance.Labels = stringMap(lookup(item, f.mapping.Metadata))
+		}
+		if f.mapping.Health != "" {
+			health, _ := scalar(item, f.mapping.Health)
+			instance.Healthy = f.healthy[health]
+		}
+		out = append(out, instance)
+	}
+	return out, nil
+}
+
+// lookup evaluates the path against the document, returning all matching values.
+func lookup(doc any, path string) []any {
+	values := []any{doc}
+	if path == "" {
+		return values
+	}
+	for _, segment := range strings.Split(path, ".") {
+		flatten := strings.HasSuffix(segment, "[]")
+		field := strings.TrimSuffix(segment, "[]")
+		var next []any
+		for _, v := range values {
+			if field != "" {
+				obj, ok := v.(map[string]any)
+				if !ok {
+					continue
+				}
+				if v, ok = obj[field]; !ok {
+					continue
+				}
+			}
+			if !flatten {
+				next = append(next, v)
+				continue
+			}
+			if arr, ok := v.([]any); ok {
+				next = append(next, arr...)
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// scalar returns the single value at path, as a string.
+func scalar(doc any, path string) (string, bool) {
+	values := lookup(doc, path)
+	if len(values) != 1 {
+		return "", false
+	}
+	return toString(values[0])
+}
+
+// toString converts a scalar value to a string.
+func toString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
+
+// stringMap converts the scalar values of the first object found to a map of strings.
+func stringMap(values []any) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	obj, ok := values[0].(map[string]any)
+	if !ok {
+		return nil
+	}
+	out := make(map[string]string, len(obj))
+	// Keys are not paths: metadata keys such as app.kubernetes.io/name contain dots.
+	for k, value := range obj {
+		if v, ok := toString(value); ok {
+			out[k] = v
+		}
+	}
+	return out
+}
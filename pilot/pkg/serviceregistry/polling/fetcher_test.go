@@ -0,0 +1,168 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package polling
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const eurekaApps = `{
+  "applications": {
+    "application": [
+      {
+        "name": "REVIEWS",
+        "instance": [
+          {"app": "REVIEWS", "ipAddr": "10.0.0.1", "port": {"$": 9080, "@enabled": "true"},
+           "status": "UP", "metadata": {"version": "v1"}},
+          {"app": "REVIEWS", "ipAddr": "10.0.0.2", "port": {"$": 9080, "@enabled": "true"},
+           "status": "DOWN", "metadata": {"version": "v2"}}
+        ]
+      },
+      {
+        "name": "RATINGS",
+        "instance": [
+          {"app": "RATINGS", "ipAddr": "10.0.0.3", "port": {"$": "8080"}, "status": "UP"}
+        ]
+      }
+    ]
+  }
+}`
+
+const nacosInstances = `{
+  "name": "DEFAULT_GROUP@@reviews",
+  "hosts": [
+    {"serviceName": "DEFAULT_GROUP@@reviews", "ip": "10.0.0.1", "port": 9080, "healthy": true,
+     "metadata": {"version": "v1", "protocol": "http", "weight": 1, "preserved.register.source": "SPRING_CLOUD"}},
+    {"serviceName": "DEFAULT_GROUP@@reviews", "ip": "10.0.0.2", "port": 9080, "healthy": false}
+  ]
+}`
+
+func serve(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	t.Run("eureka", func(t *testing.T) {
+		s := serve(t, eurekaApps)
+		f, err := NewHTTPFetcher(HTTPMapping{
+			URLs:          []string{s.URL},
+			Headers:       map[string]string{"Authorization": "token"},
+			Items:         "applications.application[].instance[]",
+			ServiceName:   "app",
+			Address:       "ipAddr",
+			Port:          "port.$",
+			Metadata:      "metadata",
+			Health:        "status",
+			HealthyValues: []string{"UP"},
+		}, time.Second)
+		assert.NoError(t, err)
+		instances, err := f.Fetch(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, instances, []Instance{
+			{Service: "REVIEWS", Address: "10.0.0.1", Port: 9080, Labels: map[string]string{"version": "v1"}, Healthy: true},
+			{Service: "REVIEWS", Address: "10.0.0.2", Port: 9080, Labels: map[string]string{"version": "v2"}, Healthy: false},
+			{Service: "RATINGS", Address: "10.0.0.3", Port: 8080, Labels: nil, Healthy: true},
+		})
+	})
+
+	t.Run("nacos", func(t *testing.T) {
+		s := serve(t, nacosInstances)
+		f, err := NewHTTPFetcher(HTTPMapping{
+			URLs:               []string{s.URL},
+			Headers:            map[string]string{"Authorization": "token"},
+			Items:              "hosts[]",
+			ServiceName:        "serviceName",
+			ServiceNamePattern: "@@(.*)$",
+			Address:            "ip",
+			Port:               "port",
+			Protocol:           "metadata.protocol",
+			Metadata:           "metadata",
+			Health:             "healthy",
+		}, time.Second)
+		assert.NoError(t, err)
+		instances, err := f.Fetch(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, instances, []Instance{
+			{
+				Service: "reviews", Address: "10.0.0.1", Port: 9080, Protocol: "http",
+				Labels: map[string]string{
+					"version": "v1", "protocol": "http", "weight": "1", "preserved.register.source": "SPRING_CLOUD",
+				},
+				Healthy: true,
+			},
+			{Service: "reviews", Address: "10.0.0.2", Port: 9080, Healthy: false},
+		})
+	})
+
+	t.Run("request failure", func(t *testing.T) {
+		s := serve(t, nacosInstances)
+		f, err := NewHTTPFetcher(HTTPMapping{
+			URLs:        []string{s.URL},
+			Items:       "hosts[]",
+			ServiceName: "serviceName",
+			Address:     "ip",
+			Port:        "port",
+		}, time.Second)
+		assert.NoError(t, err)
+		_, err = f.Fetch(context.Background())
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid port", func(t *testing.T) {
+		s := serve(t, `{"hosts": [{"serviceName": "a", "ip": "10.0.0.1", "port": "http"}]}`)
+		f, err := NewHTTPFetcher(HTTPMapping{
+			URLs:        []string{s.URL},
+			Headers:     map[string]string{"Authorization": "token"},
+			Items:       "hosts[]",
+			ServiceName: "serviceName",
+			Address:     "ip",
+			Port:        "port",
+		}, time.Second)
+		assert.NoError(t, err)
+		_, err = f.Fetch(context.Background())
+		assert.Error(t, err)
+	})
+}
+
+func TestNewHTTPFetcherValidation(t *testing.T) {
+	valid := HTTPMapping{URLs: []string{"http://localhost"}, ServiceName: "name", Address: "ip", Port: "port"}
+	_, err := NewHTTPFetcher(valid, time.Second)
+	assert.NoError(t, err)
+
+	noURL := valid
+	noURL.URLs = nil
+	_, err = NewHTTPFetcher(noURL, time.Second)
+	assert.Error(t, err)
+
+	noGroup := valid
+	noGroup.ServiceNamePattern = "@@.*"
+	_, err = NewHTTPFetcher(noGroup, time.Second)
+	assert.Error(t, err)
+}
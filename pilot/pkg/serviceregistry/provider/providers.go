@@ -24,6 +24,10 @@ const (
 	Kubernetes ID = "Kubernetes"
 	// External is a service registry for externally provided ServiceEntries
 	External ID = "External"
+	// Consul is a service registry backed by the Consul catalog
+	Consul ID = "Consul"
+	// Polling is a service registry periodically fetching instances from an external source, such as Nacos
+	Polling ID = "Polling"
 )
 
 func (id ID) String() string {
@@ -0,0 +1,228 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicestore
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+)
+
+// Store holds the services and endpoints of a service registry that lists whole services from an external
+// source, such as Consul, and implements the service catalog operations over them.
+//
+// Stores are thread-safe.
+type Store struct {
+	mutex     sync.RWMutex
+	services  map[host.Name]*model.Service
+	endpoints map[host.Name][]*model.IstioEndpoint
+	// instancesByIP indexes the service instances by endpoint address, for GetProxyServiceInstances.
+	instancesByIP map[string]map[host.Name][]*model.ServiceInstance
+}
+
+// New returns an empty Store.
+func New() *Store {
+	return &Store{
+		services:      map[host.Name]*model.Service{},
+		endpoints:     map[host.Name][]*model.IstioEndpoint{},
+		instancesByIP: map[string]map[host.Name][]*model.ServiceInstance{},
+	}
+}
+
+// Change is an update of a service in the Store.
+type Change struct {
+	// Prev is the service before the update, or nil if it was added.
+	Prev *model.Service
+	// Service is the service after the update, or nil if it was removed.
+	Service   *model.Service
+	Endpoints []*model.IstioEndpoint
+	// ServiceChanged is false if only the endpoints changed.
+	ServiceChanged bool
+}
+
+// Event returns the event of the service.
+func (c *Change) Event() model.Event {
+	switch {
+	case c.Service == nil:
+		return model.EventDelete
+	case c.Prev == nil:
+		return model.EventAdd
+	default:
+		return model.EventUpdate
+	}
+}
+
+// Notify notifies the xDS server and the service handlers of the change. A change of the endpoints only
+// triggers an incremental push.
+func (c *Change) Notify(shard model.ShardKey, xdsUpdater model.XDSUpdater, handlers *model.ControllerHandlers) {
+	switch {
+	case c.Service == nil:
+		xdsUpdater.SvcUpdate(shard, string(c.Prev.Hostname), c.Prev.Attributes.Namespace, model.EventDelete)
+		handlers.NotifyServiceHandlers(c.Prev, model.EventDelete)
+	case c.ServiceChanged:
+		event := c.Event()
+		// The full push triggered by the service handlers also pushes endpoints.
+		xdsUpdater.EDSCacheUpdate(shard, string(c.Service.Hostname), c.Service.Attributes.Namespace, copyEndpoints(c.Endpoints))
+		xdsUpdater.SvcUpdate(shard, string(c.Service.Hostname), c.Service.Attributes.Namespace, event)
+		handlers.NotifyServiceHandlers(c.Service, event)
+	default:
+		xdsUpdater.EDSUpdate(shard, string(c.Service.Hostname), c.Service.Attributes.Namespace, copyEndpoints(c.Endpoints))
+	}
+}
+
+// Update replaces the service and the endpoints of hostname with the ones returned by convert, which is called
+// with the current service, or nil, while the store is locked. A nil service removes it. Update returns nil if
+// convert returns false, or if neither the service nor the endpoints changed.
+func (s *Store) Update(hostname host.Name,
+	convert func(prev *model.Service) (*model.Service, []*model.IstioEndpoint, bool),
+) *Change {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	prev := s.services[hostname]
+	svc, endpoints, ok := convert(prev)
+	if !ok {
+		return nil
+	}
+	serviceChanged := !reflect.DeepEqual(prev, svc)
+	if !serviceChanged && reflect.DeepEqual(s.endpoints[hostname], endpoints) {
+		return nil
+	}
+	s.setInstances(hostname, svc, endpoints)
+	return &Change{Prev: prev, Service: svc, Endpoints: endpoints, ServiceChanged: serviceChanged}
+}
+
+// setInstances replaces the service and its instances. Must be called with the mutex held.
+func (s *Store) setInstances(hostname host.Name, svc *model.Service, endpoints []*model.IstioEndpoint) {
+	for _, ep := range s.endpoints[hostname] {
+		if byHost := s.instancesByIP[ep.Address]; byHost != nil {
+			delete(byHost, hostname)
+			if len(byHost) == 0 {
+				delete(s.instancesByIP, ep.Address)
+			}
+		}
+	}
+	if svc == nil {
+		delete(s.services, hostname)
+		delete(s.endpoints, hostname)
+		return
+	}
+	s.services[hostname] = svc
+	s.endpoints[hostname] = endpoints
+	for _, ep := range endpoints {
+		port, _ := svc.Ports.Get(ep.ServicePortName)
+		byHost := s.instancesByIP[ep.Address]
+		if byHost == nil {
+			byHost = map[host.Name][]*model.ServiceInstance{}
+			s.instancesByIP[ep.Address] = byHost
+		}
+		byHost[hostname] = append(byHost[hostname], &model.ServiceInstance{
+			Service:     svc,
+			ServicePort: port,
+			Endpoint:    ep,
+		})
+	}
+}
+
+// copyEndpoints copies the endpoints passed to the xDS server, which caches data on them.
+func copyEndpoints(endpoints []*model.IstioEndpoint) []*model.IstioEndpoint {
+	out := make([]*model.IstioEndpoint, 0, len(endpoints))
+	for _, ep := range endpoints {
+		out = append(out, ep.DeepCopy())
+	}
+	return out
+}
+
+// Services returns the services, sorted by hostname.
+func (s *Store) Services() []*model.Service {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	out := make([]*model.Service, 0, len(s.services))
+	for _, svc := range s.services {
+		out = append(out, svc)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Hostname < out[j].Hostname
+	})
+	return out
+}
+
+// GetService returns the service with the hostname, or nil.
+func (s *Store) GetService(hostname host.Name) *model.Service {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.services[hostname]
+}
+
+// InstancesByPort returns the instances of the service port whose labels match lbls.
+func (s *Store) InstancesByPort(svc *model.Service, port int, lbls labels.Instance) []*model.ServiceInstance {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	current := s.services[svc.Hostname]
+	if current == nil {
+		return nil
+	}
+	servicePort, f := current.Ports.GetByPort(port)
+	if !f {
+		return nil
+	}
+	var out []*model.ServiceInstance
+	for _, ep := range s.endpoints[svc.Hostname] {
+		if ep.ServicePortName == servicePort.Name && lbls.SubsetOf(ep.Labels) {
+			out = append(out, &model.ServiceInstance{
+				Service:     current,
+				ServicePort: servicePort,
+				Endpoint:    ep,
+			})
+		}
+	}
+	return out
+}
+
+// GetProxyServiceInstances returns the service instances co-located with the proxy, sorted by hostname.
+func (s *Store) GetProxyServiceInstances(node *model.Proxy) []*model.ServiceInstance {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var out []*model.ServiceInstance
+	for _, ip := range node.IPAddresses {
+		byHost := s.instancesByIP[ip]
+		hostnames := make([]host.Name, 0, len(byHost))
+		for hostname := range byHost {
+			hostnames = append(hostnames, hostname)
+		}
+		sort.Slice(hostnames, func(i, j int) bool {
+			return hostnames[i] < hostnames[j]
+		})
+		for _, hostname := range hostnames {
+			out = append(out, byHost[hostname]...)
+		}
+	}
+	return out
+}
+
+// PortName returns the name of a service port, unique within the service, for registries that only know the
+// port number and protocol.
+func PortName(port int, proto protocol.Instance) string {
+	if proto == protocol.Unsupported {
+		return fmt.Sprintf("port-%d", port)
+	}
+	return fmt.Sprintf("%s-%d", strings.ToLower(string(proto)), port)
+}
@@ -0,0 +1,86 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package servicestore
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func service(hostname host.Name) *model.Service {
+	return &model.Service{
+		Hostname: hostname,
+		Ports:    model.PortList{{Name: "http", Port: 80, Protocol: protocol.HTTP}},
+	}
+}
+
+func endpoint(address, version string) *model.IstioEndpoint {
+	return &model.IstioEndpoint{
+		Address:         address,
+		ServicePortName: "http",
+		EndpointPort:    8080,
+		Labels:          labels.Instance{"version": version},
+	}
+}
+
+func set(svc *model.Service, endpoints ...*model.IstioEndpoint) func(*model.Service) (*model.Service, []*model.IstioEndpoint, bool) {
+	return func(*model.Service) (*model.Service, []*model.IstioEndpoint, bool) {
+		return svc, endpoints, true
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := New()
+	a := service("a.example")
+	b := service("b.example")
+
+	change := s.Update(a.Hostname, set(a, endpoint("10.0.0.1", "v1")))
+	assert.Equal(t, change.Event(), model.EventAdd)
+	assert.Equal(t, change.ServiceChanged, true)
+	s.Update(b.Hostname, set(b, endpoint("10.0.0.1", "v1")))
+	assert.Equal(t, s.Services(), []*model.Service{a, b})
+	assert.Equal(t, s.GetService(a.Hostname), a)
+
+	// Unchanged
+	assert.Equal(t, s.Update(a.Hostname, set(a, endpoint("10.0.0.1", "v1"))), nil)
+	// Aborted
+	assert.Equal(t, s.Update(a.Hostname, func(*model.Service) (*model.Service, []*model.IstioEndpoint, bool) {
+		return nil, nil, false
+	}), nil)
+
+	// Endpoints only
+	change = s.Update(a.Hostname, set(a, endpoint("10.0.0.1", "v1"), endpoint("10.0.0.2", "v2")))
+	assert.Equal(t, change.Event(), model.EventUpdate)
+	assert.Equal(t, change.ServiceChanged, false)
+	assert.Equal(t, len(s.InstancesByPort(a, 80, nil)), 2)
+	assert.Equal(t, len(s.InstancesByPort(a, 80, labels.Instance{"version": "v2"})), 1)
+	assert.Equal(t, len(s.InstancesByPort(a, 81, nil)), 0)
+
+	instances := s.GetProxyServiceInstances(&model.Proxy{IPAddresses: []string{"10.0.0.1"}})
+	assert.Equal(t, len(instances), 2)
+	assert.Equal(t, instances[0].Service, a)
+	assert.Equal(t, instances[1].Service, b)
+
+	change = s.Update(b.Hostname, set(nil))
+	assert.Equal(t, change.Event(), model.EventDelete)
+	assert.Equal(t, change.Prev, b)
+	assert.Equal(t, s.GetService(b.Hostname), nil)
+	assert.Equal(t, len(s.GetProxyServiceInstances(&model.Proxy{IPAddresses: []string{"10.0.0.1"}})), 1)
+}
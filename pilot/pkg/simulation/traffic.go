@@ -174,66 +174,83 @@ type Result struct {
 	t    test.Failer
 }
 
-func (r Result) Matches(t *testing.T, want Result) {
+// Matches fails the test if the result does not match want. Fields unset in want are ignored, unless StrictMatch
+// is set.
+func (r Result) Matches(t test.Failer, want Result) {
 	t.Helper()
 	r.StrictMatch = want.StrictMatch // to make diff pass
 	r.Skip = want.Skip               // to make diff pass
 	diff := cmp.Diff(want, r, cmpopts.IgnoreUnexported(Result{}), cmpopts.EquateErrors())
 	if want.StrictMatch && diff != "" {
-		t.Errorf("Diff: %v", diff)
+		fail(t, fmt.Sprintf("Diff: %v", diff))
 		return
 	}
+	var errs []string
 	if want.Error != r.Error {
-		t.Errorf("want error %v got %v", want.Error, r.Error)
+		errs = append(errs, fmt.Sprintf("want error %v got %v", want.Error, r.Error))
 	}
 	if want.ListenerMatched != "" && want.ListenerMatched != r.ListenerMatched {
-		t.Errorf("want listener matched %q got %q", want.ListenerMatched, r.ListenerMatched)
+		errs = append(errs, fmt.Sprintf("want listener matched %q got %q", want.ListenerMatched, r.ListenerMatched))
 	} else {
 		// Populate each field in case we did not care about it. This avoids confusing errors when we have fields
 		// we don't care about in the test that are present in the result.
 		want.ListenerMatched = r.ListenerMatched
 	}
 	if want.FilterChainMatched != "" && want.FilterChainMatched != r.FilterChainMatched {
-		t.Errorf("want filter chain matched %q got %q", want.FilterChainMatched, r.FilterChainMatched)
+		errs = append(errs, fmt.Sprintf("want filter chain matched %q got %q", want.FilterChainMatched, r.FilterChainMatched))
 	} else {
 		want.FilterChainMatched = r.FilterChainMatched
 	}
 	if want.RouteMatched != "" && want.RouteMatched != r.RouteMatched {
-		t.Errorf("want route matched %q got %q", want.RouteMatched, r.RouteMatched)
+		errs = append(errs, fmt.Sprintf("want route matched %q got %q", want.RouteMatched, r.RouteMatched))
 	} else {
 		want.RouteMatched = r.RouteMatched
 	}
 	if want.RouteConfigMatched != "" && want.RouteConfigMatched != r.RouteConfigMatched {
-		t.Errorf("want route config matched %q got %q", want.RouteConfigMatched, r.RouteConfigMatched)
+		errs = append(errs, fmt.Sprintf("want route config matched %q got %q", want.RouteConfigMatched, r.RouteConfigMatched))
 	} else {
 		want.RouteConfigMatched = r.RouteConfigMatched
 	}
 	if want.VirtualHostMatched != "" && want.VirtualHostMatched != r.VirtualHostMatched {
-		t.Errorf("want virtual host matched %q got %q", want.VirtualHostMatched, r.VirtualHostMatched)
+		errs = append(errs, fmt.Sprintf("want virtual host matched %q got %q", want.VirtualHostMatched, r.VirtualHostMatched))
 	} else {
 		want.VirtualHostMatched = r.VirtualHostMatched
 	}
 	if want.ClusterMatched != "" && want.ClusterMatched != r.ClusterMatched {
-		t.Errorf("want cluster matched %q got %q", want.ClusterMatched, r.ClusterMatched)
+		errs = append(errs, fmt.Sprintf("want cluster matched %q got %q", want.ClusterMatched, r.ClusterMatched))
 	} else {
 		want.ClusterMatched = r.ClusterMatched
 	}
-	if t.Failed() {
+	if len(errs) > 0 {
 		t.Logf("Diff: %+v", diff)
 		t.Logf("Full Diff: %+v", cmp.Diff(want, r, cmpopts.IgnoreUnexported(Result{}), cmpopts.EquateErrors()))
+		fail(t, errs...)
 	} else if want.Skip != "" {
-		t.Skipf("Known bug: %v", r.Skip)
+		t.Skip(fmt.Sprintf("Known bug: %v", r.Skip))
 	}
 }
 
+// fail reports the errors. Tests continue after a failure, while other failers, such as the ones of test.Wrap,
+// stop at the first one.
+func fail(t test.Failer, errs ...string) {
+	t.Helper()
+	if tb, ok := t.(testing.TB); ok {
+		for _, err := range errs {
+			tb.Error(err)
+		}
+		return
+	}
+	t.Fatal(strings.Join(errs, "\n"))
+}
+
 type Simulation struct {
-	t         *testing.T
+	t         test.Failer
 	Listeners []*listener.Listener
 	Clusters  []*cluster.Cluster
 	Routes    []*route.RouteConfiguration
 }
 
-func NewSimulationFromConfigGen(t *testing.T, s *v1alpha3.ConfigGenTest, proxy *model.Proxy) *Simulation {
+func NewSimulationFromConfigGen(t test.Failer, s *v1alpha3.ConfigGenTest, proxy *model.Proxy) *Simulation {
 	l := s.Listeners(proxy)
 	sim := &Simulation{
 		t:         t,
@@ -244,7 +261,7 @@ func NewSimulationFromConfigGen(t *testing.T, s *v1alpha3.ConfigGenTest, proxy *
 	return sim
 }
 
-func NewSimulation(t *testing.T, s *xds.FakeDiscoveryServer, proxy *model.Proxy) *Simulation {
+func NewSimulation(t test.Failer, s *xds.FakeDiscoveryServer, proxy *model.Proxy) *Simulation {
 	return NewSimulationFromConfigGen(t, s.ConfigGenTest, proxy)
 }
 
@@ -255,9 +272,16 @@ func (sim *Simulation) withT(t *testing.T) *Simulation {
 	return &cpy
 }
 
+// RunExpectations runs each expectation as a sub test if the simulation was created with a *testing.T, or in
+// sequence otherwise.
 func (sim *Simulation) RunExpectations(es []Expect) {
+	t, ok := sim.t.(*testing.T)
 	for _, e := range es {
-		sim.t.Run(e.Name, func(t *testing.T) {
+		if !ok {
+			sim.Run(e.Call).Matches(sim.t, e.Result)
+			continue
+		}
+		t.Run(e.Name, func(t *testing.T) {
 			sim.withT(t).Run(e.Call).Matches(t, e.Result)
 		})
 	}
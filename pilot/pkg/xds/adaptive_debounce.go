@@ -0,0 +1,166 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// eventRateSmoothing is the weight of the latest debounce cycle in the event rate moving average.
+const eventRateSmoothing = 0.5
+
+// adaptiveDebounce adjusts the debounce quiet period based on recent pushes. The window is doubled whenever
+// istiod shows signs of falling behind, and halved otherwise:
+//   - the last push took longer than the window to reach all proxies, so pushing more often would only queue up
+//     push contexts
+//   - proxies from the previous push were still queued when the next one started
+//   - config events arrive faster than one per window, so a wider window merges more of them
+type adaptiveDebounce struct {
+	mu sync.RWMutex
+
+	min time.Duration
+	max time.Duration
+	// pending returns the number of proxies waiting in the push queue.
+	pending func() int
+	// drained returns a channel closed once all the proxies in the push queue have been pushed.
+	drained func() <-chan struct{}
+	// observing is true while a push is waiting for the push queue to drain.
+	observing atomic.Bool
+
+	window       time.Duration
+	pushDuration time.Duration
+	queueDepth   int
+	// eventRate is a moving average of the config events per second seen while debouncing.
+	eventRate float64
+}
+
+// AdaptiveDebounceStatus describes the current state of adaptive debounce.
+type AdaptiveDebounceStatus struct {
+	Window       string  `json:"window"`
+	Min          string  `json:"min"`
+	Max          string  `json:"max"`
+	PushDuration string  `json:"lastPushDuration"`
+	QueueDepth   int     `json:"lastQueueDepth"`
+	EventRate    float64 `json:"eventRate"`
+}
+
+func newAdaptiveDebounce(initial, min, max time.Duration, pending func() int, drained func() <-chan struct{}) *adaptiveDebounce {
+	if max < min {
+		max = min
+	}
+	a := &adaptiveDebounce{
+		min:     min,
+		max:     max,
+		pending: pending,
+		drained: drained,
+		window:  clampDuration(initial, min, max),
+	}
+	debounceWindow.Record(a.window.Seconds())
+	return a
+}
+
+// Window returns the current quiet period.
+func (a *adaptiveDebounce) Window() time.Duration {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.window
+}
+
+// QueueDepth returns the number of proxies currently waiting to be pushed.
+func (a *adaptiveDebounce) QueueDepth() int {
+	if a.pending == nil {
+		return 0
+	}
+	return a.pending()
+}
+
+// Observe records a push of events debounced over debounceDuration, which took pushDuration, while queueDepth
+// proxies were still waiting for the previous push. It returns the new quiet period.
+func (a *adaptiveDebounce) Observe(events int, debounceDuration, pushDuration time.Duration, queueDepth int) time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if debounceDuration > 0 {
+		rate := float64(events) / debounceDuration.Seconds()
+		a.eventRate = eventRateSmoothing*rate + (1-eventRateSmoothing)*a.eventRate
+	}
+	a.pushDuration = pushDuration
+	a.queueDepth = queueDepth
+
+	overloaded := pushDuration > a.window || queueDepth > 0 || a.eventRate*a.window.Seconds() > 1
+	if overloaded {
+		a.window = clampDuration(a.window*2, a.min, a.max)
+	} else {
+		a.window = clampDuration(a.window/2, a.min, a.max)
+	}
+	debounceWindow.Record(a.window.Seconds())
+	return a.window
+}
+
+// ObservePush observes the push started at start in the background: once it has reached all proxies, it is
+// recorded as in Observe, and done is called with the new quiet period. The wait is bounded by the max window, in
+// which case the push is considered to have taken at least that long.
+// Only one push is observed at a time. A push started while the previous one is still draining is not observed,
+// as the drain covers its proxies too, and ObservePush returns false.
+func (a *adaptiveDebounce) ObservePush(events int, debounceDuration time.Duration, start time.Time, queueDepth int,
+	done func(window time.Duration),
+) bool {
+	if !a.observing.CompareAndSwap(false, true) {
+		return false
+	}
+	go func() {
+		defer a.observing.Store(false)
+		if a.drained != nil {
+			timer := time.NewTimer(a.max)
+			select {
+			case <-a.drained():
+			case <-timer.C:
+			}
+			timer.Stop()
+		}
+		window := a.Observe(events, debounceDuration, time.Since(start), queueDepth)
+		if done != nil {
+			done(window)
+		}
+	}()
+	return true
+}
+
+// Status returns the current state, for debugging.
+func (a *adaptiveDebounce) Status() AdaptiveDebounceStatus {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return AdaptiveDebounceStatus{
+		Window:       a.window.String(),
+		Min:          a.min.String(),
+		Max:          a.max.String(),
+		PushDuration: a.pushDuration.String(),
+		QueueDepth:   a.queueDepth,
+		EventRate:    a.eventRate,
+	}
+}
+
+func clampDuration(d, min, max time.Duration) time.Duration {
+	if d < min {
+		return min
+	}
+	if d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestAdaptiveDebounce(t *testing.T) {
+	cases := []struct {
+		name          string
+		events        int
+		debounce      time.Duration
+		push          time.Duration
+		queueDepth    int
+		initialWindow time.Duration
+		want          time.Duration
+	}{
+		{
+			name:          "idle narrows",
+			events:        1,
+			debounce:      2 * time.Second,
+			push:          10 * time.Millisecond,
+			initialWindow: 400 * time.Millisecond,
+			want:          200 * time.Millisecond,
+		},
+		{
+			name:          "slow push widens",
+			events:        1,
+			debounce:      2 * time.Second,
+			push:          time.Second,
+			initialWindow: 400 * time.Millisecond,
+			want:          800 * time.Millisecond,
+		},
+		{
+			name:          "queued proxies widen",
+			events:        1,
+			debounce:      2 * time.Second,
+			push:          10 * time.Millisecond,
+			queueDepth:    10,
+			initialWindow: 400 * time.Millisecond,
+			want:          800 * time.Millisecond,
+		},
+		{
+			name:          "event storm widens",
+			events:        100,
+			debounce:      time.Second,
+			push:          10 * time.Millisecond,
+			initialWindow: 400 * time.Millisecond,
+			want:          800 * time.Millisecond,
+		},
+		{
+			name:          "bounded by max",
+			events:        1,
+			debounce:      2 * time.Second,
+			push:          5 * time.Second,
+			initialWindow: 1500 * time.Millisecond,
+			want:          2 * time.Second,
+		},
+		{
+			name:          "bounded by min",
+			events:        1,
+			debounce:      2 * time.Second,
+			push:          10 * time.Millisecond,
+			initialWindow: 60 * time.Millisecond,
+			want:          50 * time.Millisecond,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAdaptiveDebounce(tt.initialWindow, 50*time.Millisecond, 2*time.Second, nil, nil)
+			assert.Equal(t, a.Observe(tt.events, tt.debounce, tt.push, tt.queueDepth), tt.want)
+			assert.Equal(t, a.Window(), tt.want)
+		})
+	}
+}
+
+func TestAdaptiveDebounceObservePush(t *testing.T) {
+	drained := make(chan struct{})
+	a := newAdaptiveDebounce(100*time.Millisecond, 50*time.Millisecond, 2*time.Second, nil, func() <-chan struct{} {
+		return drained
+	})
+	done := make(chan time.Duration, 3)
+	observe := func(window time.Duration) {
+		done <- window
+	}
+	start := time.Now()
+	assert.Equal(t, a.ObservePush(1, 2*time.Second, start, 0, observe), true)
+	// Back to back pushes are not observed while the first one waits for the queue to drain
+	assert.Equal(t, a.ObservePush(1, 2*time.Second, time.Now(), 0, observe), false)
+	assert.Equal(t, a.ObservePush(1, 2*time.Second, time.Now(), 0, observe), false)
+	// The push only completes once the queue is drained, which takes longer than the window.
+	time.Sleep(150 * time.Millisecond)
+	close(drained)
+	assert.Equal(t, <-done, 200*time.Millisecond)
+
+	// Once observed, the next push is observed again
+	retry.UntilOrFail(t, func() bool {
+		return a.ObservePush(1, 2*time.Second, time.Now(), 0, observe)
+	}, retry.Timeout(time.Second))
+	assert.Equal(t, <-done, 100*time.Millisecond)
+	assert.Equal(t, len(done), 0)
+}
+
+func TestAdaptiveDebounceStatus(t *testing.T) {
+	out, err := withAdaptiveDebounceStatus([]byte(`{"pilot_no_ip":{}}`), AdaptiveDebounceStatus{Window: "100ms"})
+	assert.NoError(t, err)
+	status := string(out)
+	for _, want := range []string{`"pilot_no_ip"`, `"adaptiveDebounce"`, `"window": "100ms"`} {
+		if !strings.Contains(status, want) {
+			t.Fatalf("expected %s in %s", want, status)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"google.golang.org/protobuf/proto"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/kind"
+	istioversion "istio.io/pkg/version"
+)
+
+// xdsCacheSnapshotFile is the name of the XDS cache snapshot in the persistence directory.
+const xdsCacheSnapshotFile = "xds-cache.json"
+
+// LoadXdsCache warms the XDS cache from the snapshot in dir, skipping entries whose dependent configs changed since
+// the snapshot was written. This should be called once caches are synced, before serving any proxy.
+func (s *DiscoveryServer) LoadXdsCache(dir string) error {
+	snapshotter, ok := s.Cache.(model.XdsCacheSnapshotter)
+	if !ok {
+		return nil
+	}
+	f, err := os.Open(filepath.Join(dir, xdsCacheSnapshotFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	t0 := time.Now()
+	restored, err := snapshotter.ReadSnapshot(f, s.xdsCacheVersions())
+	if err != nil {
+		return fmt.Errorf("failed to read xds cache snapshot: %v", err)
+	}
+	log.Infof("restored %d xds cache entries from %s in %v", restored, dir, time.Since(t0))
+	return nil
+}
+
+// SaveXdsCache writes a snapshot of the XDS cache to dir. The snapshot is written to a temporary file first, so
+// that a crash while writing never leaves a truncated snapshot behind.
+func (s *DiscoveryServer) SaveXdsCache(dir string) error {
+	snapshotter, ok := s.Cache.(model.XdsCacheSnapshotter)
+	if !ok {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, xdsCacheSnapshotFile+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	written, err := snapshotter.WriteSnapshot(f, s.xdsCacheVersions())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write xds cache snapshot: %v", err)
+	}
+	if err := os.Rename(f.Name(), filepath.Join(dir, xdsCacheSnapshotFile)); err != nil {
+		return err
+	}
+	log.Debugf("persisted %d xds cache entries to %s", written, dir)
+	return nil
+}
+
+// PersistXdsCache periodically writes a snapshot of the XDS cache to dir, and a last one on shutdown.
+func (s *DiscoveryServer) PersistXdsCache(dir string, interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.SaveXdsCache(dir); err != nil {
+				log.Warnf("failed to persist xds cache: %v", err)
+			}
+		case <-stopCh:
+			if err := s.SaveXdsCache(dir); err != nil {
+				log.Warnf("failed to persist xds cache: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// xdsCacheVersions computes the current version of every config XDS cache entries may depend on. Services are
+// versioned by their resource version along with their endpoints, as EDS entries depend on the latter.
+func (s *DiscoveryServer) xdsCacheVersions() model.XdsCacheVersions {
+	versions := model.XdsCacheVersions{
+		Global:  s.globalCacheVersion(),
+		Configs: map[model.ConfigHash]string{},
+		Types:   map[kind.Kind]string{},
+	}
+	if s.Env.ConfigStore != nil {
+		for _, schema := range s.Env.ConfigStore.Schemas().All() {
+			gvk := schema.GroupVersionKind()
+			k := kind.FromGvk(gvk)
+			cfgs, err := s.Env.ConfigStore.List(gvk, metav1.NamespaceAll)
+			if err != nil {
+				continue
+			}
+			sort.Slice(cfgs, func(i, j int) bool {
+				if cfgs[i].Namespace == cfgs[j].Namespace {
+					return cfgs[i].Name < cfgs[j].Name
+				}
+				return cfgs[i].Namespace < cfgs[j].Namespace
+			})
+			typeHash := xxhash.New()
+			for _, cfg := range cfgs {
+				if cfg.ResourceVersion == "" {
+					// Without a resource version we cannot tell whether the config changed.
+					typeHash = nil
+					continue
+				}
+				versions.Configs[model.ConfigKey{Kind: k, Name: cfg.Name, Namespace: cfg.Namespace}.HashCode()] = cfg.ResourceVersion
+				if typeHash != nil {
+					_, _ = typeHash.WriteString(cfg.Namespace + "/" + cfg.Name + "/" + cfg.ResourceVersion + "\n")
+				}
+			}
+			if typeHash != nil {
+				versions.Types[k] = strconv.FormatUint(typeHash.Sum64(), 16)
+			}
+		}
+	}
+	if s.Env.ServiceDiscovery != nil {
+		for _, svc := range s.Env.Services() {
+			if svc.ResourceVersion == "" {
+				continue
+			}
+			key := model.ConfigKey{Kind: kind.ServiceEntry, Name: string(svc.Hostname), Namespace: svc.Attributes.Namespace}
+			versions.Configs[key.HashCode()] = svc.ResourceVersion + "/" + s.endpointsVersion(svc)
+		}
+	}
+	return versions
+}
+
+// globalCacheVersion identifies the inputs of config generation that are not tracked as dependencies of
+// cache entries. Cache keys are also only stable for a given build.
+func (s *DiscoveryServer) globalCacheVersion() string {
+	hash := xxhash.New()
+	_, _ = hash.WriteString(istioversion.Info.String())
+	if m := s.Env.Mesh(); m != nil {
+		writeProto(hash, m)
+	}
+	if n := s.Env.MeshNetworks(); n != nil {
+		writeProto(hash, n)
+	}
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
+
+func writeProto(hash *xxhash.Digest, m proto.Message) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return
+	}
+	_, _ = hash.Write(b)
+}
+
+// endpointsVersion returns a hash of the endpoints of the service, in a stable order.
+func (s *DiscoveryServer) endpointsVersion(svc *model.Service) string {
+	shards, f := s.Env.EndpointIndex.ShardsForService(string(svc.Hostname), svc.Attributes.Namespace)
+	if !f {
+		return "0"
+	}
+	var eps []string
+	shards.RLock()
+	for _, key := range shards.Keys() {
+		for _, ep := range shards.Shards[key] {
+			eps = append(eps, fmt.Sprintf("%v|%s|%s|%d|%s|%d|%s|%s|%s|%v|%d|%s|%s|%s",
+				key, ep.Address, ep.ServicePortName, ep.EndpointPort, ep.Network, ep.HealthStatus, ep.Locality.Label,
+				ep.Locality.ClusterID, ep.ServiceAccount, ep.Labels, ep.LbWeight, ep.TLSMode, ep.WorkloadName, ep.Namespace))
+		}
+	}
+	shards.RUnlock()
+	sort.Strings(eps)
+	hash := xxhash.New()
+	for _, ep := range eps {
+		_, _ = hash.WriteString(ep + "\n")
+	}
+	return strconv.FormatUint(hash.Sum64(), 16)
+}
@@ -0,0 +1,171 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	networking "istio.io/api/networking/v1alpha3"
+	security "istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+type snapshotEntry struct {
+	key              string
+	dependentTypes   []kind.Kind
+	dependentConfigs []model.ConfigHash
+}
+
+func (e *snapshotEntry) Key() string {
+	return e.key
+}
+
+func (e *snapshotEntry) DependentTypes() []kind.Kind {
+	return e.dependentTypes
+}
+
+func (e *snapshotEntry) DependentConfigs() []model.ConfigHash {
+	return e.dependentConfigs
+}
+
+func (e *snapshotEntry) Cacheable() bool {
+	return true
+}
+
+// unversionedStore drops the resource version of the configs of a kind.
+type unversionedStore struct {
+	model.ConfigStore
+	kind config.GroupVersionKind
+}
+
+func (s unversionedStore) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+	cfgs, err := s.ConfigStore.List(typ, namespace)
+	if typ != s.kind {
+		return cfgs, err
+	}
+	out := make([]config.Config, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		cfg.ResourceVersion = ""
+		out = append(out, cfg)
+	}
+	return out, err
+}
+
+func TestXdsCacheSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	svcKey := model.ConfigKey{Kind: kind.ServiceEntry, Name: "foo.com", Namespace: "default"}.HashCode()
+	drKey := model.ConfigKey{Kind: kind.DestinationRule, Name: "foo", Namespace: "default"}.HashCode()
+	entries := []*snapshotEntry{
+		{key: "svc", dependentConfigs: []model.ConfigHash{svcKey}},
+		{key: "svc-dr", dependentConfigs: []model.ConfigHash{svcKey, drKey}},
+		{key: "svc-pa", dependentConfigs: []model.ConfigHash{svcKey}, dependentTypes: []kind.Kind{kind.PeerAuthentication}},
+	}
+
+	type options struct {
+		drVersion  string
+		address    string
+		modifyOpts func(*xds.FakeOptions)
+	}
+	// newServer starts an istiod, as after a restart, with the given config versions and service endpoints.
+	newServer := func(o options) *xds.FakeDiscoveryServer {
+		t.Helper()
+		opts := xds.FakeOptions{
+			Services: []*model.Service{{
+				Hostname:        "foo.com",
+				DefaultAddress:  "10.11.0.1",
+				Ports:           []*model.Port{{Name: "http", Port: 80, Protocol: protocol.HTTP}},
+				Attributes:      model.ServiceAttributes{Name: "foo", Namespace: "default"},
+				ResourceVersion: "1",
+			}},
+			Configs: []config.Config{
+				{
+					Meta: config.Meta{
+						GroupVersionKind: gvk.DestinationRule,
+						Name:             "foo",
+						Namespace:        "default",
+						ResourceVersion:  o.drVersion,
+					},
+					Spec: &networking.DestinationRule{Host: "foo.com"},
+				},
+				{
+					Meta: config.Meta{
+						GroupVersionKind: gvk.PeerAuthentication,
+						Name:             "default",
+						Namespace:        "default",
+						ResourceVersion:  "1",
+					},
+					Spec: &security.PeerAuthentication{},
+				},
+			},
+		}
+		if o.modifyOpts != nil {
+			o.modifyOpts(&opts)
+		}
+		s := xds.NewFakeDiscoveryServer(t, opts)
+		s.MemRegistry.SetEndpoints("foo.com", "default", []*model.IstioEndpoint{{
+			Address:         o.address,
+			ServicePortName: "http",
+			EndpointPort:    8080,
+		}})
+		return s
+	}
+	cached := func(s *xds.FakeDiscoveryServer) []string {
+		t.Helper()
+		assert.NoError(t, s.Discovery.LoadXdsCache(dir))
+		var out []string
+		for _, e := range entries {
+			if _, f := s.Discovery.Cache.Get(e); f {
+				out = append(out, e.key)
+			}
+		}
+		return out
+	}
+
+	initial := options{drVersion: "1", address: "10.0.0.1"}
+	s := newServer(initial)
+	for _, e := range entries {
+		s.Discovery.Cache.Add(e, &model.PushRequest{Start: time.Now()}, &discovery.Resource{Name: e.key})
+	}
+	assert.NoError(t, s.Discovery.SaveXdsCache(dir))
+
+	t.Run("unchanged", func(t *testing.T) {
+		assert.Equal(t, cached(newServer(initial)), []string{"svc", "svc-dr", "svc-pa"})
+	})
+	t.Run("config changed", func(t *testing.T) {
+		assert.Equal(t, cached(newServer(options{drVersion: "2", address: "10.0.0.1"})), []string{"svc", "svc-pa"})
+	})
+	t.Run("endpoints changed", func(t *testing.T) {
+		assert.Equal(t, cached(newServer(options{drVersion: "1", address: "10.0.0.2"})), nil)
+	})
+	t.Run("unversioned config", func(t *testing.T) {
+		o := initial
+		o.modifyOpts = func(opts *xds.FakeOptions) {
+			opts.DiscoveryServerModifier = func(s *xds.DiscoveryServer) {
+				s.Env.ConfigStore = unversionedStore{ConfigStore: s.Env.ConfigStore, kind: gvk.PeerAuthentication}
+			}
+		}
+		// Without a version, any of the configs of the kind may have changed
+		assert.Equal(t, cached(newServer(o)), []string{"svc", "svc-dr"})
+	})
+}
@@ -188,6 +188,8 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 
 	s.addDebugHandler(mux, internalMux, "/debug/ecdsz", "Status and debug interface for ECDS", s.ecdsz)
 	s.addDebugHandler(mux, internalMux, "/debug/edsz", "Status and debug interface for EDS", s.Edsz)
+	s.addDebugHandler(mux, internalMux, "/debug/endpoint_explain",
+		"Explains why an endpoint address is, or is not, sent to the passed in proxyID", s.endpointExplain)
 	s.addDebugHandler(mux, internalMux, "/debug/ndsz", "Status and debug interface for NDS", s.ndsz)
 	s.addDebugHandler(mux, internalMux, "/debug/adsz", "Status and debug interface for ADS", s.adsz)
 	s.addDebugHandler(mux, internalMux, "/debug/adsz?push=true", "Initiates push of the current state to all connected endpoints", s.adsz)
@@ -216,6 +218,7 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/inject", "Active inject template", s.injectTemplateHandler(webhook))
 	s.addDebugHandler(mux, internalMux, "/debug/mesh", "Active mesh config", s.meshHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/clusterz", "List remote clusters where istiod reads endpoints", s.clusterz)
+	s.addDebugHandler(mux, internalMux, "/debug/configsourcez", "List config sources read from remote clusters", s.configsourcez)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
 	s.addDebugHandler(mux, internalMux, "/debug/mcsz", "List information about Kubernetes MCS services", s.mcsz)
 
@@ -919,11 +922,32 @@ func (s *DiscoveryServer) pushStatusHandler(w http.ResponseWriter, req *http.Req
 		handleHTTPError(w, err)
 		return
 	}
+	if s.debounceOptions.adaptive != nil {
+		out, err = withAdaptiveDebounceStatus(out, s.debounceOptions.adaptive.Status())
+		if err != nil {
+			handleHTTPError(w, err)
+			return
+		}
+	}
 	w.Header().Add("Content-Type", "application/json")
 
 	_, _ = w.Write(out)
 }
 
+// withAdaptiveDebounceStatus adds the adaptive debounce state to the push status, under the "adaptiveDebounce" key.
+func withAdaptiveDebounceStatus(pushStatus []byte, debounce AdaptiveDebounceStatus) ([]byte, error) {
+	status := map[string]json.RawMessage{}
+	if err := json.Unmarshal(pushStatus, &status); err != nil {
+		return nil, err
+	}
+	d, err := json.Marshal(debounce)
+	if err != nil {
+		return nil, err
+	}
+	status["adaptiveDebounce"] = d
+	return json.MarshalIndent(status, "", "    ")
+}
+
 // PushContextDebug holds debug information for push context.
 type PushContextDebug struct {
 	AuthorizationPolicies *model.AuthorizationPolicies
@@ -1040,6 +1064,47 @@ func (s *DiscoveryServer) Edsz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, eps, req)
 }
 
+// endpointExplain reports the decisions that kept or dropped the endpoints with the address
+// in the EDS of the proxy. With a cluster, only that cluster is explained; otherwise all the
+// EDS clusters of the proxy with an endpoint with the address are.
+func (s *DiscoveryServer) endpointExplain(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+	address := req.URL.Query().Get("address")
+	if address == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide an address in the query string\n"))
+		return
+	}
+
+	clusters := con.Clusters()
+	push := con.proxy.LastPushContext
+	out := []EndpointExplanation{}
+	if clusterName := req.URL.Query().Get("cluster"); clusterName != "" {
+		e, _ := s.ExplainEndpoint(con.proxy, push, clusterName, address)
+		if !sets.New(clusters...).Contains(clusterName) {
+			e.Sent = false
+			e.Decisions = append([]EndpointDecision{{
+				Endpoint: address,
+				Stage:    StageService,
+				Reason: "the proxy does not watch the endpoints of the cluster, the cluster is not an EDS cluster " +
+					"or is not sent to the proxy",
+			}}, e.Decisions...)
+		}
+		out = append(out, e)
+	} else {
+		for _, clusterName := range clusters {
+			if e, found := s.ExplainEndpoint(con.proxy, push, clusterName, address); found {
+				out = append(out, e)
+			}
+		}
+	}
+	writeJSON(w, out, req)
+}
+
 func (s *DiscoveryServer) forceDisconnect(w http.ResponseWriter, req *http.Request) {
 	proxyID, con := s.getDebugConnection(req)
 	if con == nil {
@@ -1128,6 +1193,14 @@ func (s *DiscoveryServer) clusterz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, s.ListRemoteClusters(), req)
 }
 
+func (s *DiscoveryServer) configsourcez(w http.ResponseWriter, req *http.Request) {
+	if s.ListConfigSources == nil {
+		w.WriteHeader(400)
+		return
+	}
+	writeJSON(w, s.ListConfigSources(), req)
+}
+
 // handlePushRequest handles a ?push=true query param and triggers a push.
 // A boolean response is returned to indicate if the caller should continue
 func (s *DiscoveryServer) handlePushRequest(w http.ResponseWriter, req *http.Request) bool {
@@ -20,12 +20,15 @@ import (
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/util/sets"
 )
 
@@ -213,3 +216,113 @@ func TestDeltaReconnectRequests(t *testing.T) {
 		t.Fatalf("unexpected remove resources: %v", resn)
 	}
 }
+
+func TestDeltaLDS(t *testing.T) {
+	test.SetForTest(t, &features.EnableUnsafeDeltaTest, true)
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	s.MemRegistry.AddHTTPService("http.example.com", "10.10.0.1", 8080)
+	s.MemRegistry.AddService(tcpService("tcp.example.com", "10.10.0.2", 9090))
+
+	ads := s.ConnectDeltaADS().WithType(v3.ListenerType)
+	res := ads.RequestResponseAck(nil)
+	if resn := xdstest.ExtractResource(res.Resources); !resn.Contains("0.0.0.0_8080") || !resn.Contains("10.10.0.2_9090") {
+		t.Fatalf("unexpected resources: %v", resn)
+	}
+
+	// A push without config updates sends everything
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
+	res = ads.ExpectResponse()
+	if resn := xdstest.ExtractResource(res.Resources); !resn.Contains("0.0.0.0_8080") || !resn.Contains("10.10.0.2_9090") {
+		t.Fatalf("unexpected resources: %v", resn)
+	}
+
+	// Adding a service only sends the listeners for its port
+	s.MemRegistry.AddService(tcpService("new.example.com", "10.10.0.3", 7070))
+	s.Discovery.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.ServiceEntry, Name: "new.example.com", Namespace: "default"}),
+	})
+	res = ads.ExpectResponse()
+	if resn := xdstest.ExtractResource(res.Resources); !resn.Equals(sets.New("10.10.0.3_7070")) {
+		t.Fatalf("unexpected resources: %v", resn)
+	}
+	if len(res.RemovedResources) != 0 {
+		t.Fatalf("unexpected removed resources: %v", res.RemovedResources)
+	}
+
+	// Removing the service removes its listener
+	s.MemRegistry.RemoveService("new.example.com")
+	s.Discovery.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.ServiceEntry, Name: "new.example.com", Namespace: "default"}),
+	})
+	res = ads.ExpectResponse()
+	if len(res.Resources) != 0 {
+		t.Fatalf("unexpected resources: %v", xdstest.ExtractResource(res.Resources))
+	}
+	if resn := sets.New(res.RemovedResources...); !resn.Equals(sets.New("10.10.0.3_7070")) {
+		t.Fatalf("unexpected removed resources: %v", resn)
+	}
+}
+
+func TestDeltaRDS(t *testing.T) {
+	test.SetForTest(t, &features.EnableUnsafeDeltaTest, true)
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	s.MemRegistry.AddHTTPService("a.example.com", "10.10.0.1", 8080)
+	s.MemRegistry.AddHTTPService("b.example.com", "10.10.0.2", 9090)
+
+	ads := s.ConnectDeltaADS().WithType(v3.RouteType)
+	res := ads.RequestResponseAck(&discovery.DeltaDiscoveryRequest{
+		ResourceNamesSubscribe: []string{"8080", "9090"},
+	})
+	if resn := xdstest.ExtractResource(res.Resources); !resn.Equals(sets.New("8080", "9090")) {
+		t.Fatalf("unexpected resources: %v", resn)
+	}
+
+	// A push without config updates sends everything
+	s.Discovery.ConfigUpdate(&model.PushRequest{Full: true})
+	res = ads.ExpectResponse()
+	if resn := xdstest.ExtractResource(res.Resources); !resn.Equals(sets.New("8080", "9090")) {
+		t.Fatalf("unexpected resources: %v", resn)
+	}
+
+	// Updating a service only sends the routes for its port
+	s.MemRegistry.AddHTTPService("c.example.com", "10.10.0.3", 9090)
+	s.Discovery.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.ServiceEntry, Name: "c.example.com", Namespace: "default"}),
+	})
+	res = ads.ExpectResponse()
+	if resn := xdstest.ExtractResource(res.Resources); !resn.Equals(sets.New("9090")) {
+		t.Fatalf("unexpected resources: %v", resn)
+	}
+
+	// Removing the service still updates the route for its port
+	s.MemRegistry.RemoveService("c.example.com")
+	s.Discovery.ConfigUpdate(&model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.ServiceEntry, Name: "c.example.com", Namespace: "default"}),
+	})
+	res = ads.ExpectResponse()
+	if resn := xdstest.ExtractResource(res.Resources); !resn.Equals(sets.New("9090")) {
+		t.Fatalf("unexpected resources: %v", resn)
+	}
+}
+
+func tcpService(hostname, vip string, port int) *model.Service {
+	return &model.Service{
+		Hostname:       host.Name(hostname),
+		DefaultAddress: vip,
+		Ports: model.PortList{
+			{
+				Name:     "tcp",
+				Port:     port,
+				Protocol: protocol.TCP,
+			},
+		},
+		Attributes: model.ServiceAttributes{
+			Name:      hostname,
+			Namespace: "default",
+		},
+	}
+}
@@ -139,6 +139,45 @@ func (s *DiscoveryServer) compareDiff(
 					v3.GetShortType(w.TypeUrl), con.proxy.ID, extraChanges, len(gotDeleted), len(gotChanged))
 			}
 		}
+		if usedDelta && !incremental {
+			s.compareAppliedDelta(con, w, current, full, resp, deleted, details)
+		}
+	}
+}
+
+// compareAppliedDelta checks that applying a Delta XDS response onto the resources the client currently has results
+// in the same state as the SotW response. Unlike the per-resource checks above, this catches resources sent with
+// stale content, and for wildcard types, resources the client would keep although SotW no longer generates them.
+func (s *DiscoveryServer) compareAppliedDelta(
+	con *Connection,
+	w *model.WatchedResource,
+	current model.Resources,
+	full model.Resources,
+	resp model.Resources,
+	deleted model.DeletedResources,
+	details string,
+) {
+	applied := applyDelta(current, &discovery.DeltaDiscoveryResponse{Resources: resp, RemovedResources: deleted})
+	appliedByName := map[string]*discovery.Resource{}
+	for _, v := range applied {
+		appliedByName[v.Name] = v
+	}
+	var stale []string
+	fullNames := sets.New[string]()
+	for _, v := range full {
+		fullNames.Insert(v.Name)
+		a := appliedByName[v.Name]
+		if a == nil || cmp.Diff(a.Resource, v.Resource, protocmp.Transform()) != "" {
+			stale = append(stale, v.Name)
+		}
+	}
+	if len(stale) > 0 {
+		log.Errorf("%s: TEST for node:%s applied delta differs from sotw: %v %v", v3.GetShortType(w.TypeUrl), con.proxy.ID, details, stale)
+	}
+	if isWildcardTypeURL(w.TypeUrl) {
+		if leaked := sets.SortedList(sets.New(extractNames(applied)...).Difference(fullNames)); len(leaked) > 0 {
+			log.Errorf("%s: TEST for node:%s applied delta keeps resources not in sotw: %v %v", v3.GetShortType(w.TypeUrl), con.proxy.ID, details, leaked)
+		}
 	}
 }
 
@@ -70,6 +70,17 @@ type debounceOptions struct {
 
 	// enableEDSDebounce indicates whether EDS pushes should be debounced.
 	enableEDSDebounce bool
+
+	// adaptive, if set, replaces debounceAfter with a quiet period adjusted after each push.
+	adaptive *adaptiveDebounce
+}
+
+// quietPeriod returns the time to wait without events before pushing.
+func (o debounceOptions) quietPeriod() time.Duration {
+	if o.adaptive != nil {
+		return o.adaptive.Window()
+	}
+	return o.debounceAfter
 }
 
 // DiscoveryServer is Pilot's gRPC implementation for Envoy's xds APIs
@@ -151,6 +162,9 @@ type DiscoveryServer struct {
 	// ListRemoteClusters collects debug information about other clusters this istiod reads from.
 	ListRemoteClusters func() []cluster.DebugInfo
 
+	// ListConfigSources collects debug information about the config sources read from remote clusters.
+	ListConfigSources func() []cluster.ConfigSourceDebugInfo
+
 	// ClusterAliases are aliase names for cluster. When a proxy connects with a cluster ID
 	// and if it has a different alias we should use that a cluster ID for proxy.
 	ClusterAliases map[cluster.ID]cluster.ID
@@ -179,6 +193,11 @@ func NewDiscoveryServer(env *model.Environment, instanceID string, clusterAliase
 		instanceID: instanceID,
 	}
 
+	if features.EnableAdaptiveDebounce {
+		out.debounceOptions.adaptive = newAdaptiveDebounce(features.DebounceAfter,
+			features.AdaptiveDebounceMin, features.AdaptiveDebounceMax, out.pushQueue.Pending, out.pushQueue.Drained)
+	}
+
 	out.ClusterAliases = make(map[cluster.ID]cluster.ID)
 	for alias := range clusterAliases {
 		out.ClusterAliases[cluster.ID(alias)] = cluster.ID(clusterAliases[alias])
@@ -260,7 +279,9 @@ func (s *DiscoveryServer) getNonK8sRegistries() []serviceregistry.Instance {
 	}
 
 	for _, registry := range registries {
-		if registry.Provider() != provider.Kubernetes && registry.Provider() != provider.External {
+		// These registries push their own endpoint updates.
+		if registry.Provider() != provider.Kubernetes && registry.Provider() != provider.External &&
+			registry.Provider() != provider.Consul && registry.Provider() != provider.Polling {
 			nonK8sRegistries = append(nonK8sRegistries, registry)
 		}
 	}
@@ -389,17 +410,30 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 	freeCh := make(chan struct{}, 1)
 
 	push := func(req *model.PushRequest, debouncedEvents int, startDebounce time.Time) {
+		debounceDuration := time.Since(startDebounce)
+		var queueDepth int
+		if opts.adaptive != nil {
+			queueDepth = opts.adaptive.QueueDepth()
+		}
+		t0 := time.Now()
 		pushFn(req)
 		updateSent.Add(int64(debouncedEvents))
 		debounceTime.Record(time.Since(startDebounce).Seconds())
+		if opts.adaptive != nil {
+			// pushFn only enqueues the proxies, the push is over once the queue is drained.
+			opts.adaptive.ObservePush(debouncedEvents, debounceDuration, t0, queueDepth, func(window time.Duration) {
+				log.Debugf("Adaptive debounce window is now %v", window)
+			})
+		}
 		freeCh <- struct{}{}
 	}
 
 	pushWorker := func() {
 		eventDelay := time.Since(startDebounce)
 		quietTime := time.Since(lastConfigUpdateTime)
+		quietPeriod := opts.quietPeriod()
 		// it has been too long or quiet enough
-		if eventDelay >= opts.debounceMax || quietTime >= opts.debounceAfter {
+		if eventDelay >= opts.debounceMax || quietTime >= quietPeriod {
 			if req != nil {
 				pushCounter++
 				if req.ConfigsUpdated == nil {
@@ -417,7 +451,7 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 				debouncedEvents = 0
 			}
 		} else {
-			timeChan = time.After(opts.debounceAfter - quietTime)
+			timeChan = time.After(quietPeriod - quietTime)
 		}
 	}
 
@@ -442,7 +476,7 @@ func debounce(ch chan *model.PushRequest, stopCh <-chan struct{}, opts debounceO
 
 			lastConfigUpdateTime = time.Now()
 			if debouncedEvents == 0 {
-				timeChan = time.After(opts.debounceAfter)
+				timeChan = time.After(opts.quietPeriod())
 				startDebounce = lastConfigUpdateTime
 			}
 			debouncedEvents++
@@ -0,0 +1,152 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
+)
+
+// Stages of the EDS generation that keep or drop an endpoint, in the order they are applied.
+const (
+	StageService         = "service"
+	StageClusterLocal    = "cluster-local"
+	StageDiscoverability = "discoverability"
+	StagePort            = "port"
+	StageSubset          = "subset"
+	StageHealth          = "health"
+	StageNetwork         = "network"
+	StageMTLS            = "mtls"
+	StageLocality        = "locality"
+)
+
+// EndpointDecision is a decision of the EDS generation that kept or dropped an endpoint.
+type EndpointDecision struct {
+	// Endpoint is the address and port of the endpoint, or only the address for the decisions on the cluster.
+	Endpoint string `json:"endpoint"`
+	Stage    string `json:"stage"`
+	Kept     bool   `json:"kept"`
+	Reason   string `json:"reason"`
+}
+
+// EndpointExplanation reports why the endpoints with an address are, or are not, in the
+// ClusterLoadAssignment of a cluster sent to a proxy.
+type EndpointExplanation struct {
+	Cluster string `json:"cluster"`
+	// Sent is true if an endpoint with the address is in the ClusterLoadAssignment.
+	Sent      bool               `json:"sent"`
+	Decisions []EndpointDecision `json:"decisions"`
+}
+
+// endpointTracer records the decisions made for the endpoints with an address while building a
+// ClusterLoadAssignment. A nil endpointTracer traces nothing.
+type endpointTracer struct {
+	address   string
+	decisions []EndpointDecision
+}
+
+func (t *endpointTracer) traces(ep *model.IstioEndpoint) bool {
+	return t != nil && ep != nil && ep.Address == t.address
+}
+
+func (t *endpointTracer) record(ep *model.IstioEndpoint, stage string, kept bool, reason string) {
+	t.decisions = append(t.decisions, EndpointDecision{
+		Endpoint: net.JoinHostPort(ep.Address, strconv.Itoa(int(ep.EndpointPort))),
+		Stage:    stage,
+		Kept:     kept,
+		Reason:   reason,
+	})
+}
+
+func healthReason(ep *model.IstioEndpoint) string {
+	switch ep.HealthStatus {
+	case model.UnHealthy:
+		return "the endpoint is not ready, and is sent as unhealthy since PILOT_SEND_UNHEALTHY_ENDPOINTS is enabled"
+	case model.Draining:
+		return "the endpoint is draining, and is sent as draining for the persistent session service"
+	default:
+		return "the endpoint is ready"
+	}
+}
+
+// ExplainEndpoint builds the ClusterLoadAssignment of the cluster for the proxy, and reports each decision
+// that kept or dropped the endpoints with the address. The second result is false if the address is not an
+// endpoint of the service of the cluster.
+func (s *DiscoveryServer) ExplainEndpoint(proxy *model.Proxy, push *model.PushContext, clusterName, address string) (EndpointExplanation, bool) {
+	out := EndpointExplanation{Cluster: clusterName, Decisions: []EndpointDecision{}}
+	dropped := func(reason string, args ...any) (EndpointExplanation, bool) {
+		out.Decisions = append(out.Decisions, EndpointDecision{
+			Endpoint: address,
+			Stage:    StageService,
+			Reason:   fmt.Sprintf(reason, args...),
+		})
+		return out, false
+	}
+
+	b := NewEndpointBuilder(clusterName, proxy, push)
+	if b.service == nil {
+		return dropped("service %s is not visible to the proxy, check the exportTo of the service and the egress hosts "+
+			"of the Sidecar of the proxy", b.hostname)
+	}
+	if b.service.Resolution == model.DNSLB || b.service.Resolution == model.DNSRoundRobinLB {
+		return dropped("service %s uses DNS resolution, its endpoints are resolved by the proxy instead of sent with EDS", b.hostname)
+	}
+	if _, f := b.service.Ports.GetByPort(b.port); !f {
+		return dropped("service %s has no port %d", b.hostname, b.port)
+	}
+	shards, f := s.Env.EndpointIndex.ShardsForService(string(b.hostname), b.service.Attributes.Namespace)
+	if !f {
+		return dropped("service %s has no endpoints", b.hostname)
+	}
+	found := false
+	shards.RLock()
+	for _, eps := range shards.Shards {
+		for _, ep := range eps {
+			if ep.Address == address {
+				found = true
+			}
+		}
+	}
+	shards.RUnlock()
+	if !found {
+		return dropped("%s is not an endpoint of service %s; endpoints of pods that are not ready are only "+
+			"discovered when PILOT_SEND_UNHEALTHY_ENDPOINTS is enabled", address, b.hostname)
+	}
+
+	b.tracer = &endpointTracer{address: address}
+	cla := s.generateEndpoints(b)
+	for _, llb := range cla.Endpoints {
+		for _, lbEp := range llb.LbEndpoints {
+			sa := lbEp.GetEndpoint().GetAddress().GetSocketAddress()
+			if sa.GetAddress() != address {
+				continue
+			}
+			out.Sent = true
+			b.tracer.decisions = append(b.tracer.decisions, EndpointDecision{
+				Endpoint: net.JoinHostPort(sa.GetAddress(), strconv.Itoa(int(sa.GetPortValue()))),
+				Stage:    StageLocality,
+				Kept:     true,
+				Reason: fmt.Sprintf("the endpoint is sent in locality %q with priority %d and load balancing weight %d",
+					util.LocalityToString(llb.Locality), llb.Priority, lbEp.GetLoadBalancingWeight().GetValue()),
+			})
+		}
+	}
+	out.Decisions = append(out.Decisions, b.tracer.decisions...)
+	return out, true
+}
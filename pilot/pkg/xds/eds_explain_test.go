@@ -0,0 +1,86 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds_test
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const explainConfig = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: explain
+  namespace: default
+spec:
+  hosts:
+  - explain.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+    labels:
+      version: v1
+  - address: 1.1.1.2
+    labels:
+      version: v2
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: explain
+  namespace: default
+spec:
+  host: explain.example.com
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+`
+
+func TestExplainEndpoint(t *testing.T) {
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: explainConfig})
+	proxy := s.SetupProxy(nil)
+	push := s.PushContext()
+	const cluster = "outbound|80|v1|explain.example.com"
+
+	e, found := s.Discovery.ExplainEndpoint(proxy, push, cluster, "1.1.1.1")
+	assert.Equal(t, found, true)
+	assert.Equal(t, e.Sent, true)
+	assert.Equal(t, e.Decisions[len(e.Decisions)-1].Stage, xds.StageLocality)
+
+	e, found = s.Discovery.ExplainEndpoint(proxy, push, cluster, "1.1.1.2")
+	assert.Equal(t, found, true)
+	assert.Equal(t, e.Sent, false)
+	assert.Equal(t, len(e.Decisions), 1)
+	assert.Equal(t, e.Decisions[0].Endpoint, "1.1.1.2:80")
+	assert.Equal(t, e.Decisions[0].Stage, xds.StageSubset)
+	assert.Equal(t, e.Decisions[0].Kept, false)
+
+	e, found = s.Discovery.ExplainEndpoint(proxy, push, cluster, "1.1.1.3")
+	assert.Equal(t, found, false)
+	assert.Equal(t, e.Sent, false)
+	assert.Equal(t, e.Decisions[0].Stage, xds.StageService)
+
+	e, found = s.Discovery.ExplainEndpoint(proxy, push, "outbound|80||unknown.example.com", "1.1.1.1")
+	assert.Equal(t, found, false)
+	assert.Equal(t, e.Decisions[0].Stage, xds.StageService)
+}
@@ -17,6 +17,7 @@ package xds
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"fmt"
 	"net"
 	"sort"
 	"strconv"
@@ -65,6 +66,9 @@ type EndpointBuilder struct {
 	proxy      *model.Proxy
 
 	mtlsChecker *mtlsChecker
+
+	// tracer records the decisions made for an endpoint, when explaining the endpoints of the cluster.
+	tracer *endpointTracer
 }
 
 func NewEndpointBuilder(clusterName string, proxy *model.Proxy, push *model.PushContext) EndpointBuilder {
@@ -257,18 +261,36 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 		// If the downstream service is configured as cluster-local, only include endpoints that
 		// reside in the same cluster.
 		if isClusterLocal && (shardKey.Cluster != b.clusterID) {
+			for _, ep := range endpoints {
+				if b.tracer.traces(ep) {
+					b.tracer.record(ep, StageClusterLocal, false, fmt.Sprintf(
+						"the service is cluster-local, and the endpoint is in cluster %s while the proxy is in cluster %s", shardKey.Cluster, b.clusterID))
+				}
+			}
 			continue
 		}
 		for _, ep := range endpoints {
 			// TODO(nmittler): Consider merging discoverability policy with cluster-local
 			if !ep.IsDiscoverableFromProxy(b.proxy) {
+				if b.tracer.traces(ep) {
+					b.tracer.record(ep, StageDiscoverability, false, fmt.Sprintf(
+						"the endpoint is not discoverable from the proxy with the %s discoverability policy", ep.DiscoverabilityPolicy))
+				}
 				continue
 			}
 			if svcPort.Name != ep.ServicePortName {
+				if b.tracer.traces(ep) {
+					b.tracer.record(ep, StagePort, false, fmt.Sprintf(
+						"the endpoint is for service port %q, not port %q of the cluster", ep.ServicePortName, svcPort.Name))
+				}
 				continue
 			}
 			// Port labels
 			if !subsetLabels.SubsetOf(ep.Labels) {
+				if b.tracer.traces(ep) {
+					b.tracer.record(ep, StageSubset, false, fmt.Sprintf(
+						"the labels of the endpoint do not match the labels %s of subset %q", subsetLabels, b.subsetName))
+				}
 				continue
 			}
 			// Draining endpoints are only sent to 'persistent session' clusters.
@@ -277,9 +299,17 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 			if draining {
 				persistentSession := b.service.Attributes.Labels[features.PersistentSessionLabel] != ""
 				if !persistentSession {
+					if b.tracer.traces(ep) {
+						b.tracer.record(ep, StageHealth, false, fmt.Sprintf(
+							"the endpoint is draining, and draining endpoints are only sent for services with the %s label",
+							features.PersistentSessionLabel))
+					}
 					continue
 				}
 			}
+			if b.tracer.traces(ep) {
+				b.tracer.record(ep, StageHealth, true, healthReason(ep))
+			}
 
 			locLbEps, found := localityEpMap[ep.Locality.Label]
 			if !found {
@@ -15,7 +15,11 @@
 package xds
 
 import (
+	"fmt"
 	"math"
+	"net"
+	"strconv"
+	"strings"
 
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
 	"google.golang.org/protobuf/proto"
@@ -70,6 +74,10 @@ func (b *EndpointBuilder) EndpointsByNetworkFilter(endpoints []*LocalityEndpoint
 
 			// If the proxy can't view the network for this endpoint, exclude it entirely.
 			if !b.proxyView.IsVisible(istioEndpoint) {
+				if b.tracer.traces(istioEndpoint) {
+					b.tracer.record(istioEndpoint, StageNetwork, false, fmt.Sprintf(
+						"network %q of the endpoint is not visible to the proxy", istioEndpoint.Network))
+				}
 				continue
 			}
 
@@ -92,6 +100,13 @@ func (b *EndpointBuilder) EndpointsByNetworkFilter(endpoints []*LocalityEndpoint
 			// the endpoint is either on the local network or on a remote network that can be reached
 			// directly from the local network.
 			if b.proxy.InNetwork(epNetwork) || len(gateways) == 0 {
+				if b.tracer.traces(istioEndpoint) {
+					reason := "the endpoint is on the network of the proxy"
+					if !b.proxy.InNetwork(epNetwork) {
+						reason = fmt.Sprintf("network %q of the endpoint has no gateway, so the endpoint is reached directly", epNetwork)
+					}
+					b.tracer.record(istioEndpoint, StageNetwork, true, reason)
+				}
 				// The endpoint is directly reachable - just add it.
 				lbEndpoints.append(ep.istioEndpoints[i], lbEp)
 				continue
@@ -100,9 +115,21 @@ func (b *EndpointBuilder) EndpointsByNetworkFilter(endpoints []*LocalityEndpoint
 			// Cross-network traffic relies on mTLS to be enabled for SNI routing
 			// TODO BTS may allow us to work around this
 			if b.mtlsChecker.isMtlsDisabled(lbEp) {
+				if b.tracer.traces(istioEndpoint) {
+					b.tracer.record(istioEndpoint, StageNetwork, false, fmt.Sprintf(
+						"the endpoint is on network %q, and is not reached through its gateways since mTLS is disabled for it", epNetwork))
+				}
 				continue
 			}
 
+			if b.tracer.traces(istioEndpoint) {
+				addrs := make([]string, 0, len(gateways))
+				for _, gw := range gateways {
+					addrs = append(addrs, net.JoinHostPort(gw.Addr, strconv.Itoa(int(gw.Port))))
+				}
+				b.tracer.record(istioEndpoint, StageNetwork, false, fmt.Sprintf(
+					"the endpoint is on network %q, and is replaced by the gateways %s of the network", epNetwork, strings.Join(addrs, ", ")))
+			}
 			// Apply the weight for this endpoint to the network gateways.
 			splitWeightAmongGateways(weight, gateways, gatewayWeights)
 		}
@@ -218,6 +245,10 @@ func (b *EndpointBuilder) EndpointsWithMTLSFilter(endpoints []*LocalityEndpoints
 
 		for i, lbEp := range ep.llbEndpoints.LbEndpoints {
 			if b.mtlsChecker.isMtlsDisabled(lbEp) {
+				if b.tracer.traces(ep.istioEndpoints[i]) {
+					b.tracer.record(ep.istioEndpoints[i], StageMTLS, false,
+						"mTLS is disabled for the endpoint, and the cluster only accepts mTLS endpoints for AUTO_PASSTHROUGH gateways")
+				}
 				// no mTLS, skip it
 				continue
 			}
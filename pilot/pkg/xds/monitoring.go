@@ -31,6 +31,10 @@ var (
 	typeTag    = monitoring.MustCreateLabel("type")
 	versionTag = monitoring.MustCreateLabel("version")
 
+	// classTag and affectedTag describe the class of a proxy in the push queue
+	classTag    = monitoring.MustCreateLabel("class")
+	affectedTag = monitoring.MustCreateLabel("affected")
+
 	// pilot_total_xds_rejects should be used instead. This is for backwards compatibility
 	cdsReject = monitoring.NewGauge(
 		"pilot_xds_cds_reject",
@@ -134,6 +138,18 @@ var (
 		[]float64{.1, .5, 1, 3, 5, 10, 20, 30},
 	)
 
+	pushQueueWaitTime = monitoring.NewDistribution(
+		"pilot_push_queue_wait_time",
+		"Time in seconds a proxy waits in the push queue, labeled by proxy type and whether the push likely affects it.",
+		[]float64{.1, .5, 1, 3, 5, 10, 20, 30},
+		monitoring.WithLabels(classTag, affectedTag),
+	)
+
+	debounceWindow = monitoring.NewGauge(
+		"pilot_debounce_window",
+		"Current quiet period in seconds used for debouncing config updates, when adaptive debounce is enabled.",
+	)
+
 	pushTriggers = monitoring.NewSum(
 		"pilot_push_triggers",
 		"Total number of times a push was triggered, labeled by reason for the push.",
@@ -286,6 +302,8 @@ func init() {
 		pushTime,
 		proxiesConvergeDelay,
 		proxiesQueueTime,
+		pushQueueWaitTime,
+		debounceWindow,
 		pushContextErrors,
 		totalXDSInternalErrors,
 		inboundUpdates,
@@ -15,11 +15,20 @@
 package xds
 
 import (
+	"sort"
+	"strconv"
 	"sync"
+	"time"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/schema/kind"
 )
 
+// PushQueue holds the connections waiting for a push. Rather than a single FIFO, connections are grouped into
+// classes by proxy type, which are dequeued in proportion to their weights, so a burst of sidecar pushes does
+// not delay gateways. Within a class, proxies likely affected by the push are dequeued first, and namespaces
+// take turns so that a single large namespace cannot starve the others.
 type PushQueue struct {
 	cond *sync.Cond
 
@@ -27,23 +36,203 @@ type PushQueue struct {
 	// the PushRequest will be merged.
 	pending map[*Connection]*model.PushRequest
 
-	// queue maintains ordering of the queue
-	queue []*Connection
+	// classes maintains ordering of the queue, ordered by decreasing weight.
+	classes     []*pushClass
+	classByType map[model.NodeType]*pushClass
+	// queued is the number of connections in the queue, across all classes.
+	queued int
 
 	// processing stores all connections that have been Dequeue(), but not MarkDone().
 	// The value stored will be initially be nil, but may be populated if the connection is Enqueue().
 	// If model.PushRequest is not nil, it will be Enqueued again once MarkDone has been called.
 	processing map[*Connection]*model.PushRequest
 
+	// drained stores the channels to close once no connection is queued or being pushed.
+	drained []chan struct{}
+
 	shuttingDown bool
 }
 
 func NewPushQueue() *PushQueue {
+	weights := make(map[model.NodeType]int, len(features.PushQueueWeights))
+	for nodeType, weight := range features.PushQueueWeights {
+		weights[model.NodeType(nodeType)] = weight
+	}
+	return newPushQueue(weights)
+}
+
+// newPushQueue creates a queue with the given weights per proxy type. Unlisted types have a weight of 1.
+func newPushQueue(weights map[model.NodeType]int) *PushQueue {
+	classes := make([]*pushClass, 0, len(model.NodeTypes))
+	classByType := make(map[model.NodeType]*pushClass, len(model.NodeTypes))
+	for _, nodeType := range model.NodeTypes {
+		weight, f := weights[nodeType]
+		if !f || weight < 1 {
+			weight = 1
+		}
+		class := &pushClass{
+			nodeType: nodeType,
+			weight:   weight,
+			affected: newNamespaceQueue(),
+			other:    newNamespaceQueue(),
+		}
+		classes = append(classes, class)
+		classByType[nodeType] = class
+	}
+	// On ties, gateways go first.
+	sort.SliceStable(classes, func(i, j int) bool {
+		if classes[i].weight == classes[j].weight {
+			return classes[i].nodeType == model.Router && classes[j].nodeType != model.Router
+		}
+		return classes[i].weight > classes[j].weight
+	})
 	return &PushQueue{
-		pending:    make(map[*Connection]*model.PushRequest),
-		processing: make(map[*Connection]*model.PushRequest),
-		cond:       sync.NewCond(&sync.Mutex{}),
+		pending:     make(map[*Connection]*model.PushRequest),
+		processing:  make(map[*Connection]*model.PushRequest),
+		classes:     classes,
+		classByType: classByType,
+		cond:        sync.NewCond(&sync.Mutex{}),
+	}
+}
+
+// pushClass holds the queued connections of a proxy type.
+type pushClass struct {
+	nodeType model.NodeType
+	weight   int
+	// current is the smooth weighted round-robin state of the class.
+	current int
+	// affected holds proxies likely affected by their push, which are dequeued before the others.
+	affected *namespaceQueue
+	other    *namespaceQueue
+}
+
+func (c *pushClass) len() int {
+	return c.affected.len() + c.other.len()
+}
+
+// queuedConnection is a connection waiting in the queue.
+type queuedConnection struct {
+	con      *Connection
+	enqueued time.Time
+}
+
+// namespaceQueue is a FIFO per namespace, with namespaces dequeued in turn.
+type namespaceQueue struct {
+	// order holds the namespaces with queued connections, in the order they are next dequeued.
+	order  []string
+	queues map[string][]queuedConnection
+	size   int
+}
+
+func newNamespaceQueue() *namespaceQueue {
+	return &namespaceQueue{queues: map[string][]queuedConnection{}}
+}
+
+func (q *namespaceQueue) len() int {
+	return q.size
+}
+
+func (q *namespaceQueue) push(namespace string, qc queuedConnection) {
+	if len(q.queues[namespace]) == 0 {
+		q.order = append(q.order, namespace)
+	}
+	q.queues[namespace] = append(q.queues[namespace], qc)
+	q.size++
+}
+
+func (q *namespaceQueue) pop() queuedConnection {
+	namespace := q.order[0]
+	q.order = q.order[1:]
+	queue := q.queues[namespace]
+	qc := queue[0]
+	// The underlying array will still exist, despite the slice changing, so the object may not GC without this
+	// See https://github.com/grpc/grpc-go/issues/4758
+	queue[0] = queuedConnection{}
+	queue = queue[1:]
+	if len(queue) == 0 {
+		delete(q.queues, namespace)
+	} else {
+		q.queues[namespace] = queue
+		// Give the other namespaces a turn before dequeuing from this one again.
+		q.order = append(q.order, namespace)
+	}
+	q.size--
+	return qc
+}
+
+// classFor returns the class of the proxy. Unknown proxy types are queued as sidecars.
+func (p *PushQueue) classFor(con *Connection) *pushClass {
+	if con.proxy != nil {
+		if c, f := p.classByType[con.proxy.Type]; f {
+			return c
+		}
+	}
+	return p.classByType[model.SidecarProxy]
+}
+
+// likelyAffected is a cheap estimate of whether the push affects the proxy, only used to order the queue.
+// The sidecar scope cannot be used here, as it is concurrently updated by the connection. Config in the root
+// namespace is mesh-wide, and services are consumed across namespaces, so they are considered to affect all
+// proxies.
+func likelyAffected(proxy *model.Proxy, request *model.PushRequest) bool {
+	if proxy == nil {
+		return false
+	}
+	if len(request.ConfigsUpdated) == 0 {
+		return true
+	}
+	rootNamespace := ""
+	if request.Push != nil && request.Push.Mesh != nil {
+		rootNamespace = request.Push.Mesh.RootNamespace
 	}
+	for cfg := range request.ConfigsUpdated {
+		switch cfg.Kind {
+		case kind.ServiceEntry, kind.Service:
+			return true
+		}
+		if cfg.Namespace == proxy.ConfigNamespace || (rootNamespace != "" && cfg.Namespace == rootNamespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// push adds the connection to the queue. The class is determined once, when the connection is added; requests
+// merged while it is pending do not move it.
+func (p *PushQueue) push(con *Connection, request *model.PushRequest) {
+	p.pending[con] = request
+	class := p.classFor(con)
+	qc := queuedConnection{con: con, enqueued: time.Now()}
+	namespace := ""
+	if con.proxy != nil {
+		namespace = con.proxy.ConfigNamespace
+	}
+	if likelyAffected(con.proxy, request) {
+		class.affected.push(namespace, qc)
+	} else {
+		class.other.push(namespace, qc)
+	}
+	p.queued++
+	// Signal waiters on Dequeue that a new item is available
+	p.cond.Signal()
+}
+
+// next picks the class to dequeue from, using smooth weighted round-robin across the non-empty classes.
+func (p *PushQueue) next() *pushClass {
+	var selected *pushClass
+	total := 0
+	for _, c := range p.classes {
+		if c.len() == 0 {
+			continue
+		}
+		c.current += c.weight
+		total += c.weight
+		if selected == nil || c.current > selected.current {
+			selected = c
+		}
+	}
+	selected.current -= total
+	return selected
 }
 
 // Enqueue will mark a proxy as pending a push. If it is already pending, pushInfo will be merged.
@@ -67,10 +256,7 @@ func (p *PushQueue) Enqueue(con *Connection, pushRequest *model.PushRequest) {
 		return
 	}
 
-	p.pending[con] = pushRequest
-	p.queue = append(p.queue, con)
-	// Signal waiters on Dequeue that a new item is available
-	p.cond.Signal()
+	p.push(con, pushRequest)
 }
 
 // Remove a proxy from the queue. If there are no proxies ready to be removed, this will block
@@ -79,20 +265,31 @@ func (p *PushQueue) Dequeue() (con *Connection, request *model.PushRequest, shut
 	defer p.cond.L.Unlock()
 
 	// Block until there is one to remove. Enqueue will signal when one is added.
-	for len(p.queue) == 0 && !p.shuttingDown {
+	for p.queued == 0 && !p.shuttingDown {
 		p.cond.Wait()
 	}
 
-	if len(p.queue) == 0 {
+	if p.queued == 0 {
 		// We must be shutting down.
 		return nil, nil, true
 	}
 
-	con = p.queue[0]
-	// The underlying array will still exist, despite the slice changing, so the object may not GC without this
-	// See https://github.com/grpc/grpc-go/issues/4758
-	p.queue[0] = nil
-	p.queue = p.queue[1:]
+	class := p.next()
+	affected := class.affected.len() > 0
+	var qc queuedConnection
+	if affected {
+		qc = class.affected.pop()
+	} else {
+		qc = class.other.pop()
+	}
+	p.queued--
+	if class.len() == 0 {
+		// Classes only compete while they have pending connections.
+		class.current = 0
+	}
+	con = qc.con
+	pushQueueWaitTime.With(classTag.Value(string(class.nodeType)), affectedTag.Value(strconv.FormatBool(affected))).
+		Record(time.Since(qc.enqueued).Seconds())
 
 	request = p.pending[con]
 	delete(p.pending, con)
@@ -112,17 +309,39 @@ func (p *PushQueue) MarkDone(con *Connection) {
 	// If the info is present, that means Enqueue was called while connection was not yet marked done.
 	// This means we need to add it back to the queue.
 	if request != nil {
-		p.pending[con] = request
-		p.queue = append(p.queue, con)
-		p.cond.Signal()
+		p.push(con, request)
+	}
+	if p.queued == 0 && len(p.processing) == 0 {
+		p.notifyDrained()
+	}
+}
+
+// Drained returns a channel closed once no connection is queued or being pushed, i.e. once all the
+// pushes enqueued so far have been sent.
+func (p *PushQueue) Drained() <-chan struct{} {
+	p.cond.L.Lock()
+	defer p.cond.L.Unlock()
+	ch := make(chan struct{})
+	if p.shuttingDown || (p.queued == 0 && len(p.processing) == 0) {
+		close(ch)
+		return ch
+	}
+	p.drained = append(p.drained, ch)
+	return ch
+}
+
+func (p *PushQueue) notifyDrained() {
+	for _, ch := range p.drained {
+		close(ch)
 	}
+	p.drained = nil
 }
 
 // Get number of pending proxies
 func (p *PushQueue) Pending() int {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
-	return len(p.queue)
+	return p.queued
 }
 
 // ShutDown will cause queue to ignore all new items added to it. As soon as the
@@ -132,5 +351,6 @@ func (p *PushQueue) ShutDown() {
 	p.cond.L.Lock()
 	defer p.cond.L.Unlock()
 	p.shuttingDown = true
+	p.notifyDrained()
 	p.cond.Broadcast()
 }
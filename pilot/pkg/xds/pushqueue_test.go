@@ -23,8 +23,10 @@ import (
 	"testing"
 	"time"
 
+	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/test/util/assert"
 	"istio.io/istio/pkg/util/sets"
 	"istio.io/istio/tests/util/leak"
 )
@@ -130,6 +132,41 @@ func TestProxyQueue(t *testing.T) {
 		ExpectTimeout(t, p)
 	})
 
+	t.Run("drained", func(t *testing.T) {
+		t.Parallel()
+		p := NewPushQueue()
+		defer p.ShutDown()
+
+		expectDrained := func(ch <-chan struct{}, want bool) {
+			t.Helper()
+			select {
+			case <-ch:
+				if !want {
+					t.Fatalf("expected the queue not to be drained")
+				}
+			default:
+				if want {
+					t.Fatalf("expected the queue to be drained")
+				}
+			}
+		}
+		expectDrained(p.Drained(), true)
+
+		p.Enqueue(proxies[0], &model.PushRequest{})
+		drained := p.Drained()
+		expectDrained(drained, false)
+		ExpectDequeue(t, p, proxies[0])
+		// The connection is still being pushed
+		expectDrained(drained, false)
+		p.Enqueue(proxies[0], &model.PushRequest{})
+		p.MarkDone(proxies[0])
+		// The connection was enqueued again during the push
+		expectDrained(drained, false)
+		ExpectDequeue(t, p, proxies[0])
+		p.MarkDone(proxies[0])
+		expectDrained(drained, true)
+	})
+
 	t.Run("add and remove and add and markdone", func(t *testing.T) {
 		t.Parallel()
 		p := NewPushQueue()
@@ -382,3 +419,79 @@ func TestPushQueueLeak(t *testing.T) {
 	ds.Discovery.startPush(&model.PushRequest{})
 	p.Cleanup()
 }
+
+func TestPushQueuePriority(t *testing.T) {
+	proxy := func(name string, nodeType model.NodeType, namespace string) *Connection {
+		return &Connection{conID: name, proxy: &model.Proxy{Type: nodeType, ConfigNamespace: namespace}}
+	}
+	dequeueAll := func(p *PushQueue) []string {
+		var got []string
+		for p.Pending() > 0 {
+			con, _, _ := p.Dequeue()
+			got = append(got, con.conID)
+		}
+		return got
+	}
+	update := func(namespace string) *model.PushRequest {
+		return &model.PushRequest{ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.VirtualService, Name: "vs", Namespace: namespace})}
+	}
+
+	t.Run("gateways first", func(t *testing.T) {
+		p := newPushQueue(map[model.NodeType]int{model.Router: 2, model.SidecarProxy: 1})
+		defer p.ShutDown()
+		for i := 0; i < 3; i++ {
+			p.Enqueue(proxy(fmt.Sprintf("sidecar-%d", i), model.SidecarProxy, "ns"), &model.PushRequest{})
+		}
+		for i := 0; i < 3; i++ {
+			p.Enqueue(proxy(fmt.Sprintf("gateway-%d", i), model.Router, "ns"), &model.PushRequest{})
+		}
+		assert.Equal(t, dequeueAll(p), []string{"gateway-0", "sidecar-0", "gateway-1", "gateway-2", "sidecar-1", "sidecar-2"})
+	})
+
+	t.Run("equal weights", func(t *testing.T) {
+		p := newPushQueue(nil)
+		defer p.ShutDown()
+		p.Enqueue(proxy("sidecar-0", model.SidecarProxy, "ns"), &model.PushRequest{})
+		p.Enqueue(proxy("sidecar-1", model.SidecarProxy, "ns"), &model.PushRequest{})
+		p.Enqueue(proxy("gateway-0", model.Router, "ns"), &model.PushRequest{})
+		p.Enqueue(proxy("gateway-1", model.Router, "ns"), &model.PushRequest{})
+		assert.Equal(t, dequeueAll(p), []string{"gateway-0", "sidecar-0", "gateway-1", "sidecar-1"})
+	})
+
+	t.Run("affected proxies first", func(t *testing.T) {
+		p := newPushQueue(nil)
+		defer p.ShutDown()
+		p.Enqueue(proxy("a", model.SidecarProxy, "a"), update("b"))
+		p.Enqueue(proxy("b", model.SidecarProxy, "b"), update("b"))
+		p.Enqueue(proxy("c", model.SidecarProxy, "c"), update("b"))
+		assert.Equal(t, dequeueAll(p), []string{"b", "a", "c"})
+	})
+
+	t.Run("mesh-wide updates affect all proxies", func(t *testing.T) {
+		push := model.NewPushContext()
+		push.Mesh = &meshconfig.MeshConfig{RootNamespace: "istio-system"}
+		rootUpdate := &model.PushRequest{
+			Push:           push,
+			ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.EnvoyFilter, Name: "ef", Namespace: "istio-system"}),
+		}
+		serviceUpdate := &model.PushRequest{
+			ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.ServiceEntry, Name: "svc.b.svc.cluster.local", Namespace: "b"}),
+		}
+		p := newPushQueue(nil)
+		defer p.ShutDown()
+		p.Enqueue(proxy("a", model.SidecarProxy, "a"), update("b"))
+		p.Enqueue(proxy("c", model.SidecarProxy, "c"), rootUpdate)
+		p.Enqueue(proxy("d", model.SidecarProxy, "d"), serviceUpdate)
+		assert.Equal(t, dequeueAll(p), []string{"c", "d", "a"})
+	})
+
+	t.Run("namespaces take turns", func(t *testing.T) {
+		p := newPushQueue(nil)
+		defer p.ShutDown()
+		for i := 0; i < 3; i++ {
+			p.Enqueue(proxy(fmt.Sprintf("big-%d", i), model.SidecarProxy, "big"), &model.PushRequest{})
+		}
+		p.Enqueue(proxy("small-0", model.SidecarProxy, "small"), &model.PushRequest{})
+		assert.Equal(t, dequeueAll(p), []string{"big-0", "small-0", "big-1", "big-2"})
+	})
+}
@@ -23,7 +23,7 @@ type RdsGenerator struct {
 	Server *DiscoveryServer
 }
 
-var _ model.XdsResourceGenerator = &RdsGenerator{}
+var _ model.XdsDeltaResourceGenerator = &RdsGenerator{}
 
 // Map of all configs that do not impact RDS
 var skippedRdsConfigs = map[kind.Kind]struct{}{
@@ -65,3 +65,15 @@ func (c RdsGenerator) Generate(proxy *model.Proxy, w *model.WatchedResource, req
 	resources, logDetails := c.Server.ConfigGenerator.BuildHTTPRoutes(proxy, req, w.ResourceNames)
 	return resources, logDetails, nil
 }
+
+// GenerateDeltas for RDS builds deltas when only services change, regenerating only the watched routes
+// serving the ports of the changed services.
+func (c RdsGenerator) GenerateDeltas(proxy *model.Proxy, req *model.PushRequest,
+	w *model.WatchedResource,
+) (model.Resources, model.DeletedResources, model.XdsLogDetails, bool, error) {
+	if !rdsNeedsPush(req) {
+		return nil, nil, model.DefaultXdsLogDetails, false, nil
+	}
+	resources, removed, logDetails, usedDelta := c.Server.ConfigGenerator.BuildDeltaHTTPRoutes(proxy, req, w)
+	return resources, removed, logDetails, usedDelta, nil
+}
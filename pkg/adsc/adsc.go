@@ -122,6 +122,15 @@ type Config struct {
 	// BackoffPolicy determines the reconnect policy. Based on MCP client.
 	BackoffPolicy backoff.BackOff
 
+	// ConfigFilter restricts the MCP config added to the Store. Use FilteredConfigInitialRequests to also restrict
+	// the config requested from the server.
+	ConfigFilter *ConfigFilter
+
+	// Delta uses the incremental (delta) xDS protocol. Only MCP and mesh config types are supported: the server
+	// only sends the resources that changed, and on reconnect the versions of the resources already received are
+	// sent so that the Store is not rebuilt from scratch.
+	Delta bool
+
 	// ResponseHandler will be called on each DiscoveryResponse.
 	// TODO: mirror Generator, allow adding handler per type
 	ResponseHandler ResponseHandler
@@ -144,6 +153,8 @@ type ADSC struct {
 	// Stream is the GRPC connection stream, allowing direct GRPC send operations.
 	// Set after Dial is called.
 	stream discovery.AggregatedDiscoveryService_StreamAggregatedResourcesClient
+	// deltaStream is the delta xDS stream, used instead of stream if Config.Delta is set.
+	deltaStream discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesClient
 	// xds client used to create a stream
 	client discovery.AggregatedDiscoveryServiceClient
 	conn   *grpc.ClientConn
@@ -218,6 +229,12 @@ type ADSC struct {
 
 	sync     map[string]time.Time
 	Locality *core.Locality
+
+	// filter restricts the MCP config added to the Store.
+	filter *configFilter
+
+	// knownVersions has the versions of the resources received in delta mode, by type URL and name.
+	knownVersions map[string]map[string]string
 }
 
 type ResponseHandler interface {
@@ -266,6 +283,10 @@ func New(discoveryAddr string, opts *Config) (*ADSC, error) {
 	if opts.BackoffPolicy == nil {
 		opts.BackoffPolicy = backoff.NewExponentialBackOff(backoff.DefaultOption())
 	}
+	filter, err := newConfigFilter(opts.ConfigFilter)
+	if err != nil {
+		return nil, err
+	}
 	adsc := &ADSC{
 		Updates:     make(chan string, 100),
 		XDSUpdates:  make(chan *discovery.DiscoveryResponse, 100),
@@ -276,6 +297,9 @@ func New(discoveryAddr string, opts *Config) (*ADSC, error) {
 		cfg:         opts,
 		sync:        map[string]time.Time{},
 		errChan:     make(chan error, 10),
+		filter:      filter,
+
+		knownVersions: map[string]map[string]string{},
 	}
 
 	if opts.Namespace == "" {
@@ -421,6 +445,9 @@ func (a *ADSC) Close() {
 // And then it will run a go routine receiving and handling xds response.
 // Note: it is non blocking
 func (a *ADSC) Run() error {
+	if a.cfg.Delta {
+		return a.runDelta()
+	}
 	var err error
 	a.client = discovery.NewAggregatedDiscoveryServiceClient(a.conn)
 	a.stream, err = a.client.StreamAggregatedResources(context.Background())
@@ -486,27 +513,32 @@ func (a *ADSC) reconnect() {
 	}
 }
 
+// handleStreamError is called when the stream is closed, and schedules a reconnect if enabled.
+func (a *ADSC) handleStreamError(err error) {
+	a.RecvWg.Done()
+	adscLog.Infof("Connection closed for node %v with err: %v", a.nodeID, err)
+	select {
+	case a.errChan <- err:
+	default:
+	}
+	// if 'reconnect' enabled - schedule a new Run
+	if a.cfg.BackoffPolicy != nil {
+		time.AfterFunc(a.cfg.BackoffPolicy.NextBackOff(), a.reconnect)
+	} else {
+		a.Close()
+		a.WaitClear()
+		a.Updates <- ""
+		a.XDSUpdates <- nil
+		close(a.errChan)
+	}
+}
+
 func (a *ADSC) handleRecv() {
 	for {
 		var err error
 		msg, err := a.stream.Recv()
 		if err != nil {
-			a.RecvWg.Done()
-			adscLog.Infof("Connection closed for node %v with err: %v", a.nodeID, err)
-			select {
-			case a.errChan <- err:
-			default:
-			}
-			// if 'reconnect' enabled - schedule a new Run
-			if a.cfg.BackoffPolicy != nil {
-				time.AfterFunc(a.cfg.BackoffPolicy.NextBackOff(), a.reconnect)
-			} else {
-				a.Close()
-				a.WaitClear()
-				a.Updates <- ""
-				a.XDSUpdates <- nil
-				close(a.errChan)
-			}
+			a.handleStreamError(err)
 			return
 		}
 
@@ -1232,7 +1264,7 @@ func (a *ADSC) GetEndpoints() map[string]*endpoint.ClusterLoadAssignment {
 
 func (a *ADSC) handleMCP(groupVersionKind config.GroupVersionKind, resources []*anypb.Any) {
 	// Generic - fill up the store
-	if a.Store == nil {
+	if a.Store == nil || !a.filter.allowKind(groupVersionKind) {
 		return
 	}
 
@@ -1244,67 +1276,82 @@ func (a *ADSC) handleMCP(groupVersionKind config.GroupVersionKind, resources []*
 
 	received := make(map[string]*config.Config)
 	for _, rsc := range resources {
-		m := &mcp.Resource{}
-		err := rsc.UnmarshalTo(m)
-		if err != nil {
-			adscLog.Warnf("Error unmarshalling received MCP config %v", err)
-			continue
-		}
-		newCfg, err := a.mcpToPilot(m)
-		if err != nil {
-			adscLog.Warn("Invalid data ", err, " ", string(rsc.Value))
-			continue
-		}
+		newCfg := a.applyMCP(groupVersionKind, rsc)
 		if newCfg == nil {
 			continue
 		}
 		received[newCfg.Namespace+"/"+newCfg.Name] = newCfg
+	}
 
-		newCfg.GroupVersionKind = groupVersionKind
-		oldCfg := a.Store.Get(newCfg.GroupVersionKind, newCfg.Name, newCfg.Namespace)
+	// remove deleted resources from cache
+	for _, config := range existingConfigs {
+		if _, ok := received[config.Namespace+"/"+config.Name]; !ok {
+			a.deleteMCP(config.GroupVersionKind, config.Namespace, config.Name)
+		}
+	}
+}
 
-		if oldCfg == nil {
-			if _, err = a.Store.Create(*newCfg); err != nil {
-				adscLog.Warnf("Error adding a new resource to the store %v", err)
-				continue
-			}
-		} else if oldCfg.ResourceVersion != newCfg.ResourceVersion || newCfg.ResourceVersion == "" {
-			// update the store only when resource version differs or unset.
-			newCfg.Annotations[mem.ResourceVersion] = newCfg.ResourceVersion
-			newCfg.ResourceVersion = oldCfg.ResourceVersion
-			if _, err = a.Store.Update(*newCfg); err != nil {
-				adscLog.Warnf("Error updating an existing resource in the store %v", err)
-				continue
-			}
+// applyMCP adds or updates a received MCP resource in the store. It returns the received config, or nil if the
+// resource is invalid or filtered out.
+func (a *ADSC) applyMCP(groupVersionKind config.GroupVersionKind, rsc *anypb.Any) *config.Config {
+	m := &mcp.Resource{}
+	err := rsc.UnmarshalTo(m)
+	if err != nil {
+		adscLog.Warnf("Error unmarshalling received MCP config %v", err)
+		return nil
+	}
+	newCfg, err := a.mcpToPilot(m)
+	if err != nil {
+		adscLog.Warn("Invalid data ", err, " ", string(rsc.Value))
+		return nil
+	}
+	if newCfg == nil || !a.filter.allowNamespace(newCfg.Namespace) {
+		return nil
+	}
+
+	newCfg.GroupVersionKind = groupVersionKind
+	oldCfg := a.Store.Get(newCfg.GroupVersionKind, newCfg.Name, newCfg.Namespace)
+
+	if oldCfg == nil {
+		if _, err = a.Store.Create(*newCfg); err != nil {
+			adscLog.Warnf("Error adding a new resource to the store %v", err)
+			return nil
 		}
-		if a.LocalCacheDir != "" {
-			strResponse, err := json.MarshalIndent(newCfg, "  ", "  ")
-			if err != nil {
-				adscLog.Warnf("Error marshaling received MCP config %v", err)
-				continue
-			}
-			err = os.WriteFile(a.LocalCacheDir+"_res."+
-				newCfg.GroupVersionKind.Kind+"."+newCfg.Namespace+"."+newCfg.Name+".json", strResponse, 0o644)
-			if err != nil {
-				adscLog.Warnf("Error writing received MCP config to local file %v", err)
-			}
+	} else if oldCfg.ResourceVersion != newCfg.ResourceVersion || newCfg.ResourceVersion == "" {
+		// update the store only when resource version differs or unset.
+		newCfg.Annotations[mem.ResourceVersion] = newCfg.ResourceVersion
+		newCfg.ResourceVersion = oldCfg.ResourceVersion
+		if _, err = a.Store.Update(*newCfg); err != nil {
+			adscLog.Warnf("Error updating an existing resource in the store %v", err)
+			return nil
 		}
 	}
+	if a.LocalCacheDir != "" {
+		strResponse, err := json.MarshalIndent(newCfg, "  ", "  ")
+		if err != nil {
+			adscLog.Warnf("Error marshaling received MCP config %v", err)
+			return nil
+		}
+		err = os.WriteFile(a.LocalCacheDir+"_res."+
+			newCfg.GroupVersionKind.Kind+"."+newCfg.Namespace+"."+newCfg.Name+".json", strResponse, 0o644)
+		if err != nil {
+			adscLog.Warnf("Error writing received MCP config to local file %v", err)
+		}
+	}
+	return newCfg
+}
 
-	// remove deleted resources from cache
-	for _, config := range existingConfigs {
-		if _, ok := received[config.Namespace+"/"+config.Name]; !ok {
-			if err := a.Store.Delete(config.GroupVersionKind, config.Name, config.Namespace, nil); err != nil {
-				adscLog.Warnf("Error deleting an outdated resource from the store %v", err)
-				continue
-			}
-			if a.LocalCacheDir != "" {
-				err = os.Remove(a.LocalCacheDir + "_res." +
-					config.GroupVersionKind.Kind + "." + config.Namespace + "." + config.Name + ".json")
-				if err != nil {
-					adscLog.Warnf("Error deleting received MCP config to local file %v", err)
-				}
-			}
+// deleteMCP removes a config from the store, and from the local cache.
+func (a *ADSC) deleteMCP(groupVersionKind config.GroupVersionKind, namespace, name string) {
+	if err := a.Store.Delete(groupVersionKind, name, namespace, nil); err != nil {
+		adscLog.Warnf("Error deleting an outdated resource from the store %v", err)
+		return
+	}
+	if a.LocalCacheDir != "" {
+		err := os.Remove(a.LocalCacheDir + "_res." +
+			groupVersionKind.Kind + "." + namespace + "." + name + ".json")
+		if err != nil {
+			adscLog.Warnf("Error deleting received MCP config to local file %v", err)
 		}
 	}
 }
@@ -43,7 +43,9 @@ import (
 	"istio.io/istio/pkg/config/schema/collections"
 )
 
-type testAdscRunServer struct{}
+type testAdscRunServer struct {
+	deltaStreamHandler func(stream discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error
+}
 
 var StreamHandler func(stream discovery.AggregatedDiscoveryService_StreamAggregatedResourcesServer) error
 
@@ -51,8 +53,11 @@ func (t *testAdscRunServer) StreamAggregatedResources(stream discovery.Aggregate
 	return StreamHandler(stream)
 }
 
-func (t *testAdscRunServer) DeltaAggregatedResources(discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
-	return nil
+func (t *testAdscRunServer) DeltaAggregatedResources(stream discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+	if t.deltaStreamHandler == nil {
+		return nil
+	}
+	return t.deltaStreamHandler(stream)
 }
 
 func TestADSC_Run(t *testing.T) {
@@ -0,0 +1,179 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adsc
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// runDelta creates a new delta stream using the existing grpc client connection and sends the initial requests.
+// The versions of the resources already received are sent with the requests, so that on reconnect the server
+// only sends the resources that changed, and the resources that were removed meanwhile.
+//
+// Only MCP and mesh config types are handled in delta mode. The resource names of the initial requests, such as the
+// namespaces of FilteredConfigInitialRequests, are subscribed to; the resources of a type are all watched if there
+// are none. ConfigFilter is still applied when resources are received.
+func (a *ADSC) runDelta() error {
+	var err error
+	a.client = discovery.NewAggregatedDiscoveryServiceClient(a.conn)
+	a.deltaStream, err = a.client.DeltaAggregatedResources(context.Background())
+	if err != nil {
+		return err
+	}
+	a.InitialLoad = 0
+	for i, r := range a.cfg.InitialDiscoveryRequests {
+		req := &discovery.DeltaDiscoveryRequest{
+			TypeUrl:                 r.TypeUrl,
+			ResourceNamesSubscribe:  r.ResourceNames,
+			InitialResourceVersions: a.initialResourceVersions(r.TypeUrl),
+		}
+		if i == 0 {
+			req.Node = a.node()
+		}
+		if err := a.deltaStream.Send(req); err != nil {
+			return err
+		}
+	}
+
+	a.RecvWg.Add(1)
+	go a.handleDeltaRecv()
+	return nil
+}
+
+// initialResourceVersions returns a copy of the versions of the resources of a type received so far.
+func (a *ADSC) initialResourceVersions(typeURL string) map[string]string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	known := a.knownVersions[typeURL]
+	if len(known) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(known))
+	for name, version := range known {
+		out[name] = version
+	}
+	return out
+}
+
+func (a *ADSC) handleDeltaRecv() {
+	for {
+		msg, err := a.deltaStream.Recv()
+		if err != nil {
+			a.handleStreamError(err)
+			return
+		}
+
+		gvk, isMCP := convertTypeURLToMCPGVK(msg.TypeUrl)
+
+		adscLog.Info("Received ", a.url, " type ", msg.TypeUrl,
+			" cnt=", len(msg.Resources), " removed=", len(msg.RemovedResources), " nonce=", msg.Nonce)
+
+		switch {
+		case msg.TypeUrl == collections.IstioMeshV1Alpha1MeshConfig.Resource().GroupVersionKind().String():
+			if len(msg.Resources) > 0 {
+				a.handleDeltaMesh(msg.Resources[0])
+			}
+		case isMCP:
+			a.handleDeltaMCP(gvk, msg.Resources, msg.RemovedResources)
+		default:
+			adscLog.Warnf("Ignoring delta response of unsupported type %s", msg.TypeUrl)
+		}
+
+		a.mutex.Lock()
+		if isMCP {
+			if _, exist := a.sync[gvk.String()]; !exist {
+				a.sync[gvk.String()] = time.Now()
+			}
+		}
+		a.VersionInfo[msg.TypeUrl] = msg.SystemVersionInfo
+		_ = a.deltaStream.Send(&discovery.DeltaDiscoveryRequest{
+			TypeUrl:       msg.TypeUrl,
+			ResponseNonce: msg.Nonce,
+		})
+		a.mutex.Unlock()
+	}
+}
+
+func (a *ADSC) handleDeltaMesh(rsc *discovery.Resource) {
+	if rsc.Resource == nil {
+		return
+	}
+	m := &v1alpha1.MeshConfig{}
+	if err := rsc.Resource.UnmarshalTo(m); err != nil {
+		adscLog.Warn("Failed to unmarshal mesh config", err)
+		return
+	}
+	a.Mesh = m
+	if a.LocalCacheDir != "" {
+		strResponse, err := protomarshal.ToJSONWithIndent(m, "  ")
+		if err != nil {
+			return
+		}
+		_ = os.WriteFile(a.LocalCacheDir+"_mesh.json", []byte(strResponse), 0o644)
+	}
+}
+
+// handleDeltaMCP adds or updates the received resources in the store, and deletes the removed ones. Unlike
+// handleMCP, resources that are not in the response are kept.
+func (a *ADSC) handleDeltaMCP(groupVersionKind config.GroupVersionKind, resources []*discovery.Resource, removed []string) {
+	typeURL := groupVersionKind.String()
+	a.mutex.Lock()
+	if a.knownVersions == nil {
+		a.knownVersions = map[string]map[string]string{}
+	}
+	known := a.knownVersions[typeURL]
+	if known == nil {
+		known = map[string]string{}
+		a.knownVersions[typeURL] = known
+	}
+	for _, rsc := range resources {
+		known[rsc.Name] = rsc.Version
+	}
+	for _, name := range removed {
+		delete(known, name)
+	}
+	a.mutex.Unlock()
+
+	if a.Store == nil || !a.filter.allowKind(groupVersionKind) {
+		return
+	}
+	for _, rsc := range resources {
+		if rsc.Resource == nil {
+			continue
+		}
+		a.applyMCP(groupVersionKind, rsc.Resource)
+	}
+	for _, name := range removed {
+		ns, n, ok := strings.Cut(name, "/")
+		if !ok {
+			adscLog.Warnf("Invalid removed resource name %s", name)
+			continue
+		}
+		if a.Store.Get(groupVersionKind, n, ns) == nil {
+			continue
+		}
+		a.deleteMCP(groupVersionKind, ns, n)
+	}
+}
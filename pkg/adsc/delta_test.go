@@ -0,0 +1,160 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adsc
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pkg/backoff"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func deltaResource(name, version string) *discovery.Resource {
+	return &discovery.Resource{
+		Name:     "default/" + name,
+		Version:  version,
+		Resource: constructResource(name, name+".bar.com", "192.1.1.1", version),
+	}
+}
+
+func storedNames(t *testing.T, a *ADSC) []string {
+	t.Helper()
+	configs, err := a.Store.List(gvk.ServiceEntry, "")
+	assert.NoError(t, err)
+	out := make([]string, 0, len(configs))
+	for _, c := range configs {
+		out = append(out, c.Namespace+"/"+c.Name)
+	}
+	return out
+}
+
+func TestADSC_handleDeltaMCP(t *testing.T) {
+	a := &ADSC{
+		VersionInfo: map[string]string{},
+		Store:       memory.Make(collections.Pilot),
+		cfg:         &Config{},
+	}
+	a.handleDeltaMCP(gvk.ServiceEntry, []*discovery.Resource{deltaResource("foo1", "1"), deltaResource("foo2", "1")}, nil)
+	assert.Equal(t, len(storedNames(t, a)), 2)
+
+	// Resources missing from a delta response are kept.
+	a.handleDeltaMCP(gvk.ServiceEntry, []*discovery.Resource{deltaResource("foo3", "1")}, nil)
+	assert.Equal(t, len(storedNames(t, a)), 3)
+
+	a.handleDeltaMCP(gvk.ServiceEntry, []*discovery.Resource{deltaResource("foo1", "2")}, []string{"default/foo2", "default/unknown"})
+	configs, _ := a.Store.List(gvk.ServiceEntry, "")
+	assert.Equal(t, len(configs), 2)
+	assert.Equal(t, a.Store.Get(gvk.ServiceEntry, "foo2", "default") == nil, true)
+	assert.Equal(t, a.initialResourceVersions(gvk.ServiceEntry.String()), map[string]string{
+		"default/foo1": "2",
+		"default/foo3": "1",
+	})
+}
+
+func TestADSC_RunDelta(t *testing.T) {
+	seType := gvk.ServiceEntry.String()
+	requests := make(chan *discovery.DeltaDiscoveryRequest, 10)
+	connections := atomic.NewInt32(0)
+	server := &testAdscRunServer{deltaStreamHandler: func(stream discovery.AggregatedDiscoveryService_DeltaAggregatedResourcesServer) error {
+		// The mesh config request, then the ServiceEntry request.
+		for i := 0; i < 2; i++ {
+			req, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			requests <- req
+		}
+		resp := &discovery.DeltaDiscoveryResponse{TypeUrl: seType, Nonce: "nonce"}
+		first := connections.Inc() == 1
+		if first {
+			resp.Resources = []*discovery.Resource{deltaResource("foo1", "1"), deltaResource("foo2", "1")}
+		} else {
+			resp.RemovedResources = []string{"default/foo2"}
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+		// Wait for the ack, so the response is processed before the stream is closed.
+		if _, err := stream.Recv(); err != nil {
+			return err
+		}
+		if first {
+			return errors.New("closing stream")
+		}
+		<-stream.Context().Done()
+		return nil
+	}}
+
+	l, err := net.Listen("tcp", ":0")
+	assert.NoError(t, err)
+	xds := grpc.NewServer()
+	discovery.RegisterAggregatedDiscoveryServiceServer(xds, server)
+	go func() {
+		_ = xds.Serve(l)
+	}()
+	t.Cleanup(xds.Stop)
+
+	a, err := New(l.Addr().String(), &Config{
+		Delta: true,
+		InitialDiscoveryRequests: FilteredConfigInitialRequests(&ConfigFilter{
+			Namespaces: []string{"default"},
+			Kinds:      []config.GroupVersionKind{gvk.ServiceEntry},
+		}),
+		BackoffPolicy: backoff.NewExponentialBackOff(backoff.Option{InitialInterval: 10 * time.Millisecond, MaxInterval: 10 * time.Millisecond}),
+	})
+	assert.NoError(t, err)
+	a.Store = memory.Make(collections.Pilot)
+	t.Cleanup(a.Close)
+	assert.NoError(t, a.Run())
+
+	req := <-requests
+	assert.Equal(t, req.Node != nil, true)
+	req = <-requests
+	assert.Equal(t, req.TypeUrl, seType)
+	// The namespaces of the filter are subscribed to.
+	assert.Equal(t, req.ResourceNamesSubscribe, []string{"default/*"})
+	assert.Equal(t, len(req.InitialResourceVersions), 0)
+
+	// On reconnect, the versions of the resources received are sent.
+	for i := 0; i < 2; i++ {
+		select {
+		case req = <-requests:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for reconnect")
+		}
+	}
+	assert.Equal(t, req.ResourceNamesSubscribe, []string{"default/*"})
+	assert.Equal(t, req.InitialResourceVersions, map[string]string{
+		"default/foo1": "1",
+		"default/foo2": "1",
+	})
+	retry.UntilOrFail(t, func() bool {
+		names := storedNames(t, a)
+		return len(names) == 1 && names[0] == "default/foo1"
+	}, retry.Timeout(5*time.Second))
+	assert.Equal(t, a.HasSynced(), true)
+}
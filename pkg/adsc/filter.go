@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adsc
+
+import (
+	"fmt"
+	"sort"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// NamespaceNameLabel is the label set by Kubernetes on every namespace, holding its name. It is the only label
+// namespace selectors are matched against, as namespaces are not sent over MCP.
+const NamespaceNameLabel = "kubernetes.io/metadata.name"
+
+// ConfigFilter restricts the config read from an MCP server, by namespace and kind.
+type ConfigFilter struct {
+	// Namespaces config is read from. All namespaces are read if empty.
+	// The namespaces are also sent as resource names of the form <namespace>/*, so that the server can skip the
+	// other namespaces.
+	Namespaces []string
+	// NamespaceSelectors select the namespaces config is read from, like the mesh discovery selectors. A
+	// namespace is read if it matches any of the selectors. Only the NamespaceNameLabel label can be matched.
+	NamespaceSelectors []*metav1.LabelSelector
+	// Kinds of config read. All Pilot kinds are read if empty.
+	Kinds []config.GroupVersionKind
+}
+
+// configFilter is a compiled ConfigFilter. A nil filter allows everything.
+type configFilter struct {
+	namespaces sets.String
+	selectors  []klabels.Selector
+	kinds      sets.Set[config.GroupVersionKind]
+}
+
+func newConfigFilter(f *ConfigFilter) (*configFilter, error) {
+	if f == nil {
+		return nil, nil
+	}
+	out := &configFilter{}
+	if len(f.Namespaces) > 0 {
+		out.namespaces = sets.New(f.Namespaces...)
+	}
+	for _, s := range f.NamespaceSelectors {
+		selector, err := metav1.LabelSelectorAsSelector(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace selector: %v", err)
+		}
+		out.selectors = append(out.selectors, selector)
+	}
+	if len(f.Kinds) > 0 {
+		out.kinds = sets.New(f.Kinds...)
+	}
+	return out, nil
+}
+
+func (f *configFilter) allowKind(kind config.GroupVersionKind) bool {
+	return f == nil || f.kinds == nil || f.kinds.Contains(kind)
+}
+
+func (f *configFilter) allowNamespace(namespace string) bool {
+	if f == nil {
+		return true
+	}
+	if f.namespaces != nil && !f.namespaces.Contains(namespace) {
+		return false
+	}
+	if len(f.selectors) == 0 {
+		return true
+	}
+	lbls := klabels.Set{NamespaceNameLabel: namespace}
+	for _, selector := range f.selectors {
+		if selector.Matches(lbls) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilteredConfigInitialRequests returns the requests for the config allowed by the filter, and the mesh config.
+func FilteredConfigInitialRequests(f *ConfigFilter) []*discovery.DiscoveryRequest {
+	if f == nil {
+		return ConfigInitialRequests()
+	}
+	kinds := sets.New(f.Kinds...)
+	var names []string
+	for _, ns := range f.Namespaces {
+		names = append(names, ns+"/*")
+	}
+	sort.Strings(names)
+	out := []*discovery.DiscoveryRequest{{
+		TypeUrl: collections.IstioMeshV1Alpha1MeshConfig.Resource().GroupVersionKind().String(),
+	}}
+	for _, sch := range collections.Pilot.All() {
+		kind := sch.Resource().GroupVersionKind()
+		if len(kinds) > 0 && !kinds.Contains(kind) {
+			continue
+		}
+		out = append(out, &discovery.DiscoveryRequest{
+			TypeUrl:       kind.String(),
+			ResourceNames: names,
+		})
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adsc
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mcp "istio.io/api/mcp/v1alpha1"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestConfigFilter(t *testing.T) {
+	cases := []struct {
+		name    string
+		filter  *ConfigFilter
+		allowed []string
+	}{
+		{
+			name:    "nil",
+			filter:  nil,
+			allowed: []string{"a", "b", "c"},
+		},
+		{
+			name:    "namespaces",
+			filter:  &ConfigFilter{Namespaces: []string{"a", "b"}},
+			allowed: []string{"a", "b"},
+		},
+		{
+			name: "selectors",
+			filter: &ConfigFilter{NamespaceSelectors: []*metav1.LabelSelector{
+				{MatchLabels: map[string]string{NamespaceNameLabel: "a"}},
+				{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: NamespaceNameLabel, Operator: metav1.LabelSelectorOpIn, Values: []string{"c"}},
+				}},
+			}},
+			allowed: []string{"a", "c"},
+		},
+		{
+			name: "namespaces and selectors",
+			filter: &ConfigFilter{
+				Namespaces: []string{"a", "b"},
+				NamespaceSelectors: []*metav1.LabelSelector{{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: NamespaceNameLabel, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"a"}},
+				}}},
+			},
+			allowed: []string{"b"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newConfigFilter(tt.filter)
+			assert.NoError(t, err)
+			var allowed []string
+			for _, ns := range []string{"a", "b", "c"} {
+				if f.allowNamespace(ns) {
+					allowed = append(allowed, ns)
+				}
+			}
+			assert.Equal(t, allowed, tt.allowed)
+		})
+	}
+
+	_, err := newConfigFilter(&ConfigFilter{NamespaceSelectors: []*metav1.LabelSelector{{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{Key: NamespaceNameLabel, Operator: "bad"}},
+	}}})
+	assert.Error(t, err)
+}
+
+func TestFilteredConfigInitialRequests(t *testing.T) {
+	assert.Equal(t, len(FilteredConfigInitialRequests(nil)), len(ConfigInitialRequests()))
+
+	reqs := FilteredConfigInitialRequests(&ConfigFilter{
+		Namespaces: []string{"b", "a"},
+		Kinds:      []config.GroupVersionKind{gvk.VirtualService},
+	})
+	assert.Equal(t, len(reqs), 2)
+	assert.Equal(t, reqs[0].TypeUrl, collections.IstioMeshV1Alpha1MeshConfig.Resource().GroupVersionKind().String())
+	assert.Equal(t, reqs[1].TypeUrl, gvk.VirtualService.String())
+	assert.Equal(t, reqs[1].ResourceNames, []string{"a/*", "b/*"})
+}
+
+func TestHandleMCPFiltered(t *testing.T) {
+	filter, err := newConfigFilter(&ConfigFilter{
+		Namespaces: []string{"default"},
+		Kinds:      []config.GroupVersionKind{gvk.ServiceEntry},
+	})
+	assert.NoError(t, err)
+	a := &ADSC{
+		VersionInfo: map[string]string{},
+		Store:       memory.Make(collections.Pilot),
+		cfg:         &Config{},
+		filter:      filter,
+	}
+	resources := []*anypb.Any{
+		constructResource("foo1", "foo1.bar.com", "192.1.1.1", "1"),
+		constructResourceWithOptions("foo2", "foo2.bar.com", "192.1.1.2", "1", func(resource *mcp.Resource) {
+			resource.Metadata.Name = "other/foo2"
+		}),
+	}
+	a.handleMCP(gvk.ServiceEntry, resources)
+	configs, _ := a.Store.List(gvk.ServiceEntry, "")
+	assert.Equal(t, len(configs), 1)
+	assert.Equal(t, configs[0].Namespace+"/"+configs[0].Name, "default/foo1")
+
+	// Kinds that are not allowed are not stored.
+	a.handleMCP(gvk.VirtualService, resources)
+	configs, _ = a.Store.List(gvk.VirtualService, "")
+	assert.Equal(t, len(configs), 0)
+}
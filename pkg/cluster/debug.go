@@ -22,3 +22,11 @@ type DebugInfo struct {
 	SecretName string `json:"secretName"`
 	SyncStatus string `json:"syncStatus"`
 }
+
+// ConfigSourceDebugInfo contains minimal information about config sources read from remote clusters.
+type ConfigSourceDebugInfo struct {
+	Address    string   `json:"address"`
+	ClusterID  ID       `json:"clusterID"`
+	Namespaces []string `json:"namespaces,omitempty"`
+	SyncStatus string   `json:"syncStatus"`
+}
@@ -36,10 +36,16 @@ import (
 
 // All returns all analyzers
 func All() []analysis.Analyzer {
+	return append(InCluster(), Expensive()...)
+}
+
+// InCluster returns the analyzers run periodically by istiod, which are all analyzers but the Expensive ones.
+func InCluster() []analysis.Analyzer {
 	analyzers := []analysis.Analyzer{
 		// Please keep this list sorted alphabetically by pkg.name for convenience
 		&annotations.K8sAnalyzer{},
 		&authz.AuthorizationPoliciesAnalyzer{},
+		&authz.PolicyConflictAnalyzer{},
 		&deployment.ServiceAssociationAnalyzer{},
 		&deployment.ApplicationUIDAnalyzer{},
 		&deprecation.FieldAnalyzer{},
@@ -60,6 +66,7 @@ func All() []analysis.Analyzer {
 		&virtualservice.GatewayAnalyzer{},
 		&virtualservice.JWTClaimRouteAnalyzer{},
 		&virtualservice.RegexAnalyzer{},
+		&virtualservice.ShadowedRouteAnalyzer{},
 		&destinationrule.CaCertificateAnalyzer{},
 		&serviceentry.ProtocolAddressesAnalyzer{},
 		&webhook.Analyzer{},
@@ -72,7 +79,20 @@ func All() []analysis.Analyzer {
 	return analyzers
 }
 
+// Expensive returns the analyzers that generate the config of the proxies, whose cost grows with the number of
+// workloads. They are only run on demand, by istioctl analyze.
+func Expensive() []analysis.Analyzer {
+	return []analysis.Analyzer{
+		&envoyfilter.PatchMatchAnalyzer{},
+	}
+}
+
 // AllCombined returns all analyzers combined as one
 func AllCombined() *analysis.CombinedAnalyzer {
 	return analysis.Combine("all", All()...)
 }
+
+// InClusterCombined returns the InCluster analyzers combined as one
+func InClusterCombined() *analysis.CombinedAnalyzer {
+	return analysis.Combine("incluster", InCluster()...)
+}
@@ -348,6 +348,18 @@ var testGrid = []testCase{
 			{msg.JwtClaimBasedRoutingWithoutRequestAuthN, "VirtualService foo"},
 		},
 	},
+	{
+		name:       "virtualServiceShadowedRoutes",
+		inputFiles: []string{"testdata/virtualservice_shadowedroutes.yaml"},
+		analyzer:   &virtualservice.ShadowedRouteAnalyzer{},
+		expected: []message{
+			{msg.VirtualServiceShadowedRoute, "VirtualService default/prefix-shadowed"},
+			{msg.VirtualServiceShadowedRoute, "VirtualService default/method-shadowed"},
+			{msg.VirtualServiceShadowedRoute, "VirtualService default/multiple-shadowed"},
+			{msg.VirtualServiceShadowedRoute, "VirtualService default/root"},
+			{msg.VirtualServiceShadowedRoute, "VirtualService default/delegate-v2"},
+		},
+	},
 	{
 		name:       "serviceMultipleDeployments",
 		inputFiles: []string{"testdata/deployment-multi-service.yaml"},
@@ -411,6 +423,21 @@ var testGrid = []testCase{
 			{msg.ReferencedResourceNotFound, "AuthorizationPolicy httpbin/httpbin-bogus-not-ns"},
 		},
 	},
+	{
+		name: "authorizationPolicyConflicts",
+		inputFiles: []string{
+			"testdata/authorizationpolicies-conflicts.yaml",
+		},
+		meshConfigFile: "testdata/authorizationpolicies-conflicts-mesh.yaml",
+		analyzer:       &authz.PolicyConflictAnalyzer{},
+		expected: []message{
+			{msg.AuthorizationPolicyAllowRuleDenied, "AuthorizationPolicy foo/allow-admin"},
+			{msg.AuthorizationPolicyAllowRuleDenied, "AuthorizationPolicy bar/allow-internal"},
+			{msg.AuthorizationPolicyAllowAllRule, "AuthorizationPolicy baz/allow-all-widening"},
+			{msg.AuthorizationPolicyUnknownProvider, "AuthorizationPolicy foo/custom-unknown"},
+			{msg.AuthorizationPolicyTrustDomainMismatch, "AuthorizationPolicy foo/trust-domain-mismatch"},
+		},
+	},
 	{
 		name: "destinationrule with no cacert, simple at destinationlevel",
 		inputFiles: []string{
@@ -729,6 +756,17 @@ var testGrid = []testCase{
 			{msg.EnvoyFilterUsesRelativeOperation, "EnvoyFilter bookinfo/test-remove-5"},
 		},
 	},
+	{
+		name:       "EnvoyFilterPatchMatch",
+		inputFiles: []string{"testdata/envoy-filter-patch-match.yaml"},
+		analyzer:   &envoyfilter.PatchMatchAnalyzer{},
+		expected: []message{
+			{msg.EnvoyFilterPatchNotMatched, "EnvoyFilter default/patch-not-matched"},
+			{msg.EnvoyFilterPatchNotMatched, "EnvoyFilter default/patch-version-mismatch"},
+			{msg.EnvoyFilterRelativeFilterNotFound, "EnvoyFilter default/relative-filter-not-found"},
+			{msg.EnvoyFilterPatchInvalidTypedConfig, "EnvoyFilter default/invalid-typed-config"},
+		},
+	},
 	{
 		name:       "Analyze conflicting gateway with list type",
 		inputFiles: []string{"testdata/analyze-list-type.yaml"},
@@ -839,6 +877,20 @@ func TestAnalyzersInAll(t *testing.T) {
 	}
 }
 
+// Verify that the expensive analyzers are not run periodically by istiod
+func TestExpensiveAnalyzersNotInCluster(t *testing.T) {
+	g := NewWithT(t)
+
+	var inCluster []string
+	for _, a := range InCluster() {
+		inCluster = append(inCluster, a.Metadata().Name)
+	}
+	g.Expect(inCluster).To(HaveLen(len(All()) - len(Expensive())))
+	for _, a := range Expensive() {
+		g.Expect(inCluster).NotTo(ContainElement(a.Metadata().Name))
+	}
+}
+
 func TestAnalyzersHaveUniqueNames(t *testing.T) {
 	g := NewWithT(t)
 
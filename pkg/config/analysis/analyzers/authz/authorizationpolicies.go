@@ -20,7 +20,6 @@ import (
 
 	klabels "k8s.io/apimachinery/pkg/labels"
 
-	"istio.io/api/mesh/v1alpha1"
 	"istio.io/api/security/v1beta1"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
@@ -33,10 +32,7 @@ import (
 // AuthorizationPoliciesAnalyzer checks the validity of authorization policies
 type AuthorizationPoliciesAnalyzer struct{}
 
-var (
-	_          analysis.Analyzer = &AuthorizationPoliciesAnalyzer{}
-	meshConfig *v1alpha1.MeshConfig
-)
+var _ analysis.Analyzer = &AuthorizationPoliciesAnalyzer{}
 
 func (a *AuthorizationPoliciesAnalyzer) Metadata() analysis.Metadata {
 	return analysis.Metadata{
@@ -100,23 +96,10 @@ func (a *AuthorizationPoliciesAnalyzer) analyzeNoMatchingWorkloads(r *resource.I
 // Returns true when the namespace is the root namespace.
 // It takes the MeshConfig names istio, if not the last instance found.
 func meshWidePolicy(ns string, c analysis.Context) bool {
-	mConf := fetchMeshConfig(c)
+	mConf := util.FetchMeshConfig(c)
 	return mConf != nil && ns == mConf.GetRootNamespace()
 }
 
-func fetchMeshConfig(c analysis.Context) *v1alpha1.MeshConfig {
-	if meshConfig != nil {
-		return meshConfig
-	}
-
-	c.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
-		meshConfig = r.Message.(*v1alpha1.MeshConfig)
-		return r.Metadata.FullName.Name != util.MeshConfigName
-	})
-
-	return meshConfig
-}
-
 func hasMatchingPodsRunning(selector klabels.Selector, podLabelsMap map[string][]klabels.Set) bool {
 	for _, setList := range podLabelsMap {
 		if hasMatchingPodsRunningIn(selector, setList) {
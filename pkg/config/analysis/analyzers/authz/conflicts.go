@@ -0,0 +1,222 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"istio.io/api/annotation"
+	"istio.io/api/security/v1beta1"
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// PolicyConflictAnalyzer checks for authorization policy rules that conflict with other policies, widen access or
+// never match, using the rule matching of the authorization filter generation.
+type PolicyConflictAnalyzer struct{}
+
+var _ analysis.Analyzer = &PolicyConflictAnalyzer{}
+
+// policy is an authorization policy with the models of its rules. The model of an invalid rule is nil.
+type policy struct {
+	r      *resource.Instance
+	ap     *v1beta1.AuthorizationPolicy
+	models []*authzmodel.Model
+}
+
+func (a *PolicyConflictAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "auth.PolicyConflictAnalyzer",
+		Description: "Checks for conflicting, redundant and ineffective authorization policy rules",
+		Inputs: collection.Names{
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+			collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+		},
+	}
+}
+
+func (a *PolicyConflictAnalyzer) Analyze(c analysis.Context) {
+	mc := util.FetchMeshConfig(c)
+	bundle := trustdomain.NewBundle(mc.GetTrustDomain(), mc.GetTrustDomainAliases())
+	providers := sets.New[string]()
+	for _, p := range mc.GetExtensionProviders() {
+		providers.Insert(p.GetName())
+	}
+
+	var allow, deny []policy
+	c.ForEach(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), func(r *resource.Instance) bool {
+		ap := r.Message.(*v1beta1.AuthorizationPolicy)
+		a.analyzeProvider(r, c, providers)
+		a.analyzeTrustDomain(r, c, bundle, mc.GetTrustDomain())
+
+		if isDryRun(r) {
+			return true
+		}
+		p := policy{r: r, ap: ap}
+		for _, rule := range ap.GetRules() {
+			// Invalid rules are reported by the schema validation
+			m, _ := authzmodel.New(rule)
+			p.models = append(p.models, m)
+		}
+		switch ap.GetAction() {
+		case v1beta1.AuthorizationPolicy_ALLOW:
+			allow = append(allow, p)
+		case v1beta1.AuthorizationPolicy_DENY:
+			deny = append(deny, p)
+		}
+		return true
+	})
+
+	rootNamespace := mc.GetRootNamespace()
+	for _, p := range allow {
+		a.analyzeAllowAll(p, allow, c, rootNamespace)
+		a.analyzeDeniedAllow(p, deny, c, rootNamespace)
+	}
+}
+
+// analyzeAllowAll reports ALLOW rules matching all requests, when other ALLOW rules apply to the same workloads.
+func (a *PolicyConflictAnalyzer) analyzeAllowAll(p policy, allow []policy, c analysis.Context, rootNamespace string) {
+	for i, m := range p.models {
+		if m == nil || !m.MatchesAll() {
+			continue
+		}
+		var ineffective []string
+		for j := range p.models {
+			if j != i {
+				ineffective = append(ineffective, fmt.Sprintf("rule #%d", j))
+			}
+		}
+		for _, other := range allow {
+			if other.r != p.r && appliesToAll(p, other, rootNamespace) {
+				ineffective = append(ineffective, "the rules of AuthorizationPolicy "+other.r.Metadata.FullName.String())
+			}
+		}
+		if len(ineffective) == 0 {
+			// A single rule matching all requests is the documented way to allow all requests
+			continue
+		}
+		c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+			msg.NewAuthorizationPolicyAllowAllRule(p.r, fmt.Sprintf("#%d", i), strings.Join(ineffective, ", ")))
+	}
+}
+
+// analyzeDeniedAllow reports ALLOW rules matching only requests denied by a DENY policy applying to all the
+// workloads of the ALLOW policy.
+func (a *PolicyConflictAnalyzer) analyzeDeniedAllow(p policy, deny []policy, c analysis.Context, rootNamespace string) {
+	for i, m := range p.models {
+		if m == nil {
+			continue
+		}
+	denyPolicies:
+		for _, d := range deny {
+			if !appliesToAll(d, p, rootNamespace) {
+				continue
+			}
+			for j, dm := range d.models {
+				if dm != nil && dm.Covers(m) {
+					c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(),
+						msg.NewAuthorizationPolicyAllowRuleDenied(p.r, fmt.Sprintf("#%d", i), fmt.Sprintf("#%d", j),
+							"AuthorizationPolicy "+d.r.Metadata.FullName.String()))
+					break denyPolicies
+				}
+			}
+		}
+	}
+}
+
+func (a *PolicyConflictAnalyzer) analyzeProvider(r *resource.Instance, c analysis.Context, providers sets.Set[string]) {
+	ap := r.Message.(*v1beta1.AuthorizationPolicy)
+	if ap.GetAction() != v1beta1.AuthorizationPolicy_CUSTOM || ap.GetProvider() == nil {
+		return
+	}
+	name := ap.GetProvider().GetName()
+	if providers.Contains(name) {
+		return
+	}
+	m := msg.NewAuthorizationPolicyUnknownProvider(r, name)
+
+	if line, ok := util.ErrorLine(r, util.AuthorizationPolicyProviderName); ok {
+		m.Line = line
+	}
+
+	c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), m)
+}
+
+func (a *PolicyConflictAnalyzer) analyzeTrustDomain(r *resource.Instance, c analysis.Context, bundle trustdomain.Bundle, trustDomain string) {
+	ap := r.Message.(*v1beta1.AuthorizationPolicy)
+	report := func(principal, path string) {
+		if bundle.MatchTrustDomain(principal) {
+			return
+		}
+		m := msg.NewAuthorizationPolicyTrustDomainMismatch(r, principal, trustDomain)
+
+		if line, ok := util.ErrorLine(r, path); ok {
+			m.Line = line
+		}
+
+		c.Report(collections.IstioSecurityV1Beta1Authorizationpolicies.Name(), m)
+	}
+
+	for i, rule := range ap.GetRules() {
+		for j, from := range rule.GetFrom() {
+			for k, principal := range from.GetSource().GetPrincipals() {
+				report(principal, fmt.Sprintf(util.AuthorizationPolicyPrincipal, i, j, k))
+			}
+			for k, principal := range from.GetSource().GetNotPrincipals() {
+				report(principal, fmt.Sprintf(util.AuthorizationPolicyNotPrincipal, i, j, k))
+			}
+		}
+		for _, when := range rule.GetWhen() {
+			if when.GetKey() != "source.principal" {
+				continue
+			}
+			principals := make([]string, 0, len(when.GetValues())+len(when.GetNotValues()))
+			principals = append(principals, when.GetValues()...)
+			principals = append(principals, when.GetNotValues()...)
+			for _, principal := range principals {
+				report(principal, "")
+			}
+		}
+	}
+}
+
+// appliesToAll returns true if the policy applies to all the workloads the other policy applies to.
+func appliesToAll(p, other policy, rootNamespace string) bool {
+	ns := p.r.Metadata.FullName.Namespace.String()
+	if ns != rootNamespace && ns != other.r.Metadata.FullName.Namespace.String() {
+		return false
+	}
+	otherLabels := other.ap.GetSelector().GetMatchLabels()
+	for k, v := range p.ap.GetSelector().GetMatchLabels() {
+		if ov, f := otherLabels[k]; !f || ov != v {
+			return false
+		}
+	}
+	return true
+}
+
+func isDryRun(r *resource.Instance) bool {
+	dryRun, _ := strconv.ParseBool(r.Metadata.Annotations[annotation.IoIstioDryRun.Name])
+	return dryRun
+}
@@ -0,0 +1,179 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoyfilter
+
+import (
+	"fmt"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
+	memregistry "istio.io/istio/pilot/pkg/serviceregistry/memory"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
+	istiocluster "istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// configGenerator generates the config of proxies for a fixed set of configs, services and instances, with the
+// config generation of istiod.
+type configGenerator struct {
+	env  *model.Environment
+	gen  *v1alpha3.ConfigGeneratorImpl
+	stop chan struct{}
+}
+
+// newConfigGenerator returns a generator for the configs, services and instances. It must be closed once done.
+func newConfigGenerator(configs []config.Config, services []*model.Service, instances []*model.ServiceInstance,
+	mc *meshconfig.MeshConfig,
+) (*configGenerator, error) {
+	store := memory.NewSyncController(memory.MakeSkipValidation(collections.Pilot))
+	se := serviceentry.NewController(store, noopXdsUpdater{})
+	msd := memregistry.NewServiceDiscovery(services...)
+	for _, instance := range instances {
+		msd.AddInstance(instance.Service.Hostname, instance)
+	}
+	msd.ClusterID = istiocluster.ID(provider.Mock)
+	registries := aggregate.NewController(aggregate.Options{})
+	registries.AddRegistry(se)
+	registries.AddRegistry(serviceregistry.Simple{
+		ClusterID:        istiocluster.ID(provider.Mock),
+		ProviderID:       provider.Mock,
+		ServiceDiscovery: msd,
+		Controller:       msd.Controller,
+	})
+
+	env := model.NewEnvironment()
+	env.Watcher = mesh.NewFixedWatcher(mc)
+	env.NetworksWatcher = mesh.NewFixedNetworksWatcher(nil)
+	env.ServiceDiscovery = registries
+	env.ConfigStore = store
+	env.Init()
+
+	g := &configGenerator{
+		env:  env,
+		gen:  v1alpha3.NewConfigGenerator(&model.DisabledCache{}),
+		stop: make(chan struct{}),
+	}
+	// The ServiceEntry registry processes the endpoints of the ServiceEntries in the background.
+	go se.Run(g.stop)
+	for _, cfg := range configs {
+		if _, err := store.Create(cfg); err != nil {
+			g.close()
+			return nil, fmt.Errorf("failed to create config %s/%s: %v", cfg.Namespace, cfg.Name, err)
+		}
+	}
+	if err := env.InitNetworksManager(noopXdsUpdater{}); err != nil {
+		g.close()
+		return nil, err
+	}
+	if err := env.PushContext.InitContext(env, nil, nil); err != nil {
+		g.close()
+		return nil, fmt.Errorf("failed to initialize push context: %v", err)
+	}
+	return g, nil
+}
+
+func (g *configGenerator) close() {
+	close(g.stop)
+}
+
+// setupProxy initializes the proxy for the push context, as istiod does when the proxy connects.
+func (g *configGenerator) setupProxy(p *model.Proxy) *model.Proxy {
+	p.IstioVersion = model.ParseIstioVersion(p.Metadata.IstioVersion)
+	p.DNSDomain = p.ConfigNamespace + ".svc." + g.env.DomainSuffix
+	push := g.env.PushContext
+	p.SetSidecarScope(push)
+	p.SetServiceInstances(g.env.ServiceDiscovery)
+	p.SetGatewaysForProxy(push)
+	p.DiscoverIPMode()
+	return p
+}
+
+func (g *configGenerator) listeners(p *model.Proxy) []*listener.Listener {
+	return g.gen.BuildListeners(p, g.env.PushContext)
+}
+
+func (g *configGenerator) clusters(p *model.Proxy) ([]*cluster.Cluster, error) {
+	resources, _ := g.gen.BuildClusters(p, &model.PushRequest{Push: g.env.PushContext})
+	out := make([]*cluster.Cluster, 0, len(resources))
+	for _, r := range resources {
+		c := &cluster.Cluster{}
+		if err := r.Resource.UnmarshalTo(c); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// routes returns the route configurations referenced by the listeners.
+func (g *configGenerator) routes(p *model.Proxy, listeners []*listener.Listener) ([]*route.RouteConfiguration, error) {
+	var names []string
+	for _, l := range listeners {
+		for _, fc := range l.FilterChains {
+			for _, f := range fc.Filters {
+				if f.Name != wellknown.HTTPConnectionManager || f.GetTypedConfig() == nil {
+					continue
+				}
+				hc := &hcm.HttpConnectionManager{}
+				if err := f.GetTypedConfig().UnmarshalTo(hc); err != nil {
+					return nil, err
+				}
+				if rds := hc.GetRds(); rds != nil {
+					names = append(names, rds.RouteConfigName)
+				}
+			}
+		}
+	}
+	resources, _ := g.gen.BuildHTTPRoutes(p, &model.PushRequest{Push: g.env.PushContext}, names)
+	out := make([]*route.RouteConfiguration, 0, len(resources))
+	for _, r := range resources {
+		rc := &route.RouteConfiguration{}
+		if err := r.Resource.UnmarshalTo(rc); err != nil {
+			return nil, err
+		}
+		out = append(out, rc)
+	}
+	return out, nil
+}
+
+// noopXdsUpdater ignores the updates of the registries, as the config is generated once.
+type noopXdsUpdater struct{}
+
+var _ model.XDSUpdater = noopXdsUpdater{}
+
+func (noopXdsUpdater) ConfigUpdate(*model.PushRequest) {}
+
+func (noopXdsUpdater) EDSUpdate(model.ShardKey, string, string, []*model.IstioEndpoint) {}
+
+func (noopXdsUpdater) EDSCacheUpdate(model.ShardKey, string, string, []*model.IstioEndpoint) {}
+
+func (noopXdsUpdater) SvcUpdate(model.ShardKey, string, string, model.Event) {}
+
+func (noopXdsUpdater) ProxyUpdate(istiocluster.ID, string) {}
+
+func (noopXdsUpdater) RemoveShard(model.ShardKey) {}
@@ -0,0 +1,574 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package envoyfilter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	network "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	efpatch "istio.io/istio/pilot/pkg/networking/core/v1alpha3/envoyfilter"
+	networkingutil "istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// PatchMatchAnalyzer generates the config of the workloads selected by each EnvoyFilter, and checks that its
+// patches apply to the generated config.
+type PatchMatchAnalyzer struct{}
+
+var _ analysis.Analyzer = &PatchMatchAnalyzer{}
+
+// workloadIP is the address of the proxy the config is generated for. The config does not depend on it.
+const workloadIP = "1.1.1.1"
+
+// configCollections are the collections of the Istio config used to generate the config of the workloads.
+var configCollections = []collection.Schema{
+	collections.IstioNetworkingV1Alpha3Envoyfilters,
+	collections.IstioNetworkingV1Alpha3Serviceentries,
+	collections.IstioNetworkingV1Alpha3Virtualservices,
+	collections.IstioNetworkingV1Alpha3Destinationrules,
+	collections.IstioNetworkingV1Alpha3Gateways,
+	collections.IstioNetworkingV1Alpha3Sidecars,
+}
+
+// workload is a proxy of the mesh. Pods with the same labels and proxy are a single workload.
+type workload struct {
+	name      string
+	namespace string
+	labels    map[string]string
+	typ       model.NodeType
+	version   string
+	pod       *v1.Pod
+}
+
+// generated is the config generated for a workload, without the analyzed EnvoyFilter.
+type generated struct {
+	workload  *workload
+	proxy     *model.Proxy
+	listeners []*listener.Listener
+	clusters  []*cluster.Cluster
+	routes    []*route.RouteConfiguration
+}
+
+// Metadata implements analysis.Analyzer
+func (*PatchMatchAnalyzer) Metadata() analysis.Metadata {
+	inputs := collection.Names{
+		collections.IstioMeshV1Alpha1MeshConfig.Name(),
+		collections.K8SCoreV1Pods.Name(),
+		collections.K8SCoreV1Services.Name(),
+	}
+	for _, s := range configCollections {
+		inputs = append(inputs, s.Name())
+	}
+	return analysis.Metadata{
+		Name:        "envoyfilter.PatchMatchAnalyzer",
+		Description: "Checks that the patches of EnvoyFilters apply to the config generated for the workloads they select",
+		Inputs:      inputs,
+	}
+}
+
+// Analyze implements analysis.Analyzer
+func (a *PatchMatchAnalyzer) Analyze(c analysis.Context) {
+	mc := util.FetchMeshConfig(c)
+	if mc == nil {
+		mc = mesh.DefaultMeshConfig()
+	}
+	configs := map[resource.FullName]config.Config{}
+	var others []config.Config
+	var filters []*resource.Instance
+	for _, s := range configCollections {
+		s := s
+		c.ForEach(s.Name(), func(r *resource.Instance) bool {
+			cfg := toConfig(r, s)
+			if s.Name() == collections.IstioNetworkingV1Alpha3Envoyfilters.Name() {
+				configs[r.Metadata.FullName] = cfg
+				filters = append(filters, r)
+			} else {
+				others = append(others, cfg)
+			}
+			return true
+		})
+	}
+	workloads := workloadsFor(c)
+	services := servicesFor(c)
+
+	selected := make(map[resource.FullName][]*workload, len(filters))
+	// selecting are the EnvoyFilters selecting each workload, the only ones that change its config.
+	selecting := map[*workload][]resource.FullName{}
+	for _, r := range filters {
+		name := r.Metadata.FullName
+		selected[name] = selectedWorkloads(r, workloads, mc.GetRootNamespace())
+		for _, w := range selected[name] {
+			selecting[w] = append(selecting[w], name)
+		}
+	}
+
+	// The config of a workload only depends on the EnvoyFilters selecting it, so it is generated once for the
+	// EnvoyFilters that share the same other EnvoyFilters, e.g. when a workload is selected by a single one.
+	type generationKey struct {
+		workload *workload
+		filters  string
+	}
+	cache := map[generationKey]*generated{}
+	for _, r := range filters {
+		if len(selected[r.Metadata.FullName]) == 0 {
+			continue
+		}
+		var gen []*generated
+		for _, w := range selected[r.Metadata.FullName] {
+			// Generate the config of the workload with the other EnvoyFilters, so that the patches of the analyzed
+			// EnvoyFilter apply to the config the proxy gets from istiod.
+			in := append([]config.Config{}, others...)
+			var names []string
+			for _, name := range selecting[w] {
+				if name != r.Metadata.FullName {
+					in = append(in, configs[name])
+					names = append(names, name.String())
+				}
+			}
+			sort.Strings(names)
+			key := generationKey{workload: w, filters: strings.Join(names, ",")}
+			g, f := cache[key]
+			if !f {
+				// Invalid config is reported by the other analyzers
+				g, _ = generate(w, in, services, mc)
+				cache[key] = g
+			}
+			if g != nil {
+				gen = append(gen, g)
+			}
+		}
+		if len(gen) > 0 {
+			a.analyzeEnvoyFilter(r, c, gen)
+		}
+	}
+}
+
+func (a *PatchMatchAnalyzer) analyzeEnvoyFilter(r *resource.Instance, c analysis.Context, gen []*generated) {
+	ef := r.Message.(*network.EnvoyFilter)
+	name, namespace := r.Metadata.FullName.Name.String(), r.Metadata.FullName.Namespace.String()
+	for i, cp := range ef.ConfigPatches {
+		cpw := model.ConvertEnvoyFilterConfigPatch(name, namespace, cp)
+		if cpw == nil || cpw.Value == nil || !analyzable(cpw) {
+			continue
+		}
+		patch := fmt.Sprintf("#%d", i)
+
+		var m diag.Message
+		applied := false
+		relative := ""
+		for _, g := range gen {
+			if !cpw.MatchesProxy(g.proxy) {
+				continue
+			}
+			if cpw.Operation == network.EnvoyFilter_Patch_MERGE {
+				if filter, typeURL, err := mergeError(g, cpw); err != nil {
+					m = msg.NewEnvoyFilterPatchInvalidTypedConfig(r, patch, filter, typeURL, err.Error())
+					break
+				}
+			}
+			if applies(g, cpw) {
+				applied = true
+				break
+			}
+			if filter := relativeFilter(cpw); filter != "" && applies(g, withoutRelativeFilter(cpw)) {
+				relative = filter
+			}
+		}
+		switch {
+		case m.Type != nil:
+			// The typed config of the patch is invalid
+		case applied:
+			continue
+		case relative != "":
+			m = msg.NewEnvoyFilterRelativeFilterNotFound(r, patch, relative, gen[0].workload.name)
+		default:
+			m = msg.NewEnvoyFilterPatchNotMatched(r, patch, gen[0].workload.name)
+		}
+		if line, ok := util.ErrorLine(r, fmt.Sprintf(util.EnvoyFilterConfigPath, i)); ok {
+			m.Line = line
+		}
+		c.Report(collections.IstioNetworkingV1Alpha3Envoyfilters.Name(), m)
+	}
+}
+
+// analyzable returns true if the effect of the patch can be found by applying it to the generated config. The
+// resources added to the config and the patches of the bootstrap and extension configs are not checked.
+func analyzable(cpw *model.EnvoyFilterConfigPatchWrapper) bool {
+	switch cpw.ApplyTo {
+	case network.EnvoyFilter_LISTENER, network.EnvoyFilter_CLUSTER, network.EnvoyFilter_ROUTE_CONFIGURATION:
+		return cpw.Operation != network.EnvoyFilter_Patch_ADD
+	case network.EnvoyFilter_FILTER_CHAIN, network.EnvoyFilter_NETWORK_FILTER, network.EnvoyFilter_HTTP_FILTER,
+		network.EnvoyFilter_LISTENER_FILTER, network.EnvoyFilter_VIRTUAL_HOST, network.EnvoyFilter_HTTP_ROUTE:
+		return true
+	default:
+		return false
+	}
+}
+
+// applies returns true if the patch changes the config generated for the workload. The patch is applied to a
+// copy of the config with the functions used by istiod.
+func applies(g *generated, cpw *model.EnvoyFilterConfigPatchWrapper) bool {
+	// The patch functions modify the value of the patch, e.g. to set the type URL of a merged filter
+	cpw = withValue(cpw, proto.Clone(cpw.Value))
+	efw := &model.EnvoyFilterWrapper{
+		Name:      cpw.Name,
+		Namespace: cpw.Namespace,
+		Patches:   map[network.EnvoyFilter_ApplyTo][]*model.EnvoyFilterConfigPatchWrapper{cpw.ApplyTo: {cpw}},
+	}
+	switch cpw.ApplyTo {
+	case network.EnvoyFilter_CLUSTER:
+		for _, c := range g.clusters {
+			pctx := patchContext(g.proxy, strings.HasPrefix(c.Name, string(model.TrafficDirectionInbound)))
+			hosts := clusterHosts(c.Name)
+			if !efpatch.ShouldKeepCluster(pctx, efw, c, hosts) {
+				return true
+			}
+			if !proto.Equal(efpatch.ApplyClusterMerge(pctx, efw, proto.Clone(c).(*cluster.Cluster), hosts), c) {
+				return true
+			}
+		}
+	case network.EnvoyFilter_ROUTE_CONFIGURATION, network.EnvoyFilter_VIRTUAL_HOST, network.EnvoyFilter_HTTP_ROUTE:
+		for _, rc := range g.routes {
+			pctx := patchContext(g.proxy, strings.HasPrefix(rc.Name, string(model.TrafficDirectionInbound)))
+			patched := efpatch.ApplyRouteConfigurationPatches(pctx, g.proxy, efw, proto.Clone(rc).(*route.RouteConfiguration))
+			if !proto.Equal(patched, rc) {
+				return true
+			}
+		}
+	default:
+		for _, l := range g.listeners {
+			pctx := patchContext(g.proxy, l.TrafficDirection == core.TrafficDirection_INBOUND)
+			patched := efpatch.ApplyListenerPatches(pctx, efw, []*listener.Listener{proto.Clone(l).(*listener.Listener)}, true)
+			if len(patched) != 1 || !proto.Equal(patched[0], l) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeError returns the error of merging the typed config of a MERGE patch of a filter into a filter it
+// applies to. Istiod keeps the config of the filter when the merge fails.
+func mergeError(g *generated, cpw *model.EnvoyFilterConfigPatchWrapper) (string, string, error) {
+	var typedConfig *anypb.Any
+	switch v := cpw.Value.(type) {
+	case *listener.Filter:
+		typedConfig = v.GetTypedConfig()
+	case *hcm.HttpFilter:
+		typedConfig = v.GetTypedConfig()
+	}
+	if typedConfig == nil {
+		return "", "", nil
+	}
+	for _, f := range mergedFilters(g, cpw) {
+		src := proto.Clone(typedConfig).(*anypb.Any)
+		// Istiod replaces the type URL of the patch with the type URL of the filter
+		src.TypeUrl = f.typedConfig.TypeUrl
+		if _, err := networkingutil.MergeAnyWithAny(f.typedConfig, src); err != nil {
+			return f.name, f.typedConfig.TypeUrl, err
+		}
+	}
+	return "", "", nil
+}
+
+type filter struct {
+	name        string
+	typedConfig *anypb.Any
+}
+
+// mergedFilters returns the filters with a typed config a MERGE patch applies to. They are the filters
+// removed by a REMOVE patch with the same match.
+func mergedFilters(g *generated, cpw *model.EnvoyFilterConfigPatchWrapper) []filter {
+	remove := withValue(cpw, cpw.Value)
+	remove.Operation = network.EnvoyFilter_Patch_REMOVE
+	efw := &model.EnvoyFilterWrapper{
+		Name:      cpw.Name,
+		Namespace: cpw.Namespace,
+		Patches:   map[network.EnvoyFilter_ApplyTo][]*model.EnvoyFilterConfigPatchWrapper{cpw.ApplyTo: {remove}},
+	}
+	var out []filter
+	for _, l := range g.listeners {
+		pctx := patchContext(g.proxy, l.TrafficDirection == core.TrafficDirection_INBOUND)
+		patched := efpatch.ApplyListenerPatches(pctx, efw, []*listener.Listener{proto.Clone(l).(*listener.Listener)}, true)
+		if len(patched) != 1 {
+			continue
+		}
+		after := filters(patched[0], cpw.ApplyTo)
+		for key, f := range filters(l, cpw.ApplyTo) {
+			if _, f2 := after[key]; !f2 && f.typedConfig != nil {
+				out = append(out, f)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].name < out[j].name
+	})
+	return out
+}
+
+// filters returns the network or HTTP filters of the listener, keyed by their position in the listener.
+func filters(l *listener.Listener, applyTo network.EnvoyFilter_ApplyTo) map[string]filter {
+	out := map[string]filter{}
+	for i, fc := range l.FilterChains {
+		for _, f := range fc.Filters {
+			key := fmt.Sprintf("%d/%s", i, f.Name)
+			if applyTo == network.EnvoyFilter_NETWORK_FILTER {
+				out[key] = filter{name: f.Name, typedConfig: f.GetTypedConfig()}
+				continue
+			}
+			if f.Name != wellknown.HTTPConnectionManager || f.GetTypedConfig() == nil {
+				continue
+			}
+			hc := &hcm.HttpConnectionManager{}
+			if err := f.GetTypedConfig().UnmarshalTo(hc); err != nil {
+				continue
+			}
+			for _, hf := range hc.HttpFilters {
+				out[key+"/"+hf.Name] = filter{name: hf.Name, typedConfig: hf.GetTypedConfig()}
+			}
+		}
+	}
+	return out
+}
+
+// relativeFilter returns the name of the filter an HTTP filter is inserted before or after.
+func relativeFilter(cpw *model.EnvoyFilterConfigPatchWrapper) string {
+	if cpw.ApplyTo != network.EnvoyFilter_HTTP_FILTER || (cpw.Operation != network.EnvoyFilter_Patch_INSERT_BEFORE &&
+		cpw.Operation != network.EnvoyFilter_Patch_INSERT_AFTER) {
+		return ""
+	}
+	return cpw.Match.GetListener().GetFilterChain().GetFilter().GetSubFilter().GetName()
+}
+
+// withoutRelativeFilter returns the patch without the filter an HTTP filter is inserted before or after.
+func withoutRelativeFilter(cpw *model.EnvoyFilterConfigPatchWrapper) *model.EnvoyFilterConfigPatchWrapper {
+	out := withValue(cpw, cpw.Value)
+	out.Match = proto.Clone(cpw.Match).(*network.EnvoyFilter_EnvoyConfigObjectMatch)
+	out.Match.GetListener().GetFilterChain().GetFilter().SubFilter = nil
+	return out
+}
+
+func withValue(cpw *model.EnvoyFilterConfigPatchWrapper, value proto.Message) *model.EnvoyFilterConfigPatchWrapper {
+	out := *cpw
+	out.Value = value
+	return &out
+}
+
+func patchContext(proxy *model.Proxy, inbound bool) network.EnvoyFilter_PatchContext {
+	switch {
+	case proxy.Type == model.Router:
+		return network.EnvoyFilter_GATEWAY
+	case inbound:
+		return network.EnvoyFilter_SIDECAR_INBOUND
+	default:
+		return network.EnvoyFilter_SIDECAR_OUTBOUND
+	}
+}
+
+// clusterHosts returns the hosts of the service of the cluster, as matched by the cluster patches.
+func clusterHosts(name string) []host.Name {
+	_, _, hostname, _ := model.ParseSubsetKey(name)
+	if hostname == "" {
+		return nil
+	}
+	return []host.Name{hostname}
+}
+
+// generate generates the config of the workload.
+func generate(w *workload, configs []config.Config, services []v1.Service, mc *meshconfig.MeshConfig) (*generated, error) {
+	var svcs []*model.Service
+	var instances []*model.ServiceInstance
+	for _, s := range services {
+		svc := kube.ConvertService(s, constants.DefaultClusterLocalDomain, provider.Mock)
+		svcs = append(svcs, svc)
+		if s.Namespace != w.namespace || len(s.Spec.Selector) == 0 ||
+			!klabels.SelectorFromSet(s.Spec.Selector).Matches(klabels.Set(w.labels)) {
+			continue
+		}
+		for i := range s.Spec.Ports {
+			port := &s.Spec.Ports[i]
+			target, err := controller.FindPort(w.pod, port)
+			if err != nil {
+				continue
+			}
+			instances = append(instances, &model.ServiceInstance{
+				Service:     svc,
+				ServicePort: svc.Ports[i],
+				Endpoint: &model.IstioEndpoint{
+					Address:         workloadIP,
+					EndpointPort:    uint32(target),
+					ServicePortName: port.Name,
+					Labels:          w.labels,
+					Namespace:       w.namespace,
+				},
+			})
+		}
+	}
+	cg, err := newConfigGenerator(configs, svcs, instances, mc)
+	if err != nil {
+		return nil, err
+	}
+	defer cg.close()
+	proxy := cg.setupProxy(&model.Proxy{
+		Type:            w.typ,
+		ConfigNamespace: w.namespace,
+		Labels:          w.labels,
+		IPAddresses:     []string{workloadIP},
+		Metadata: &model.NodeMetadata{
+			Labels:       w.labels,
+			Namespace:    w.namespace,
+			IstioVersion: w.version,
+		},
+	})
+	listeners := cg.listeners(proxy)
+	clusters, err := cg.clusters(proxy)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := cg.routes(proxy, listeners)
+	if err != nil {
+		return nil, err
+	}
+	return &generated{
+		workload:  w,
+		proxy:     proxy,
+		listeners: listeners,
+		clusters:  clusters,
+		routes:    routes,
+	}, nil
+}
+
+// workloadsFor returns the workloads of the pods with a proxy.
+func workloadsFor(c analysis.Context) []*workload {
+	var out []*workload
+	seen := map[string]bool{}
+	c.ForEach(collections.K8SCoreV1Pods.Name(), func(r *resource.Instance) bool {
+		spec := r.Message.(*v1.PodSpec)
+		w := &workload{
+			name:      r.Metadata.FullName.String(),
+			namespace: r.Metadata.FullName.Namespace.String(),
+			labels:    r.Metadata.Labels,
+			typ:       model.SidecarProxy,
+			pod: &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      r.Metadata.FullName.Name.String(),
+					Namespace: r.Metadata.FullName.Namespace.String(),
+					Labels:    r.Metadata.Labels,
+				},
+				Spec: *spec,
+			},
+		}
+		proxy := false
+		for _, container := range spec.Containers {
+			if container.Name != util.IstioProxyName {
+				continue
+			}
+			proxy = true
+			for _, arg := range container.Args {
+				if arg == string(model.Router) {
+					w.typ = model.Router
+				}
+			}
+			w.version = util.ProxyImageVersion(container.Image)
+		}
+		if !proxy {
+			return true
+		}
+		key := fmt.Sprintf("%s/%s/%s/%s", w.namespace, klabels.Set(w.labels).String(), w.typ, w.version)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, w)
+		}
+		return true
+	})
+	return out
+}
+
+// servicesFor returns the Kubernetes services.
+func servicesFor(c analysis.Context) []v1.Service {
+	var out []v1.Service
+	c.ForEach(collections.K8SCoreV1Services.Name(), func(r *resource.Instance) bool {
+		out = append(out, v1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        r.Metadata.FullName.Name.String(),
+				Namespace:   r.Metadata.FullName.Namespace.String(),
+				Labels:      r.Metadata.Labels,
+				Annotations: r.Metadata.Annotations,
+			},
+			Spec: *r.Message.(*v1.ServiceSpec),
+		})
+		return true
+	})
+	return out
+}
+
+// selectedWorkloads returns the workloads selected by the EnvoyFilter.
+func selectedWorkloads(r *resource.Instance, workloads []*workload, rootNamespace string) []*workload {
+	ef := r.Message.(*network.EnvoyFilter)
+	namespace := r.Metadata.FullName.Namespace.String()
+	selector := klabels.SelectorFromSet(ef.GetWorkloadSelector().GetLabels())
+	var out []*workload
+	for _, w := range workloads {
+		if namespace != rootNamespace && namespace != w.namespace {
+			continue
+		}
+		if selector.Matches(klabels.Set(w.labels)) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func toConfig(r *resource.Instance, s collection.Schema) config.Config {
+	return config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: s.Resource().GroupVersionKind(),
+			Name:             r.Metadata.FullName.Name.String(),
+			Namespace:        r.Metadata.FullName.Namespace.String(),
+			Labels:           r.Metadata.Labels,
+			Annotations:      r.Metadata.Annotations,
+		},
+		Spec: r.Message,
+	}
+}
@@ -15,7 +15,6 @@
 package telemetry
 
 import (
-	"istio.io/api/mesh/v1alpha1"
 	telemetryapi "istio.io/api/telemetry/v1alpha1"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/util"
@@ -43,7 +42,7 @@ func (a *ProdiverAnalyzer) Metadata() analysis.Metadata {
 
 // Analyze implements Analyzer
 func (a *ProdiverAnalyzer) Analyze(c analysis.Context) {
-	meshConfig := fetchMeshConfig(c)
+	meshConfig := util.FetchMeshConfig(c)
 	if meshConfig.DefaultProviders == nil ||
 		len(meshConfig.DefaultProviders.AccessLogging) == 0 {
 		c.ForEach(collections.IstioTelemetryV1Alpha1Telemetries.Name(), func(r *resource.Instance) bool {
@@ -60,13 +59,3 @@ func (a *ProdiverAnalyzer) Analyze(c analysis.Context) {
 		})
 	}
 }
-
-func fetchMeshConfig(c analysis.Context) *v1alpha1.MeshConfig {
-	var meshConfig *v1alpha1.MeshConfig
-	c.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
-		meshConfig = r.Message.(*v1alpha1.MeshConfig)
-		return r.Metadata.FullName.Name != util.MeshConfigName
-	})
-
-	return meshConfig
-}
@@ -30,6 +30,10 @@ const (
 	// Required parameters: route rule, route rule index, route index.
 	DestinationHost = "{.spec.%s[%d].route[%d].destination.host}"
 
+	// Path for HTTP route name in VirtualService.
+	// Required parameters: http index.
+	HTTPRouteName = "{.spec.http[%d].name}"
+
 	// Path for mirror host in VirtualService.
 	// Required parameters: http index.
 	MirrorHost = "{.spec.http[%d].mirror.host}"
@@ -78,6 +82,18 @@ const (
 	// Required parameters: rule index, from index, namespace index.
 	AuthorizationPolicyNameSpace = "{.spec.rules[%d].from[%d].source.namespaces[%d]}"
 
+	// Path for principal in authorizationPolicy.
+	// Required parameters: rule index, from index, principal index.
+	AuthorizationPolicyPrincipal = "{.spec.rules[%d].from[%d].source.principals[%d]}"
+
+	// Path for not principal in authorizationPolicy.
+	// Required parameters: rule index, from index, not principal index.
+	AuthorizationPolicyNotPrincipal = "{.spec.rules[%d].from[%d].source.notPrincipals[%d]}"
+
+	// Path for provider name in authorizationPolicy.
+	// Required parameters: none.
+	AuthorizationPolicyProviderName = "{.spec.provider.name}"
+
 	// Path for annotation.
 	// Required parameters: annotation name.
 	Annotation = "{.metadata.annotations.%s}"
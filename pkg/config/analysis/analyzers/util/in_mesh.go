@@ -15,6 +15,8 @@
 package util
 
 import (
+	"strings"
+
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 
@@ -96,3 +98,15 @@ func hasIstioProxy(containers []v1.Container) bool {
 
 	return proxyImage != ""
 }
+
+// ProxyImageVersion returns the Istio version of a proxy image, from its tag, or an empty string if the image
+// has no tag. The variant of the image is stripped, e.g. 1.17.1 for proxyv2:1.17.1-distroless.
+func ProxyImageVersion(image string) string {
+	image = image[strings.LastIndex(image, "/")+1:]
+	_, tag, ok := strings.Cut(image, ":")
+	if !ok {
+		return ""
+	}
+	version, _, _ := strings.Cut(tag, "-")
+	return version
+}
@@ -0,0 +1,390 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package virtualservice
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// ShadowedRouteAnalyzer checks for HTTP routes that are never matched, because the matches of earlier routes are a
+// superset of their matches. The routes of delegate virtual services are merged into their root, as done by Istiod.
+type ShadowedRouteAnalyzer struct{}
+
+var _ analysis.Analyzer = &ShadowedRouteAnalyzer{}
+
+// httpRoute is an HTTP route of a virtual service, after merging delegates into their root.
+type httpRoute struct {
+	// r is the virtual service defining the route
+	r     *resource.Instance
+	index int
+	route *v1alpha3.HTTPRoute
+	// matches are the matches of the route merged with the matches of the root route for delegates. A route without
+	// matches has a single empty match.
+	matches []*v1alpha3.HTTPMatchRequest
+}
+
+// Metadata implements Analyzer
+func (a *ShadowedRouteAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "virtualservice.ShadowedRouteAnalyzer",
+		Description: "Checks for HTTP routes shadowed by earlier routes",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+		},
+	}
+}
+
+// Analyze implements Analyzer
+func (a *ShadowedRouteAnalyzer) Analyze(ctx analysis.Context) {
+	// A delegate can be used by several roots, report its routes once
+	reported := map[string]bool{}
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), func(r *resource.Instance) bool {
+		vs := r.Message.(*v1alpha3.VirtualService)
+		// Delegates are analyzed as part of their root
+		if len(vs.GetHosts()) == 0 {
+			return true
+		}
+		a.analyzeRoutes(ctx, mergedRoutes(ctx, r), reported)
+		return true
+	})
+}
+
+func (a *ShadowedRouteAnalyzer) analyzeRoutes(ctx analysis.Context, routes []httpRoute, reported map[string]bool) {
+	for i, route := range routes {
+		if reportedByValidation(routes[:i], route) {
+			continue
+		}
+		shadowing := shadowingRoutes(routes[:i], route)
+		if len(shadowing) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(shadowing))
+		for _, s := range shadowing {
+			names = append(names, routeRef(s, route.r))
+		}
+		m := msg.NewVirtualServiceShadowedRoute(route.r, routeRef(route, route.r), strings.Join(names, ", "))
+		if reported[m.String()] {
+			continue
+		}
+		reported[m.String()] = true
+
+		if line, ok := routeLine(route); ok {
+			m.Line = line
+		}
+
+		ctx.Report(collections.IstioNetworkingV1Alpha3Virtualservices.Name(), m)
+	}
+}
+
+// mergedRoutes returns the HTTP routes of a virtual service, with the routes delegating to another virtual service
+// replaced by the routes of the delegate.
+func mergedRoutes(ctx analysis.Context, r *resource.Instance) []httpRoute {
+	vs := r.Message.(*v1alpha3.VirtualService)
+	var out []httpRoute
+	for i, route := range vs.GetHttp() {
+		delegate := route.GetDelegate()
+		if delegate == nil {
+			out = append(out, httpRoute{r: r, index: i, route: route, matches: routeMatches(route)})
+			continue
+		}
+		ns := delegate.GetNamespace()
+		if ns == "" {
+			ns = r.Metadata.FullName.Namespace.String()
+		}
+		dr := ctx.Find(collections.IstioNetworkingV1Alpha3Virtualservices.Name(),
+			resource.NewFullName(resource.Namespace(ns), resource.LocalName(delegate.GetName())))
+		if dr == nil {
+			// Istiod ignores routes with a missing delegate
+			continue
+		}
+		for j, child := range dr.Message.(*v1alpha3.VirtualService).GetHttp() {
+			matches, ok := mergeMatches(routeMatches(route), routeMatches(child))
+			if !ok {
+				// Istiod ignores delegate routes conflicting with the root route
+				continue
+			}
+			out = append(out, httpRoute{r: dr, index: j, route: child, matches: matches})
+		}
+	}
+	return out
+}
+
+func routeMatches(route *v1alpha3.HTTPRoute) []*v1alpha3.HTTPMatchRequest {
+	if len(route.GetMatch()) == 0 {
+		return []*v1alpha3.HTTPMatchRequest{{}}
+	}
+	return route.GetMatch()
+}
+
+// mergeMatches merges the matches of a delegate route with the matches of its root route. Every match of the
+// delegate must be covered by a match of the root.
+func mergeMatches(root, delegate []*v1alpha3.HTTPMatchRequest) ([]*v1alpha3.HTTPMatchRequest, bool) {
+	var out []*v1alpha3.HTTPMatchRequest
+	for _, d := range delegate {
+		found := false
+		for _, r := range root {
+			merged := mergeMatch(r, d)
+			if matchContains(r, merged) {
+				out = append(out, merged)
+				found = true
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return out, true
+}
+
+func mergeMatch(root, delegate *v1alpha3.HTTPMatchRequest) *v1alpha3.HTTPMatchRequest {
+	out := proto.Clone(delegate).(*v1alpha3.HTTPMatchRequest)
+	if out.Uri == nil {
+		out.Uri = root.Uri
+	}
+	if out.Scheme == nil {
+		out.Scheme = root.Scheme
+	}
+	if out.Method == nil {
+		out.Method = root.Method
+	}
+	if out.Authority == nil {
+		out.Authority = root.Authority
+	}
+	out.Headers = mergeStringMatches(root.Headers, out.Headers)
+	out.WithoutHeaders = mergeStringMatches(root.WithoutHeaders, out.WithoutHeaders)
+	out.QueryParams = mergeStringMatches(root.QueryParams, out.QueryParams)
+	if out.Port == 0 {
+		out.Port = root.Port
+	}
+	for k, v := range root.SourceLabels {
+		if _, f := out.SourceLabels[k]; !f {
+			if out.SourceLabels == nil {
+				out.SourceLabels = map[string]string{}
+			}
+			out.SourceLabels[k] = v
+		}
+	}
+	if out.SourceNamespace == "" {
+		out.SourceNamespace = root.SourceNamespace
+	}
+	if len(out.Gateways) == 0 {
+		out.Gateways = root.Gateways
+	}
+	return out
+}
+
+func mergeStringMatches(root, delegate map[string]*v1alpha3.StringMatch) map[string]*v1alpha3.StringMatch {
+	if len(root) == 0 {
+		return delegate
+	}
+	out := make(map[string]*v1alpha3.StringMatch, len(root)+len(delegate))
+	for k, v := range root {
+		out[k] = v
+	}
+	for k, v := range delegate {
+		out[k] = v
+	}
+	return out
+}
+
+// shadowingRoutes returns the earlier routes covering the matches of a route, or nil if one of its matches is not
+// covered.
+func shadowingRoutes(earlier []httpRoute, route httpRoute) []httpRoute {
+	var out []httpRoute
+	seen := map[int]bool{}
+	for _, m := range route.matches {
+		found := false
+		for i, e := range earlier {
+			if routeContains(e, m) {
+				if !seen[i] {
+					seen[i] = true
+					out = append(out, e)
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+	return out
+}
+
+func routeContains(route httpRoute, m *v1alpha3.HTTPMatchRequest) bool {
+	for _, rm := range route.matches {
+		if matchContains(rm, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// reportedByValidation returns true if the route is already reported as unreachable by the validation of its
+// virtual service, because its matches duplicate the matches of earlier routes of the same virtual service.
+func reportedByValidation(earlier []httpRoute, route httpRoute) bool {
+	for _, m := range route.route.GetMatch() {
+		found := false
+		for _, e := range earlier {
+			if e.r != route.r {
+				continue
+			}
+			for _, em := range e.route.GetMatch() {
+				if proto.Equal(em, m) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(route.route.GetMatch()) > 0 {
+		return true
+	}
+	for _, e := range earlier {
+		if e.r == route.r && len(e.route.GetMatch()) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchContains returns true if every request matched by b is matched by a.
+func matchContains(a, b *v1alpha3.HTTPMatchRequest) bool {
+	if a.GetUri() != nil && b.GetIgnoreUriCase() && !a.GetIgnoreUriCase() {
+		return false
+	}
+	if !stringMatchContains(a.GetUri(), b.GetUri(), a.GetIgnoreUriCase()) ||
+		!stringMatchContains(a.GetScheme(), b.GetScheme(), false) ||
+		!stringMatchContains(a.GetMethod(), b.GetMethod(), false) ||
+		!stringMatchContains(a.GetAuthority(), b.GetAuthority(), false) {
+		return false
+	}
+	for k, h := range a.GetHeaders() {
+		if !stringMatchContains(h, b.GetHeaders()[k], false) {
+			return false
+		}
+	}
+	for k, h := range a.GetWithoutHeaders() {
+		if !proto.Equal(h, b.GetWithoutHeaders()[k]) {
+			return false
+		}
+	}
+	for k, q := range a.GetQueryParams() {
+		if !stringMatchContains(q, b.GetQueryParams()[k], false) {
+			return false
+		}
+	}
+	if a.GetPort() != 0 && a.GetPort() != b.GetPort() {
+		return false
+	}
+	for k, v := range a.GetSourceLabels() {
+		if bv, f := b.GetSourceLabels()[k]; !f || bv != v {
+			return false
+		}
+	}
+	if a.GetSourceNamespace() != "" && a.GetSourceNamespace() != b.GetSourceNamespace() {
+		return false
+	}
+	if len(a.GetGateways()) > 0 {
+		if len(b.GetGateways()) == 0 {
+			return false
+		}
+		gateways := sets.New(a.GetGateways()...)
+		for _, gw := range b.GetGateways() {
+			if !gateways.Contains(gw) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stringMatchContains returns true if every value matched by b is matched by a. A nil match matches any value, and a
+// match without a type, as used for headers, matches any present value.
+func stringMatchContains(a, b *v1alpha3.StringMatch, ignoreCase bool) bool {
+	if a == nil {
+		return true
+	}
+	if b == nil {
+		return false
+	}
+	if a.GetMatchType() == nil {
+		return true
+	}
+	normalize := func(s string) string {
+		if ignoreCase {
+			return strings.ToLower(s)
+		}
+		return s
+	}
+	switch am := a.GetMatchType().(type) {
+	case *v1alpha3.StringMatch_Exact:
+		bm, ok := b.GetMatchType().(*v1alpha3.StringMatch_Exact)
+		return ok && normalize(bm.Exact) == normalize(am.Exact)
+	case *v1alpha3.StringMatch_Prefix:
+		switch bm := b.GetMatchType().(type) {
+		case *v1alpha3.StringMatch_Exact:
+			return strings.HasPrefix(normalize(bm.Exact), normalize(am.Prefix))
+		case *v1alpha3.StringMatch_Prefix:
+			return strings.HasPrefix(normalize(bm.Prefix), normalize(am.Prefix))
+		}
+		return am.Prefix == ""
+	case *v1alpha3.StringMatch_Regex:
+		switch bm := b.GetMatchType().(type) {
+		case *v1alpha3.StringMatch_Exact:
+			// Envoy regexes match the full value
+			re, err := regexp.Compile("^(?:" + am.Regex + ")$")
+			return err == nil && !ignoreCase && re.MatchString(bm.Exact)
+		case *v1alpha3.StringMatch_Regex:
+			return bm.Regex == am.Regex
+		}
+		return am.Regex == ".*"
+	}
+	return false
+}
+
+// routeRef returns a reference to a route, reported on the virtual service r.
+func routeRef(route httpRoute, r *resource.Instance) string {
+	ref := fmt.Sprintf("#%d", route.index)
+	if name := route.route.GetName(); name != "" {
+		ref += fmt.Sprintf(" (%q)", name)
+	}
+	if route.r != r {
+		ref += " of VirtualService " + route.r.Metadata.FullName.String()
+	}
+	return ref
+}
+
+func routeLine(route httpRoute) (int, bool) {
+	if line, ok := util.ErrorLine(route.r, fmt.Sprintf(util.HTTPRouteName, route.index)); ok {
+		return line, true
+	}
+	return util.ErrorLine(route.r, fmt.Sprintf(util.DestinationHost, "http", route.index, 0))
+}
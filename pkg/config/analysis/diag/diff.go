@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diag
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DiffStatus describes how a message differs between two analyses.
+type DiffStatus string
+
+const (
+	// DiffNew is a message only reported by the new analysis.
+	DiffNew DiffStatus = "new"
+	// DiffResolved is a message only reported by the old analysis.
+	DiffResolved DiffStatus = "resolved"
+	// DiffChanged is a message reported by both analyses with the same code for the same resource, but with a
+	// different level or text.
+	DiffChanged DiffStatus = "changed"
+)
+
+// MessageDiff is a message that differs between two analyses.
+type MessageDiff struct {
+	Status DiffStatus
+
+	// Message is the message of the new analysis, or the message of the old analysis if it was resolved.
+	Message Message
+
+	// Previous is the message of the old analysis, for changed messages.
+	Previous *Message
+}
+
+// MarshalJSON satisfies the Marshaler interface
+func (d *MessageDiff) MarshalJSON() ([]byte, error) {
+	result := map[string]any{
+		"status":  d.Status,
+		"message": d.Message.Unstructured(true),
+	}
+	if d.Previous != nil {
+		result["previous"] = d.Previous.Unstructured(true)
+	}
+	return json.Marshal(result)
+}
+
+// Diff returns the messages that are new, resolved or changed in the messages of an analysis, compared to the
+// messages of a previous analysis. Messages are matched by code, resource, level and text; the location of the
+// resource is ignored, so that moving a resource within or across files does not report its messages again.
+func Diff(before, after Messages) []MessageDiff {
+	b := before.SortedDedupedCopy()
+	a := after.SortedDedupedCopy()
+
+	unmatched := map[string]int{}
+	for _, m := range b {
+		unmatched[diffKey(m)]++
+	}
+	var added Messages
+	for _, m := range a {
+		if k := diffKey(m); unmatched[k] > 0 {
+			unmatched[k]--
+			continue
+		}
+		added = append(added, m)
+	}
+	var removed Messages
+	for _, m := range b {
+		if k := diffKey(m); unmatched[k] > 0 {
+			unmatched[k]--
+			removed = append(removed, m)
+		}
+	}
+
+	// Pair the remaining messages of both analyses with the same code for the same resource
+	removedByResource := map[string][]int{}
+	for i, m := range removed {
+		k := resourceKey(m)
+		removedByResource[k] = append(removedByResource[k], i)
+	}
+	paired := map[int]bool{}
+	diffs := []MessageDiff{}
+	for _, m := range added {
+		k := resourceKey(m)
+		if idx := removedByResource[k]; len(idx) > 0 {
+			removedByResource[k] = idx[1:]
+			paired[idx[0]] = true
+			prev := removed[idx[0]]
+			diffs = append(diffs, MessageDiff{Status: DiffChanged, Message: m, Previous: &prev})
+			continue
+		}
+		diffs = append(diffs, MessageDiff{Status: DiffNew, Message: m})
+	}
+	for i, m := range removed {
+		if !paired[i] {
+			diffs = append(diffs, MessageDiff{Status: DiffResolved, Message: m})
+		}
+	}
+	return diffs
+}
+
+// resourceKey identifies the code and resource of a message.
+func resourceKey(m Message) string {
+	if m.Resource == nil {
+		return m.Type.Code()
+	}
+	return m.Type.Code() + "/" + m.Resource.Origin.Comparator()
+}
+
+// diffKey identifies a message, regardless of the location of its resource.
+func diffKey(m Message) string {
+	return fmt.Sprintf("%s/%v/%s", resourceKey(m), m.Type.Level(), fmt.Sprintf(m.Type.Template(), m.Parameters...))
+}
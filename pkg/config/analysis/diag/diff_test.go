@@ -0,0 +1,47 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diag
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDiff(t *testing.T) {
+	g := NewWithT(t)
+
+	unchanged := NewMessage(NewMessageType(Error, "A1", "Template: %q"), MockResource("A"), "A")
+	resolved := NewMessage(NewMessageType(Error, "B1", "Template: %q"), MockResource("A"), "A")
+	changedBefore := NewMessage(NewMessageType(Warning, "C1", "Template: %q"), MockResource("A"), "old")
+	changedAfter := NewMessage(NewMessageType(Warning, "C1", "Template: %q"), MockResource("A"), "new")
+	added := NewMessage(NewMessageType(Error, "B1", "Template: %q"), MockResource("B"), "A")
+
+	// The same message reported at another line is not a difference.
+	moved := unchanged
+	moved.Line = 10
+
+	diffs := Diff(
+		Messages{unchanged, resolved, changedBefore},
+		Messages{changedAfter, added, moved},
+	)
+	g.Expect(diffs).To(Equal([]MessageDiff{
+		{Status: DiffNew, Message: added},
+		{Status: DiffChanged, Message: changedAfter, Previous: &changedBefore},
+		{Status: DiffResolved, Message: resolved},
+	}))
+
+	g.Expect(Diff(Messages{unchanged}, Messages{unchanged})).To(BeEmpty())
+}
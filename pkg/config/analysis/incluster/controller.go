@@ -38,16 +38,18 @@ import (
 )
 
 // Controller manages repeatedly running analyzers in istiod, and reporting results
-// via istio status fields.
+// via istio status fields, and optionally via Kubernetes Events and metrics.
 type Controller struct {
 	analyzer  *local.IstiodAnalyzer
 	statusctl *status.Controller
+	// events is nil unless PILOT_ENABLE_ANALYSIS_EVENTS is set
+	events *eventReporter
 }
 
 func NewController(stop <-chan struct{}, rwConfigStore model.ConfigStoreController,
 	kubeClient kube.Client, revision, namespace string, statusManager *status.Manager, domainSuffix string,
 ) (*Controller, error) {
-	ia := local.NewIstiodAnalyzer(analyzers.AllCombined(),
+	ia := local.NewIstiodAnalyzer(analyzers.InClusterCombined(),
 		"", resource.Namespace(namespace), func(name collection.Name) {}, true)
 	ia.AddSource(rwConfigStore)
 	// Filter out configs watched by rwConfigStore so we don't watch multiple times
@@ -72,13 +74,18 @@ func NewController(stop <-chan struct{}, rwConfigStore model.ConfigStoreControll
 		}
 		return status
 	})
-	return &Controller{analyzer: ia, statusctl: ctl}, nil
+	c := &Controller{analyzer: ia, statusctl: ctl}
+	if features.EnableAnalysisEvents {
+		c.events = newEventReporter(kubeClient, stop)
+	}
+	return c, nil
 }
 
 // Run is blocking
 func (c *Controller) Run(stop <-chan struct{}) {
 	t := time.NewTicker(features.AnalysisInterval)
 	oldmsgs := diag.Messages{}
+	var counts map[metricKey]int
 	for {
 		select {
 		case <-t.C:
@@ -110,6 +117,12 @@ func (c *Controller) Run(stop <-chan struct{}) {
 					c.statusctl.EnqueueStatusUpdateResource(m, r)
 				}
 			}
+			if c.events != nil {
+				c.events.Report(res.Messages)
+			}
+			if features.EnableAnalysisMetrics {
+				counts = recordMessageMetrics(res.Messages, counts)
+			}
 			oldmsgs = res.Messages
 			log.Debugf("finished enqueueing all statuses")
 		case <-stop:
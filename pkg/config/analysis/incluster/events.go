@@ -0,0 +1,81 @@
+/*
+ Copyright Istio Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package incluster
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// eventReporter emits a Kubernetes Event on the object of each analysis message. An Event is only emitted
+// when a message is first reported; it is emitted again only if the message is resolved and reported anew.
+type eventReporter struct {
+	recorder record.EventRecorder
+	// reported are the keys of the messages of the last analysis
+	reported sets.String
+}
+
+func newEventReporter(kubeClient kube.Client, stop <-chan struct{}) *eventReporter {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.Kube().CoreV1().Events("")})
+	go func() {
+		<-stop
+		broadcaster.Shutdown()
+	}()
+	return &eventReporter{
+		recorder: broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: "istio-analysis"}),
+		reported: sets.New[string](),
+	}
+}
+
+// Report emits an Event for each of the messages that was not reported by the previous analysis.
+func (r *eventReporter) Report(msgs diag.Messages) {
+	reported := sets.New[string]()
+	for i := range msgs {
+		m := &msgs[i]
+		if m.Resource == nil {
+			continue
+		}
+		meta := m.Resource.Metadata
+		text := fmt.Sprintf(m.Type.Template(), m.Parameters...)
+		key := fmt.Sprintf("%s/%s/%s/%s", meta.Schema.GroupVersionKind(), meta.FullName, m.Type.Code(), text)
+		reported.Insert(key)
+		if r.reported.Contains(key) {
+			continue
+		}
+		eventType := corev1.EventTypeWarning
+		if m.Type.Level() == diag.Info {
+			eventType = corev1.EventTypeNormal
+		}
+		ref := &corev1.ObjectReference{
+			Kind:       meta.Schema.Kind(),
+			APIVersion: meta.Schema.GroupVersionKind().GroupVersion(),
+			Namespace:  meta.FullName.Namespace.String(),
+			Name:       meta.FullName.Name.String(),
+		}
+		r.recorder.Eventf(ref, eventType, m.Type.Code(), "%s: %s", m.Type.Level(), text)
+	}
+	r.reported = reported
+}
@@ -0,0 +1,82 @@
+/*
+ Copyright Istio Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package incluster
+
+import (
+	"testing"
+
+	"k8s.io/client-go/tools/record"
+
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func virtualService(namespace, name string) *resource.Instance {
+	return &resource.Instance{
+		Metadata: resource.Metadata{
+			Schema:   collections.IstioNetworkingV1Alpha3Virtualservices.Resource(),
+			FullName: resource.NewFullName(resource.Namespace(namespace), resource.LocalName(name)),
+		},
+	}
+}
+
+func TestEventReporter(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	r := &eventReporter{recorder: recorder, reported: sets.New[string]()}
+	events := func() []string {
+		var out []string
+		for len(recorder.Events) > 0 {
+			out = append(out, <-recorder.Events)
+		}
+		return out
+	}
+
+	a := msg.NewReferencedResourceNotFound(virtualService("default", "a"), "host", "reviews")
+	b := msg.NewReferencedResourceNotFound(virtualService("default", "b"), "host", "ratings")
+	r.Report(diag.Messages{a})
+	assert.Equal(t, events(), []string{"Warning IST0101 Error: Referenced host not found: \"reviews\""})
+
+	// Messages already reported are not emitted again
+	r.Report(diag.Messages{a, b})
+	assert.Equal(t, events(), []string{"Warning IST0101 Error: Referenced host not found: \"ratings\""})
+
+	// Resolved messages are emitted again when they are reported anew
+	r.Report(diag.Messages{b})
+	assert.Equal(t, len(events()), 0)
+	r.Report(diag.Messages{a, b})
+	assert.Equal(t, events(), []string{"Warning IST0101 Error: Referenced host not found: \"reviews\""})
+}
+
+func TestRecordMessageMetrics(t *testing.T) {
+	a := msg.NewReferencedResourceNotFound(virtualService("default", "a"), "host", "reviews")
+	b := msg.NewReferencedResourceNotFound(virtualService("default", "b"), "host", "ratings")
+	c := msg.NewReferencedResourceNotFound(virtualService("other", "c"), "host", "ratings")
+
+	counts := recordMessageMetrics(diag.Messages{a, b, c}, nil)
+	assert.Equal(t, counts, map[metricKey]int{
+		{code: "IST0101", namespace: "default", level: "Error"}: 2,
+		{code: "IST0101", namespace: "other", level: "Error"}:   1,
+	})
+	counts = recordMessageMetrics(diag.Messages{c}, counts)
+	assert.Equal(t, counts, map[metricKey]int{
+		{code: "IST0101", namespace: "other", level: "Error"}: 1,
+	})
+}
@@ -0,0 +1,68 @@
+/*
+ Copyright Istio Authors
+
+ Licensed under the Apache License, Version 2.0 (the "License");
+ you may not use this file except in compliance with the License.
+ You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+ Unless required by applicable law or agreed to in writing, software
+ distributed under the License is distributed on an "AS IS" BASIS,
+ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ See the License for the specific language governing permissions and
+ limitations under the License.
+*/
+
+package incluster
+
+import (
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	codeTag      = monitoring.MustCreateLabel("code")
+	namespaceTag = monitoring.MustCreateLabel("namespace")
+	levelTag     = monitoring.MustCreateLabel("level")
+
+	analysisMessages = monitoring.NewGauge(
+		"pilot_analysis_messages",
+		"Number of active analysis messages by code, namespace and level.",
+		monitoring.WithLabels(codeTag, namespaceTag, levelTag),
+	)
+)
+
+func init() {
+	if features.EnableAnalysis && features.EnableAnalysisMetrics {
+		monitoring.MustRegister(analysisMessages)
+	}
+}
+
+type metricKey struct {
+	code, namespace, level string
+}
+
+// recordMessageMetrics records the number of messages of each code, namespace and level. The counts that were
+// recorded for the previous analysis and have no messages anymore are reset to zero. It returns the counts to
+// pass as previous for the next analysis.
+func recordMessageMetrics(msgs diag.Messages, previous map[metricKey]int) map[metricKey]int {
+	counts := map[metricKey]int{}
+	for _, m := range msgs {
+		key := metricKey{code: m.Type.Code(), level: m.Type.Level().String()}
+		if m.Resource != nil {
+			key.namespace = m.Resource.Metadata.FullName.Namespace.String()
+		}
+		counts[key]++
+	}
+	for key := range previous {
+		if _, f := counts[key]; !f {
+			analysisMessages.With(codeTag.Value(key.code), namespaceTag.Value(key.namespace), levelTag.Value(key.level)).Record(0)
+		}
+	}
+	for key, count := range counts {
+		analysisMessages.With(codeTag.Value(key.code), namespaceTag.Value(key.namespace), levelTag.Value(key.level)).Record(float64(count))
+	}
+	return counts
+}
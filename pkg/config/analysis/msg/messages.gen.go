@@ -224,6 +224,38 @@ var (
 	// InvalidTelemetryProvider defines a diag.MessageType for message "InvalidTelemetryProvider".
 	// Description: The Telemetry with empty providers will be ignored
 	InvalidTelemetryProvider = diag.NewMessageType(diag.Warning, "IST0157", "The Telemetry %v in namespace %q with empty providers will be ignored.")
+
+	// VirtualServiceShadowedRoute defines a diag.MessageType for message "VirtualServiceShadowedRoute".
+	// Description: A VirtualService HTTP route will never be matched, because earlier routes match a superset of its requests.
+	VirtualServiceShadowedRoute = diag.NewMessageType(diag.Warning, "IST0158", "HTTP route %v is never matched, because its matches are covered by HTTP route %v.")
+
+	// AuthorizationPolicyAllowRuleDenied defines a diag.MessageType for message "AuthorizationPolicyAllowRuleDenied".
+	// Description: An ALLOW authorization policy rule is never used, because a DENY policy denies all the requests it matches.
+	AuthorizationPolicyAllowRuleDenied = diag.NewMessageType(diag.Warning, "IST0159", "ALLOW rule %v is never used, because the requests it matches are denied by rule %v of %v.")
+
+	// AuthorizationPolicyAllowAllRule defines a diag.MessageType for message "AuthorizationPolicyAllowAllRule".
+	// Description: An ALLOW authorization policy rule matches all requests, making other ALLOW rules for the same workloads ineffective.
+	AuthorizationPolicyAllowAllRule = diag.NewMessageType(diag.Warning, "IST0160", "ALLOW rule %v matches all requests and allows every request to the selected workloads, so %v have no effect.")
+
+	// AuthorizationPolicyUnknownProvider defines a diag.MessageType for message "AuthorizationPolicyUnknownProvider".
+	// Description: A CUSTOM authorization policy references an extension provider that is not defined in the mesh config.
+	AuthorizationPolicyUnknownProvider = diag.NewMessageType(diag.Error, "IST0161", "The CUSTOM policy references the extension provider %q, which is not defined in the mesh config.")
+
+	// AuthorizationPolicyTrustDomainMismatch defines a diag.MessageType for message "AuthorizationPolicyTrustDomainMismatch".
+	// Description: An authorization policy principal uses a trust domain that does not match the trust domain of the mesh.
+	AuthorizationPolicyTrustDomainMismatch = diag.NewMessageType(diag.Warning, "IST0162", "The principal %q uses a trust domain that is neither the trust domain %q of the mesh nor one of its aliases, so it never matches a workload of the mesh.")
+
+	// EnvoyFilterPatchNotMatched defines a diag.MessageType for message "EnvoyFilterPatchNotMatched".
+	// Description: An EnvoyFilter patch does not match the configuration generated for the workloads it selects.
+	EnvoyFilterPatchNotMatched = diag.NewMessageType(diag.Warning, "IST0163", "Patch %v of this EnvoyFilter does not match the configuration of workload %v nor of the other workloads it selects, so it has no effect.")
+
+	// EnvoyFilterPatchInvalidTypedConfig defines a diag.MessageType for message "EnvoyFilterPatchInvalidTypedConfig".
+	// Description: The typed config of an EnvoyFilter MERGE patch cannot be merged into the filter it matches.
+	EnvoyFilterPatchInvalidTypedConfig = diag.NewMessageType(diag.Error, "IST0164", "The typed config of patch %v of this EnvoyFilter cannot be merged into filter %q of type %v: %v")
+
+	// EnvoyFilterRelativeFilterNotFound defines a diag.MessageType for message "EnvoyFilterRelativeFilterNotFound".
+	// Description: An EnvoyFilter patch inserts a filter relative to a filter that does not exist in the configuration of the workloads it selects.
+	EnvoyFilterRelativeFilterNotFound = diag.NewMessageType(diag.Warning, "IST0165", "Patch %v of this EnvoyFilter inserts a filter relative to filter %q, which is not in the configuration of workload %v nor of the other workloads it selects, so it has no effect.")
 )
 
 // All returns a list of all known message types.
@@ -283,6 +315,14 @@ func All() []*diag.MessageType {
 		EnvoyFilterUsesRelativeOperationWithProxyVersion,
 		UnsupportedGatewayAPIVersion,
 		InvalidTelemetryProvider,
+		VirtualServiceShadowedRoute,
+		AuthorizationPolicyAllowRuleDenied,
+		AuthorizationPolicyAllowAllRule,
+		AuthorizationPolicyUnknownProvider,
+		AuthorizationPolicyTrustDomainMismatch,
+		EnvoyFilterPatchNotMatched,
+		EnvoyFilterPatchInvalidTypedConfig,
+		EnvoyFilterRelativeFilterNotFound,
 	}
 }
 
@@ -815,3 +855,86 @@ func NewInvalidTelemetryProvider(r *resource.Instance, name string, namespace st
 		namespace,
 	)
 }
+
+// NewVirtualServiceShadowedRoute returns a new diag.Message based on VirtualServiceShadowedRoute.
+func NewVirtualServiceShadowedRoute(r *resource.Instance, route string, shadowingRoutes string) diag.Message {
+	return diag.NewMessage(
+		VirtualServiceShadowedRoute,
+		r,
+		route,
+		shadowingRoutes,
+	)
+}
+
+// NewAuthorizationPolicyAllowRuleDenied returns a new diag.Message based on AuthorizationPolicyAllowRuleDenied.
+func NewAuthorizationPolicyAllowRuleDenied(r *resource.Instance, rule string, denyRule string, denyPolicy string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyAllowRuleDenied,
+		r,
+		rule,
+		denyRule,
+		denyPolicy,
+	)
+}
+
+// NewAuthorizationPolicyAllowAllRule returns a new diag.Message based on AuthorizationPolicyAllowAllRule.
+func NewAuthorizationPolicyAllowAllRule(r *resource.Instance, rule string, ineffectiveRules string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyAllowAllRule,
+		r,
+		rule,
+		ineffectiveRules,
+	)
+}
+
+// NewAuthorizationPolicyUnknownProvider returns a new diag.Message based on AuthorizationPolicyUnknownProvider.
+func NewAuthorizationPolicyUnknownProvider(r *resource.Instance, provider string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyUnknownProvider,
+		r,
+		provider,
+	)
+}
+
+// NewAuthorizationPolicyTrustDomainMismatch returns a new diag.Message based on AuthorizationPolicyTrustDomainMismatch.
+func NewAuthorizationPolicyTrustDomainMismatch(r *resource.Instance, principal string, trustDomain string) diag.Message {
+	return diag.NewMessage(
+		AuthorizationPolicyTrustDomainMismatch,
+		r,
+		principal,
+		trustDomain,
+	)
+}
+
+// NewEnvoyFilterPatchNotMatched returns a new diag.Message based on EnvoyFilterPatchNotMatched.
+func NewEnvoyFilterPatchNotMatched(r *resource.Instance, patch string, workload string) diag.Message {
+	return diag.NewMessage(
+		EnvoyFilterPatchNotMatched,
+		r,
+		patch,
+		workload,
+	)
+}
+
+// NewEnvoyFilterPatchInvalidTypedConfig returns a new diag.Message based on EnvoyFilterPatchInvalidTypedConfig.
+func NewEnvoyFilterPatchInvalidTypedConfig(r *resource.Instance, patch string, filter string, typeURL string, err string) diag.Message {
+	return diag.NewMessage(
+		EnvoyFilterPatchInvalidTypedConfig,
+		r,
+		patch,
+		filter,
+		typeURL,
+		err,
+	)
+}
+
+// NewEnvoyFilterRelativeFilterNotFound returns a new diag.Message based on EnvoyFilterRelativeFilterNotFound.
+func NewEnvoyFilterRelativeFilterNotFound(r *resource.Instance, patch string, filter string, workload string) diag.Message {
+	return diag.NewMessage(
+		EnvoyFilterRelativeFilterNotFound,
+		r,
+		patch,
+		filter,
+		workload,
+	)
+}
@@ -537,6 +537,24 @@ var (
 		}.MustBuild(),
 	}.MustBuild()
 
+	// K8SGatewayApiV1Alpha2Grpcroutes describes the collection
+	// k8s/gateway_api/v1alpha2/grpcroutes
+	K8SGatewayApiV1Alpha2Grpcroutes = collection.Builder{
+		Name:         "k8s/gateway_api/v1alpha2/grpcroutes",
+		VariableName: "K8SGatewayApiV1Alpha2Grpcroutes",
+		Resource: resource.Builder{
+			Group:   "gateway.networking.k8s.io",
+			Kind:    "GRPCRoute",
+			Plural:  "grpcroutes",
+			Version: "v1alpha2",
+			Proto:   "k8s.io.gateway_api.api.v1alpha1.GRPCRouteSpec", StatusProto: "k8s.io.gateway_api.api.v1alpha1.GRPCRouteStatus",
+			ReflectType: reflect.TypeOf(&sigsk8siogatewayapiapisv1alpha2.GRPCRouteSpec{}).Elem(), StatusType: reflect.TypeOf(&sigsk8siogatewayapiapisv1alpha2.GRPCRouteStatus{}).Elem(),
+			ProtoPackage: "sigs.k8s.io/gateway-api/apis/v1alpha2", StatusPackage: "sigs.k8s.io/gateway-api/apis/v1alpha2",
+			ClusterScoped: false,
+			ValidateProto: validation.ValidateGRPCRoute,
+		}.MustBuild(),
+	}.MustBuild()
+
 	// K8SGatewayApiV1Alpha2Referencegrants describes the collection
 	// k8s/gateway_api/v1alpha2/referencegrants
 	K8SGatewayApiV1Alpha2Referencegrants = collection.Builder{
@@ -683,6 +701,7 @@ var (
 		MustAdd(K8SCoreV1Secrets).
 		MustAdd(K8SCoreV1Services).
 		MustAdd(K8SExtensionsV1Beta1Ingresses).
+		MustAdd(K8SGatewayApiV1Alpha2Grpcroutes).
 		MustAdd(K8SGatewayApiV1Alpha2Referencegrants).
 		MustAdd(K8SGatewayApiV1Alpha2Tcproutes).
 		MustAdd(K8SGatewayApiV1Alpha2Tlsroutes).
@@ -724,6 +743,7 @@ var (
 		MustAdd(K8SCoreV1Secrets).
 		MustAdd(K8SCoreV1Services).
 		MustAdd(K8SExtensionsV1Beta1Ingresses).
+		MustAdd(K8SGatewayApiV1Alpha2Grpcroutes).
 		MustAdd(K8SGatewayApiV1Alpha2Referencegrants).
 		MustAdd(K8SGatewayApiV1Alpha2Tcproutes).
 		MustAdd(K8SGatewayApiV1Alpha2Tlsroutes).
@@ -782,6 +802,7 @@ var (
 			MustAdd(IstioSecurityV1Beta1Peerauthentications).
 			MustAdd(IstioSecurityV1Beta1Requestauthentications).
 			MustAdd(IstioTelemetryV1Alpha1Telemetries).
+			MustAdd(K8SGatewayApiV1Alpha2Grpcroutes).
 			MustAdd(K8SGatewayApiV1Alpha2Referencegrants).
 			MustAdd(K8SGatewayApiV1Alpha2Tcproutes).
 			MustAdd(K8SGatewayApiV1Alpha2Tlsroutes).
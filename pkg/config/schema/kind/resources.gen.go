@@ -15,6 +15,7 @@ const (
 	DestinationRule
 	Endpoints
 	EnvoyFilter
+	GRPCRoute
 	Gateway
 	GatewayClass
 	HTTPRoute
@@ -59,6 +60,8 @@ func (k Kind) String() string {
 		return "Endpoints"
 	case EnvoyFilter:
 		return "EnvoyFilter"
+	case GRPCRoute:
+		return "GRPCRoute"
 	case Gateway:
 		return "Gateway"
 	case GatewayClass:
@@ -138,6 +141,9 @@ func FromGvk(gvk config.GroupVersionKind) Kind {
 	if gvk.Kind == "EnvoyFilter" && gvk.Group == "networking.istio.io" && gvk.Version == "v1alpha3" {
 		return EnvoyFilter
 	}
+	if gvk.Kind == "GRPCRoute" && gvk.Group == "gateway.networking.k8s.io" && gvk.Version == "v1alpha2" {
+		return GRPCRoute
+	}
 	if gvk.Kind == "Gateway" && gvk.Group == "networking.istio.io" && gvk.Version == "v1alpha3" {
 		return Gateway
 	}
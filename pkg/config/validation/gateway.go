@@ -63,8 +63,12 @@ var ValidateHTTPRoute = registerValidateFunc("ValidateHTTPRoute",
 // ValidateGRPCRoute checks config supplied by user
 var ValidateGRPCRoute = registerValidateFunc("ValidateGRPCRoute",
 	func(cfg config.Config) (Warning, error) {
-		// Nothing defined by upstream
-		return nil, nil
+		errs := gatewayalphavalidation.ValidateGRPCRoute(&gatewayalpha.GRPCRoute{
+			ObjectMeta: cfg.ToObjectMeta(),
+			Spec:       nilSafePtrCast[gatewayalpha.GRPCRouteSpec](cfg.Spec),
+			Status:     nilSafePtrCast[gatewayalpha.GRPCRouteStatus](cfg.Status),
+		})
+		return nil, errs.ToAggregate()
 	})
 
 // ValidateTCPRoute checks config supplied by user
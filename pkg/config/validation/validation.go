@@ -3223,8 +3223,12 @@ func validateReadinessProbe(probe *networking.ReadinessProbe) (errs error) {
 			break
 		}
 		errs = appendErrors(errs, ValidatePort(int(h.Port)))
-		if h.Scheme != "" && h.Scheme != string(apimirror.URISchemeHTTPS) && h.Scheme != string(apimirror.URISchemeHTTP) {
-			errs = appendErrors(errs, fmt.Errorf(`httpGet.scheme must be one of "http", "https"`))
+		// The grpc and grpcs schemes probe the port with the gRPC health checking protocol, the path being the
+		// name of the service to check.
+		switch h.Scheme {
+		case "", string(apimirror.URISchemeHTTP), string(apimirror.URISchemeHTTPS), "GRPC", "GRPCS":
+		default:
+			errs = appendErrors(errs, fmt.Errorf(`httpGet.scheme must be one of "http", "https", "grpc", "grpcs"`))
 		}
 		for _, header := range h.HttpHeaders {
 			if header == nil {
@@ -3393,6 +3393,37 @@ func TestValidateWorkloadGroup(t *testing.T) {
 			},
 			valid: true,
 		},
+		{
+			name: "probe grpc valid",
+			in: &networking.WorkloadGroup{
+				Template: &networking.WorkloadEntry{},
+				Probe: &networking.ReadinessProbe{
+					HealthCheckMethod: &networking.ReadinessProbe_HttpGet{
+						HttpGet: &networking.HTTPHealthCheckConfig{
+							Port:   5,
+							Path:   "/helloworld.Greeter",
+							Scheme: "GRPCS",
+						},
+					},
+				},
+			},
+			valid: true,
+		},
+		{
+			name: "probe http invalid scheme",
+			in: &networking.WorkloadGroup{
+				Template: &networking.WorkloadEntry{},
+				Probe: &networking.ReadinessProbe{
+					HealthCheckMethod: &networking.ReadinessProbe_HttpGet{
+						HttpGet: &networking.HTTPHealthCheckConfig{
+							Port:   5,
+							Scheme: "FTP",
+						},
+					},
+				},
+			},
+			valid: false,
+		},
 		{
 			name: "probe tcp invalid",
 			in: &networking.WorkloadGroup{
@@ -0,0 +1,225 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/miekg/dns"
+)
+
+const (
+	// prefetchHits is the number of hits after which an entry is considered hot, and is refreshed
+	// from the upstream before it expires.
+	prefetchHits = 3
+	// prefetchPercentage is the percentage of the TTL of a hot entry remaining when it is refreshed.
+	prefetchPercentage = 10
+)
+
+// CacheConfig configures the cache of the responses of the upstream DNS servers.
+type CacheConfig struct {
+	// Size is the maximum number of responses cached. The cache is disabled if it is 0.
+	Size int
+	// MaxNegativeTTL caps how long NXDOMAIN and empty responses are cached. Negative caching is
+	// disabled if it is 0.
+	MaxNegativeTTL time.Duration
+}
+
+// cacheKey identifies the responses to the same question.
+type cacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+	// do is the DNSSEC OK bit of the request, as it changes the records in the response.
+	do bool
+}
+
+type cacheEntry struct {
+	msg *dns.Msg
+	// storedAt and ttl determine when the entry expires.
+	storedAt time.Time
+	ttl      time.Duration
+	negative bool
+	hits     int
+	// prefetching is true while the entry is refreshed from the upstream.
+	prefetching bool
+}
+
+// responseCache caches the responses of the upstream DNS servers until their TTL expires. Responses
+// of the upstream are cached if they are successful or NXDOMAIN, and not truncated. The TTL of a
+// successful response is the lowest TTL of its records, and the TTL of a negative response is the
+// TTL of the SOA record in the authority section, capped by the max negative TTL (RFC 2308).
+// A nil responseCache caches nothing.
+type responseCache struct {
+	maxNegativeTTL time.Duration
+	now            func() time.Time
+
+	mu    sync.Mutex
+	store simplelru.LRUCache
+}
+
+// newResponseCache returns a responseCache, or nil if the cache is disabled.
+func newResponseCache(cfg CacheConfig) (*responseCache, error) {
+	if cfg.Size <= 0 {
+		return nil, nil
+	}
+	store, err := simplelru.NewLRU(cfg.Size, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &responseCache{
+		maxNegativeTTL: cfg.MaxNegativeTTL,
+		now:            time.Now,
+		store:          store,
+	}, nil
+}
+
+func keyForRequest(req *dns.Msg) cacheKey {
+	q := req.Question[0]
+	key := cacheKey{name: strings.ToLower(q.Name), qtype: q.Qtype, qclass: q.Qclass}
+	if o := req.IsEdns0(); o != nil {
+		key.do = o.Do()
+	}
+	return key
+}
+
+// get returns the cached response for the request, with the TTLs of its records decremented by the
+// time it was cached for, or nil. prefetch is true if the entry is hot and about to expire, in which
+// case the caller is expected to refresh it from the upstream.
+func (c *responseCache) get(req *dns.Msg) (response *dns.Msg, prefetch bool) {
+	if c == nil {
+		return nil, false
+	}
+	key := keyForRequest(req)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, f := c.store.Get(key)
+	if !f {
+		cacheMisses.Increment()
+		return nil, false
+	}
+	entry := v.(*cacheEntry)
+	elapsed := c.now().Sub(entry.storedAt)
+	remaining := entry.ttl - elapsed
+	if remaining <= 0 {
+		c.store.Remove(key)
+		cacheMisses.Increment()
+		return nil, false
+	}
+	if entry.negative {
+		cacheHits.With(cacheTypeTag.Value("negative")).Increment()
+	} else {
+		cacheHits.With(cacheTypeTag.Value("positive")).Increment()
+	}
+	entry.hits++
+	if entry.hits >= prefetchHits && !entry.prefetching && remaining*100 <= entry.ttl*prefetchPercentage {
+		entry.prefetching = true
+		prefetch = true
+	}
+
+	response = entry.msg.Copy()
+	response.Id = req.Id
+	response.Question = req.Question
+	ttl := uint32(remaining.Round(time.Second) / time.Second)
+	for _, rrs := range [][]dns.RR{response.Answer, response.Ns, response.Extra} {
+		for _, rr := range rrs {
+			rr.Header().Ttl = ttl
+		}
+	}
+	if o := req.IsEdns0(); o != nil {
+		response.SetEdns0(o.UDPSize(), o.Do())
+	}
+	return response, prefetch
+}
+
+// add caches the response of the upstream to the request, and returns true if it is cacheable.
+func (c *responseCache) add(req *dns.Msg, response *dns.Msg) bool {
+	if c == nil || response == nil || response.Truncated {
+		return false
+	}
+	var ttl time.Duration
+	negative := false
+	switch {
+	case response.Rcode == dns.RcodeSuccess && len(response.Answer) > 0:
+		ttl = minTTL(response.Answer)
+	case response.Rcode == dns.RcodeSuccess || response.Rcode == dns.RcodeNameError:
+		negative = true
+		ttl = negativeTTL(response)
+		if ttl > c.maxNegativeTTL {
+			ttl = c.maxNegativeTTL
+		}
+	}
+	if ttl <= 0 {
+		return false
+	}
+	msg := response.Copy()
+	// The OPT record belongs to the request it answers, it is added back for each request.
+	extra := msg.Extra[:0]
+	for _, rr := range msg.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			extra = append(extra, rr)
+		}
+	}
+	msg.Extra = extra
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Expired entries are removed in get, only the entries evicted as the cache is full are counted.
+	if evicted := c.store.Add(keyForRequest(req), &cacheEntry{msg: msg, storedAt: c.now(), ttl: ttl, negative: negative}); evicted {
+		cacheEvictions.Increment()
+	}
+	return true
+}
+
+// prefetchFailed allows the entry of the request to be prefetched again, after the upstream did not
+// return a cacheable response. The entry is kept until it expires.
+func (c *responseCache) prefetchFailed(req *dns.Msg) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if v, f := c.store.Peek(keyForRequest(req)); f {
+		v.(*cacheEntry).prefetching = false
+	}
+}
+
+// minTTL returns the lowest TTL of the records.
+func minTTL(rrs []dns.RR) time.Duration {
+	ttl := uint32(0)
+	for i, rr := range rrs {
+		if i == 0 || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	return time.Duration(ttl) * time.Second
+}
+
+// negativeTTL returns the TTL of a negative response, which is the lower of the TTL and the minimum
+// field of the SOA record in the authority section. Negative responses without a SOA record are not cached.
+func negativeTTL(response *dns.Msg) time.Duration {
+	for _, rr := range response.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			ttl := soa.Hdr.Ttl
+			if soa.Minttl < ttl {
+				ttl = soa.Minttl
+			}
+			return time.Duration(ttl) * time.Second
+		}
+	}
+	return 0
+}
@@ -0,0 +1,241 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"go.opencensus.io/stats/view"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func newTestCache(t *testing.T, size int, maxNegativeTTL time.Duration) (*responseCache, *time.Time) {
+	c, err := newResponseCache(CacheConfig{Size: size, MaxNegativeTTL: maxNegativeTTL})
+	assert.NoError(t, err)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func request(host string, qtype uint16) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion(host, qtype)
+	return req
+}
+
+func answer(req *dns.Msg, ttl uint32, ips ...string) *dns.Msg {
+	response := new(dns.Msg)
+	response.SetReply(req)
+	for _, ip := range ips {
+		rr := a(req.Question[0].Name, []netip.Addr{netip.MustParseAddr(ip)})[0]
+		rr.Header().Ttl = ttl
+		response.Answer = append(response.Answer, rr)
+	}
+	return response
+}
+
+func nxdomain(req *dns.Msg, soaTTL, soaMinTTL uint32) *dns.Msg {
+	response := new(dns.Msg)
+	response.SetRcode(req, dns.RcodeNameError)
+	if soaTTL > 0 {
+		response.Ns = []dns.RR{&dns.SOA{
+			Hdr:    dns.RR_Header{Name: "cluster.local.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: soaTTL},
+			Ns:     "ns.dns.cluster.local.",
+			Mbox:   "hostmaster.cluster.local.",
+			Minttl: soaMinTTL,
+		}}
+	}
+	return response
+}
+
+func TestResponseCache(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		c, err := newResponseCache(CacheConfig{})
+		assert.NoError(t, err)
+		req := request("www.bing.com.", dns.TypeA)
+		assert.Equal(t, c.add(req, answer(req, 60, "1.1.1.1")), false)
+		got, _ := c.get(req)
+		assert.Equal(t, got, nil)
+	})
+
+	t.Run("positive", func(t *testing.T) {
+		c, now := newTestCache(t, 10, 0)
+		req := request("www.bing.com.", dns.TypeA)
+		got, _ := c.get(req)
+		assert.Equal(t, got, nil)
+		assert.Equal(t, c.add(req, answer(req, 60, "1.1.1.1", "1.1.1.2")), true)
+
+		*now = now.Add(20 * time.Second)
+		// The name is case insensitive, and the response answers the question as asked
+		again := request("WWW.bing.com.", dns.TypeA)
+		again.Id = 1234
+		got, _ = c.get(again)
+		assert.Equal(t, got.Id, again.Id)
+		assert.Equal(t, got.Question[0].Name, "WWW.bing.com.")
+		assert.Equal(t, len(got.Answer), 2)
+		for _, rr := range got.Answer {
+			assert.Equal(t, rr.Header().Ttl, uint32(40))
+		}
+
+		// Other types are cached separately
+		got, _ = c.get(request("www.bing.com.", dns.TypeAAAA))
+		assert.Equal(t, got, nil)
+
+		*now = now.Add(40 * time.Second)
+		got, _ = c.get(req)
+		assert.Equal(t, got, nil)
+	})
+
+	t.Run("lowest ttl", func(t *testing.T) {
+		c, now := newTestCache(t, 10, 0)
+		req := request("www.bing.com.", dns.TypeA)
+		response := answer(req, 60, "1.1.1.1")
+		response.Answer = append(response.Answer, answer(req, 5, "1.1.1.2").Answer...)
+		c.add(req, response)
+		*now = now.Add(5 * time.Second)
+		got, _ := c.get(req)
+		assert.Equal(t, got, nil)
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		c, now := newTestCache(t, 10, 30*time.Second)
+		req := request("missing.bing.com.", dns.TypeA)
+		// The TTL is the lower of the TTL and minimum of the SOA record
+		assert.Equal(t, c.add(req, nxdomain(req, 300, 10)), true)
+		got, _ := c.get(req)
+		assert.Equal(t, got.Rcode, dns.RcodeNameError)
+		assert.Equal(t, got.Ns[0].Header().Ttl, uint32(10))
+		*now = now.Add(10 * time.Second)
+		got, _ = c.get(req)
+		assert.Equal(t, got, nil)
+
+		// The TTL is capped by the max negative TTL
+		c.add(req, nxdomain(req, 300, 300))
+		*now = now.Add(29 * time.Second)
+		got, _ = c.get(req)
+		assert.Equal(t, got.Ns[0].Header().Ttl, uint32(1))
+		*now = now.Add(time.Second)
+		got, _ = c.get(req)
+		assert.Equal(t, got, nil)
+
+		// Negative responses without SOA are not cached
+		assert.Equal(t, c.add(req, nxdomain(req, 0, 0)), false)
+	})
+
+	t.Run("negative caching disabled", func(t *testing.T) {
+		c, _ := newTestCache(t, 10, 0)
+		req := request("missing.bing.com.", dns.TypeA)
+		assert.Equal(t, c.add(req, nxdomain(req, 300, 10)), false)
+	})
+
+	t.Run("not cacheable", func(t *testing.T) {
+		c, _ := newTestCache(t, 10, 30*time.Second)
+		req := request("www.bing.com.", dns.TypeA)
+		assert.Equal(t, c.add(req, serverFailure(req)), false)
+		truncated := answer(req, 60, "1.1.1.1")
+		truncated.Truncated = true
+		assert.Equal(t, c.add(req, truncated), false)
+		assert.Equal(t, c.add(req, answer(req, 0, "1.1.1.1")), false)
+	})
+
+	t.Run("edns", func(t *testing.T) {
+		c, _ := newTestCache(t, 10, 0)
+		req := request("www.bing.com.", dns.TypeA)
+		req.SetEdns0(4096, false)
+		response := answer(req, 60, "1.1.1.1")
+		response.SetEdns0(1232, false)
+		c.add(req, response)
+
+		got, _ := c.get(request("www.bing.com.", dns.TypeA))
+		assert.Equal(t, got.IsEdns0() == nil, true)
+		got, _ = c.get(req)
+		assert.Equal(t, got.IsEdns0().UDPSize(), uint16(4096))
+		// The DNSSEC OK bit is part of the key
+		dnssec := request("www.bing.com.", dns.TypeA)
+		dnssec.SetEdns0(4096, true)
+		got, _ = c.get(dnssec)
+		assert.Equal(t, got, nil)
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		c, _ := newTestCache(t, 2, 0)
+		for _, host := range []string{"a.bing.com.", "b.bing.com.", "c.bing.com."} {
+			req := request(host, dns.TypeA)
+			c.add(req, answer(req, 60, "1.1.1.1"))
+		}
+		got, _ := c.get(request("a.bing.com.", dns.TypeA))
+		assert.Equal(t, got, nil)
+		got, _ = c.get(request("c.bing.com.", dns.TypeA))
+		assert.Equal(t, len(got.Answer), 1)
+	})
+
+	t.Run("evictions", func(t *testing.T) {
+		registerStats()
+		evictions := func() float64 {
+			t.Helper()
+			data, err := view.RetrieveData("dns_cache_evictions_total")
+			assert.NoError(t, err)
+			if len(data) == 0 {
+				return 0
+			}
+			return data[0].Data.(*view.SumData).Value
+		}
+		initial := evictions()
+		c, now := newTestCache(t, 2, 0)
+		a := request("a.bing.com.", dns.TypeA)
+		c.add(a, answer(a, 10, "1.1.1.1"))
+		// Expired entries are not counted as evicted
+		*now = now.Add(10 * time.Second)
+		got, _ := c.get(a)
+		assert.Equal(t, got, nil)
+		assert.Equal(t, evictions(), initial)
+
+		for _, host := range []string{"b.bing.com.", "c.bing.com.", "d.bing.com."} {
+			req := request(host, dns.TypeA)
+			c.add(req, answer(req, 60, "1.1.1.1"))
+		}
+		assert.Equal(t, evictions(), initial+1)
+	})
+
+	t.Run("prefetch", func(t *testing.T) {
+		c, now := newTestCache(t, 10, 0)
+		req := request("www.bing.com.", dns.TypeA)
+		c.add(req, answer(req, 100, "1.1.1.1"))
+		*now = now.Add(95 * time.Second)
+		// Entries are prefetched once they are hot
+		for i := 1; i < prefetchHits; i++ {
+			_, prefetch := c.get(req)
+			assert.Equal(t, prefetch, false)
+		}
+		_, prefetch := c.get(req)
+		assert.Equal(t, prefetch, true)
+		// Only once at a time
+		_, prefetch = c.get(req)
+		assert.Equal(t, prefetch, false)
+		c.prefetchFailed(req)
+		_, prefetch = c.get(req)
+		assert.Equal(t, prefetch, true)
+
+		// The refreshed entry is a new entry
+		c.add(req, answer(req, 100, "1.1.1.2"))
+		got, prefetch := c.get(req)
+		assert.Equal(t, prefetch, false)
+		assert.Equal(t, got.Answer[0].Header().Ttl, uint32(100))
+	})
+}
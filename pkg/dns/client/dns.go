@@ -20,6 +20,7 @@ import (
 	"net"
 	"net/netip"
 	"os"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -58,6 +59,15 @@ type LocalDNSServer struct {
 
 	respondBeforeSync         bool
 	forwardToUpstreamParallel bool
+
+	// cache holds the responses of the upstream DNS servers, nil if caching is disabled.
+	cache *responseCache
+
+	// encryptedUpstreams are the DNS-over-TLS or DNS-over-HTTPS resolvers used instead of resolvConfServers.
+	encryptedUpstreams []encryptedUpstream
+	upstreamFallback   FallbackPolicy
+	// clusterDomains are the domains whose names are resolved by resolvConfServers even with encryptedUpstreams.
+	clusterDomains []string
 }
 
 // LookupTable is borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hostsfile.go
@@ -77,6 +87,13 @@ type LookupTable struct {
 	// The cname records here (comprised of different variants of the hosts above,
 	// expanded by the search namespaces) pointing to the actual host.
 	cname map[string][]dns.RR
+	// The key is either a variant of a host (like productpage.ns1.), or a port of it
+	// (like _http._tcp.productpage.ns1.), the value is pre-created DNS RR records of SRV type
+	// for all the ports of the host, or for the port.
+	srv map[string][]dns.RR
+	// The key is the reverse lookup name of an IP (like 4.3.2.1.in-addr.arpa.), the value is
+	// pre-created DNS RR records of PTR type pointing to the hosts with the IP.
+	ptr map[string][]dns.RR
 }
 
 const (
@@ -86,14 +103,26 @@ const (
 	defaultTTLInSeconds = 30
 )
 
-func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardToUpstreamParallel bool) (*LocalDNSServer, error) {
+func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardToUpstreamParallel bool,
+	cacheConfig CacheConfig, upstreamConfig UpstreamConfig,
+) (*LocalDNSServer, error) {
 	h := &LocalDNSServer{
 		proxyNamespace:            proxyNamespace,
 		forwardToUpstreamParallel: forwardToUpstreamParallel,
+		upstreamFallback:          upstreamConfig.Fallback,
 	}
 
 	registerStats()
 
+	cache, err := newResponseCache(cacheConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the DNS cache: %v", err)
+	}
+	h.cache = cache
+	if h.encryptedUpstreams, err = newEncryptedUpstreams(upstreamConfig); err != nil {
+		return nil, err
+	}
+
 	// proxyDomain could contain the namespace making it redundant.
 	// we just need the .svc.cluster.local piece
 	parts := strings.Split(proxyDomain, ".")
@@ -142,6 +171,7 @@ func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardT
 		}
 		h.searchNamespaces = dnsConfig.Search
 	}
+	h.clusterDomains = clusterDomains(h.proxyDomain, h.searchNamespaces)
 
 	log.WithLabels("search", h.searchNamespaces, "servers", h.resolvConfServers).Debugf("initialized DNS")
 
@@ -192,8 +222,21 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 		name4:    map[string][]dns.RR{},
 		name6:    map[string][]dns.RR{},
 		cname:    map[string][]dns.RR{},
+		srv:      map[string][]dns.RR{},
+		ptr:      map[string][]dns.RR{},
 	}
 	h.BuildAlternateHosts(nt, lookupTable.buildDNSAnswers)
+	for hostname, ni := range nt.Table {
+		if strings.HasPrefix(hostname, "*") {
+			// SRV and PTR records cannot be synthesized for wildcard hosts.
+			continue
+		}
+		if len(ni.Ports) > 0 {
+			lookupTable.buildSRVAnswers(h.alternateHosts(hostname, ni), hostname, ni.Ports)
+		}
+		lookupTable.buildPTRAnswers(hostname, ni.Ips)
+	}
+	lookupTable.sortPTRAnswers()
 	h.lookupTable.Store(lookupTable)
 	h.nameTable.Store(nt)
 	log.Debugf("updated lookup table with %d hosts", len(lookupTable.allHosts))
@@ -205,30 +248,52 @@ func (h *LocalDNSServer) BuildAlternateHosts(nt *dnsProto.NameTable,
 	apply func(map[string]struct{}, []netip.Addr, []netip.Addr, []string),
 ) {
 	for hostname, ni := range nt.Table {
-		// Given a host
-		// if its a non-k8s host, store the host+. as the key with the pre-computed DNS RR records
-		// if its a k8s host, store all variants (i.e. shortname+., shortname+namespace+., fqdn+., etc.)
-		// shortname+. is only for hosts in current namespace
-		var altHosts sets.String
-		if ni.Registry == string(provider.Kubernetes) {
-			altHosts = generateAltHosts(hostname, ni, h.proxyNamespace, h.proxyDomain, h.proxyDomainParts)
-		} else {
-			if !strings.HasSuffix(hostname, ".") {
-				hostname += "."
-			}
-			altHosts = sets.New(hostname)
-		}
 		ipv4, ipv6 := netutil.ParseIPsSplitToV4V6(ni.Ips)
 		if len(ipv6) == 0 && len(ipv4) == 0 {
 			// malformed ips
 			continue
 		}
-		apply(altHosts, ipv4, ipv6, h.searchNamespaces)
+		apply(h.alternateHosts(hostname, ni), ipv4, ipv6, h.searchNamespaces)
 	}
 }
 
-// upstream sends the request to the upstream server, with associated logs and metrics
+// alternateHosts returns the hosts a host in the name table is resolved for.
+func (h *LocalDNSServer) alternateHosts(hostname string, ni *dnsProto.NameTable_NameInfo) sets.String {
+	// Given a host
+	// if its a non-k8s host, store the host+. as the key with the pre-computed DNS RR records
+	// if its a k8s host, store all variants (i.e. shortname+., shortname+namespace+., fqdn+., etc.)
+	// shortname+. is only for hosts in current namespace
+	if ni.Registry == string(provider.Kubernetes) {
+		return generateAltHosts(hostname, ni, h.proxyNamespace, h.proxyDomain, h.proxyDomainParts)
+	}
+	return sets.New(dns.Fqdn(hostname))
+}
+
+// upstream answers the request from the cache of upstream responses, or sends it to the upstream server
+// and caches the response.
 func (h *LocalDNSServer) upstream(proxy *dnsProxy, req *dns.Msg, hostname string) *dns.Msg {
+	if response, prefetch := h.cache.get(req); response != nil {
+		log.Debugf("response for hostname %q found in dns cache: %v", hostname, response)
+		if prefetch {
+			go h.prefetch(proxy, req.Copy(), hostname)
+		}
+		return response
+	}
+	response := h.queryUpstreamWithStats(proxy, req, hostname)
+	h.cache.add(req, response)
+	return response
+}
+
+// prefetch refreshes the cached response of a frequently requested host before it expires.
+func (h *LocalDNSServer) prefetch(proxy *dnsProxy, req *dns.Msg, hostname string) {
+	cachePrefetches.Increment()
+	if !h.cache.add(req, h.queryUpstreamWithStats(proxy, req, hostname)) {
+		h.cache.prefetchFailed(req)
+	}
+}
+
+// queryUpstreamWithStats sends the request to the upstream server, with associated logs and metrics
+func (h *LocalDNSServer) queryUpstreamWithStats(proxy *dnsProxy, req *dns.Msg, hostname string) *dns.Msg {
 	upstreamRequests.Increment()
 	start := time.Now()
 	// We did not find the host in our internal cache. Query upstream and return the response as is.
@@ -388,9 +453,23 @@ func (h *LocalDNSServer) Close() {
 	for _, p := range h.dnsProxies {
 		p.close()
 	}
+	for _, u := range h.encryptedUpstreams {
+		u.close()
+	}
 }
 
 func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
+	if len(h.encryptedUpstreams) > 0 && !h.inClusterDomain(req.Question[0].Name) {
+		if response := h.queryEncryptedUpstreams(req, scope); response != nil {
+			return response
+		}
+		if h.upstreamFallback != FallbackPlaintext {
+			scope.Infof("all encrypted upstream failed")
+			return serverFailure(req)
+		}
+		scope.Warnf("all encrypted upstream failed, falling back to the plaintext upstream %v", h.resolvConfServers)
+	}
+
 	if h.forwardToUpstreamParallel {
 		return h.queryUpstreamParallel(upstreamClient, req, scope)
 	}
@@ -508,6 +587,17 @@ func generateAltHosts(hostname string, nameinfo *dnsProto.NameTable_NameInfo, pr
 // If it is not part of the registry, return nil so that caller queries upstream. If it is part
 // of registry, we will look it up in one of our tables, failing which we will return NXDOMAIN.
 func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, bool) {
+	switch qtype {
+	case dns.TypeSRV:
+		// SRV records are only known for the ports of the hosts in our registry. Other SRV queries,
+		// including the ones for ports the host does not have, are answered by the upstream.
+		answers, f := table.srv[hostname]
+		return answers, f
+	case dns.TypePTR:
+		answers, f := table.ptr[hostname]
+		return answers, f
+	}
+
 	var hostFound bool
 
 	question := host.Name(hostname)
@@ -550,7 +640,6 @@ func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, b
 	case dns.TypeAAAA:
 		ipAnswers = table.name6[hostname]
 	default:
-		// TODO: handle PTR records for reverse dns lookups
 		return nil, false
 	}
 
@@ -626,6 +715,49 @@ func (table *LookupTable) buildDNSAnswers(altHosts map[string]struct{}, ipv4 []n
 	}
 }
 
+// buildSRVAnswers stores the SRV records of the ports of a host, for each of its alternate hosts. Following
+// the Kubernetes DNS specification, the SRV records of a port are stored as _<port name>._<protocol>.<host>,
+// and the SRV records of all the ports as the host itself. The target of the records is the host.
+func (table *LookupTable) buildSRVAnswers(altHosts sets.String, hostname string, ports []*dnsProto.NameTable_NameInfo_Port) {
+	target := strings.ToLower(dns.Fqdn(hostname))
+	for h := range altHosts {
+		h = strings.ToLower(h)
+		for _, p := range ports {
+			answer := srv(h, target, p)
+			table.srv[h] = append(table.srv[h], answer)
+			if p.Name != "" {
+				portHost := "_" + strings.ToLower(p.Name) + "._" + p.Protocol + "." + h
+				answer = srv(portHost, target, p)
+				table.srv[portHost] = append(table.srv[portHost], answer)
+			}
+		}
+	}
+}
+
+// buildPTRAnswers stores the PTR records pointing to a host for the reverse lookup names of its IPs.
+// An IP may be the IP of several hosts, e.g. the IP of a pod of a headless service is both the IP
+// of the service and of the pod, in which case a PTR record is stored for each of the hosts.
+func (table *LookupTable) buildPTRAnswers(hostname string, ips []string) {
+	target := strings.ToLower(dns.Fqdn(hostname))
+	for _, ip := range ips {
+		reverse, err := dns.ReverseAddr(ip)
+		if err != nil {
+			// malformed ip
+			continue
+		}
+		table.ptr[reverse] = append(table.ptr[reverse], ptr(reverse, target))
+	}
+}
+
+// sortPTRAnswers sorts the PTR records of each IP by target, as the name table is not ordered.
+func (table *LookupTable) sortPTRAnswers() {
+	for _, answers := range table.ptr {
+		sort.Slice(answers, func(i, j int) bool {
+			return answers[i].(*dns.PTR).Ptr < answers[j].(*dns.PTR).Ptr
+		})
+	}
+}
+
 // Borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hosts.go
 // a takes a slice of ip string and returns a slice of A RRs.
 func a(host string, ips []netip.Addr) []dns.RR {
@@ -663,6 +795,33 @@ func cname(host string, targetHost string) []dns.RR {
 	return []dns.RR{answer}
 }
 
+func srv(host string, targetHost string, port *dnsProto.NameTable_NameInfo_Port) dns.RR {
+	answer := new(dns.SRV)
+	answer.Hdr = dns.RR_Header{
+		Name:   host,
+		Rrtype: dns.TypeSRV,
+		Class:  dns.ClassINET,
+		Ttl:    defaultTTLInSeconds,
+	}
+	answer.Priority = 0
+	answer.Weight = 100
+	answer.Port = uint16(port.Number)
+	answer.Target = targetHost
+	return answer
+}
+
+func ptr(host string, targetHost string) dns.RR {
+	answer := new(dns.PTR)
+	answer.Hdr = dns.RR_Header{
+		Name:   host,
+		Rrtype: dns.TypePTR,
+		Class:  dns.ClassINET,
+		Ttl:    defaultTTLInSeconds,
+	}
+	answer.Ptr = targetHost
+	return answer
+}
+
 // Size returns if buffer size *advertised* in the requests OPT record.
 // Or when the request was over TCP, we return the maximum allowed size of 64K.
 func size(proto string, r *dns.Msg) int {
@@ -45,6 +45,7 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 		host                     string
 		id                       int
 		queryAAAA                bool
+		qtype                    uint16
 		expected                 []dns.RR
 		expectResolutionFailure  int
 		expectExternalResolution bool
@@ -245,6 +246,65 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 			host:     "example.localhost.",
 			expected: a("example.localhost.", []netip.Addr{netip.MustParseAddr("3.3.3.3")}),
 		},
+		{
+			name:  "success: SRV query for a port of k8s host - fqdn",
+			host:  "_http._tcp.productpage.ns1.svc.cluster.local.",
+			qtype: dns.TypeSRV,
+			expected: []dns.RR{srv("_http._tcp.productpage.ns1.svc.cluster.local.", "productpage.ns1.svc.cluster.local.",
+				&dnsProto.NameTable_NameInfo_Port{Name: "http", Number: 9080, Protocol: "tcp"})},
+		},
+		{
+			name:  "success: SRV query for a port of k8s host - shortname",
+			host:  "_dns._udp.productpage.",
+			qtype: dns.TypeSRV,
+			expected: []dns.RR{srv("_dns._udp.productpage.", "productpage.ns1.svc.cluster.local.",
+				&dnsProto.NameTable_NameInfo_Port{Name: "dns", Number: 53, Protocol: "udp"})},
+		},
+		{
+			name:  "success: SRV query for k8s host yields all the ports",
+			host:  "productpage.ns1.",
+			qtype: dns.TypeSRV,
+			expected: []dns.RR{
+				srv("productpage.ns1.", "productpage.ns1.svc.cluster.local.",
+					&dnsProto.NameTable_NameInfo_Port{Name: "http", Number: 9080, Protocol: "tcp"}),
+				srv("productpage.ns1.", "productpage.ns1.svc.cluster.local.",
+					&dnsProto.NameTable_NameInfo_Port{Name: "dns", Number: 53, Protocol: "udp"}),
+			},
+		},
+		{
+			name:  "success: SRV query for a port of non k8s host",
+			host:  "_https._tcp.www.google.com.",
+			qtype: dns.TypeSRV,
+			expected: []dns.RR{srv("_https._tcp.www.google.com.", "www.google.com.",
+				&dnsProto.NameTable_NameInfo_Port{Name: "https", Number: 443, Protocol: "tcp"})},
+		},
+		{
+			name:                    "failure: SRV query for an unknown port of k8s host",
+			host:                    "_grpc._tcp.productpage.ns1.svc.cluster.local.",
+			qtype:                   dns.TypeSRV,
+			expectResolutionFailure: dns.RcodeNameError,
+		},
+		{
+			name:     "success: PTR query for IPv4",
+			host:     "9.9.9.9.in-addr.arpa.",
+			qtype:    dns.TypePTR,
+			expected: []dns.RR{ptr("9.9.9.9.in-addr.arpa.", "productpage.ns1.svc.cluster.local.")},
+		},
+		{
+			name:  "success: PTR query for IPv6 of several hosts",
+			host:  "9.2.3.8.2.4.0.0.0.0.f.f.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+			qtype: dns.TypePTR,
+			expected: []dns.RR{
+				ptr("9.2.3.8.2.4.0.0.0.0.f.f.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "dual.localhost."),
+				ptr("9.2.3.8.2.4.0.0.0.0.f.f.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", "ipv6.localhost."),
+			},
+		},
+		{
+			name:                    "failure: PTR query for an unknown IP",
+			host:                    "1.2.3.4.in-addr.arpa.",
+			qtype:                   dns.TypePTR,
+			expectResolutionFailure: dns.RcodeNameError,
+		},
 	}
 
 	clients := []dns.Client{
@@ -281,6 +341,9 @@ func testDNS(t *testing.T, d *LocalDNSServer) {
 				if tt.queryAAAA {
 					q = dns.TypeAAAA
 				}
+				if tt.qtype != 0 {
+					q = tt.qtype
+				}
 				m.SetQuestion(tt.host, q)
 				if tt.modifyReq != nil {
 					tt.modifyReq(m)
@@ -410,6 +473,7 @@ func makeUpstream(t test.Failer, responses map[string]string) string {
 		}
 	})
 	for hn, desiredResp := range responses {
+		hn, desiredResp := hn, desiredResp
 		mux.HandleFunc(hn, func(resp dns.ResponseWriter, msg *dns.Msg) {
 			answer := dns.Msg{
 				Answer: a(hn, []netip.Addr{netip.MustParseAddr(desiredResp)}),
@@ -497,7 +561,8 @@ func makeUpstream(t test.Failer, responses map[string]string) string {
 
 func initDNS(t test.Failer, forwardToUpstreamParallel bool) *LocalDNSServer {
 	srv := makeUpstream(t, map[string]string{"www.bing.com.": "1.1.1.1"})
-	testAgentDNS, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", forwardToUpstreamParallel)
+	testAgentDNS, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", forwardToUpstreamParallel, CacheConfig{},
+		UpstreamConfig{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -509,12 +574,19 @@ func initDNS(t test.Failer, forwardToUpstreamParallel bool) *LocalDNSServer {
 			"www.google.com": {
 				Ips:      []string{"1.1.1.1"},
 				Registry: "External",
+				Ports: []*dnsProto.NameTable_NameInfo_Port{
+					{Name: "https", Number: 443, Protocol: "tcp"},
+				},
 			},
 			"productpage.ns1.svc.cluster.local": {
 				Ips:       []string{"9.9.9.9"},
 				Registry:  "Kubernetes",
 				Namespace: "ns1",
 				Shortname: "productpage",
+				Ports: []*dnsProto.NameTable_NameInfo_Port{
+					{Name: "http", Number: 9080, Protocol: "tcp"},
+					{Name: "dns", Number: 53, Protocol: "udp"},
+				},
 			},
 			"example.ns2.svc.cluster.local": {
 				Ips:       []string{"10.10.10.10"},
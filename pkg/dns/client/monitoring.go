@@ -19,6 +19,8 @@ import (
 )
 
 var (
+	cacheTypeTag = monitoring.MustCreateLabel("type")
+
 	requests = monitoring.NewSum(
 		"dns_requests_total",
 		"Total number of DNS requests.",
@@ -39,6 +41,28 @@ var (
 		"Total time in seconds Istio takes to get DNS response from upstream.",
 		[]float64{.005, .001, 0.01, 0.1, 1, 5},
 	)
+
+	cacheHits = monitoring.NewSum(
+		"dns_cache_hits_total",
+		"Total number of DNS requests for hosts outside the mesh answered from the cache of upstream responses, "+
+			"by type of response (positive or negative).",
+		monitoring.WithLabels(cacheTypeTag),
+	)
+
+	cacheMisses = monitoring.NewSum(
+		"dns_cache_misses_total",
+		"Total number of DNS requests for hosts outside the mesh not found in the cache of upstream responses.",
+	)
+
+	cacheEvictions = monitoring.NewSum(
+		"dns_cache_evictions_total",
+		"Total number of upstream responses evicted from the cache as it is full.",
+	)
+
+	cachePrefetches = monitoring.NewSum(
+		"dns_cache_prefetches_total",
+		"Total number of upstream responses refreshed before they expire, as they are frequently requested.",
+	)
 )
 
 func registerStats() {
@@ -46,4 +70,8 @@ func registerStats() {
 	monitoring.MustRegister(upstreamRequests)
 	monitoring.MustRegister(failures)
 	monitoring.MustRegister(requestDuration)
+	monitoring.MustRegister(cacheHits)
+	monitoring.MustRegister(cacheMisses)
+	monitoring.MustRegister(cacheEvictions)
+	monitoring.MustRegister(cachePrefetches)
 }
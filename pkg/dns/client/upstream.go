@@ -0,0 +1,274 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	istiolog "istio.io/pkg/log"
+)
+
+// FallbackPolicy determines how requests are resolved when none of the encrypted upstreams answer.
+type FallbackPolicy string
+
+const (
+	// FallbackNone answers SERVFAIL, so that requests are never sent in plaintext.
+	FallbackNone FallbackPolicy = "NONE"
+	// FallbackPlaintext forwards the requests to the nameservers of resolv.conf.
+	FallbackPlaintext FallbackPolicy = "PLAINTEXT"
+)
+
+const (
+	dotScheme      = "tls"
+	dohScheme      = "https"
+	defaultDoTPort = "853"
+	// dohMediaType is the media type of DNS messages in DNS-over-HTTPS (RFC 8484).
+	dohMediaType = "application/dns-message"
+)
+
+// UpstreamConfig configures the encrypted resolvers the DNS proxy forwards the requests for hosts outside
+// the mesh to, instead of the nameservers of resolv.conf. The requests for hosts in the cluster domain or a
+// search domain of resolv.conf are still forwarded to the nameservers of resolv.conf.
+type UpstreamConfig struct {
+	// Addresses of the resolvers, tls://<host>[:<port>] for DNS-over-TLS and https://<host>[:<port>]/<path>
+	// for DNS-over-HTTPS. The resolvers are tried in order. If empty, the nameservers of resolv.conf are used.
+	Addresses []string
+	// RootCAs returns the trust bundle the certificates of the resolvers are verified with, e.g. the mesh
+	// trust bundle. It is called for each connection, as the trust bundle may rotate. If nil, the system
+	// trust bundle is used.
+	RootCAs func() (*x509.CertPool, error)
+	// Fallback is the policy when none of the resolvers answer. Defaults to FallbackNone.
+	Fallback FallbackPolicy
+}
+
+// encryptedUpstream is a DNS-over-TLS or DNS-over-HTTPS resolver.
+type encryptedUpstream interface {
+	exchange(ctx context.Context, req *dns.Msg) (*dns.Msg, error)
+	close()
+	String() string
+}
+
+// newEncryptedUpstreams returns the resolvers of the config.
+func newEncryptedUpstreams(cfg UpstreamConfig) ([]encryptedUpstream, error) {
+	switch cfg.Fallback {
+	case "", FallbackNone, FallbackPlaintext:
+	default:
+		return nil, fmt.Errorf("unknown fallback policy %q, expected %s or %s", cfg.Fallback, FallbackNone, FallbackPlaintext)
+	}
+	out := make([]encryptedUpstream, 0, len(cfg.Addresses))
+	for _, address := range cfg.Addresses {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid upstream %q: %v", address, err)
+		}
+		if u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid upstream %q: missing host", address)
+		}
+		switch u.Scheme {
+		case dotScheme:
+			out = append(out, newDoTUpstream(u, cfg.RootCAs))
+		case dohScheme:
+			out = append(out, newDoHUpstream(u, cfg.RootCAs))
+		default:
+			return nil, fmt.Errorf("invalid upstream %q: unsupported scheme %q, expected %s or %s",
+				address, u.Scheme, dotScheme, dohScheme)
+		}
+	}
+	return out, nil
+}
+
+// upstreamTLSConfig returns the TLS config to connect to a resolver, verifying its certificate for the
+// server name with the trust bundle.
+func upstreamTLSConfig(serverName string, rootCAs func() (*x509.CertPool, error)) *tls.Config {
+	if rootCAs == nil {
+		return &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS12}
+	}
+	return &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+		// The default verification is replaced by VerifyConnection, which verifies the certificate against
+		// the current trust bundle rather than the one at the time the config was created.
+		InsecureSkipVerify: true, // nolint: gosec
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("no certificate presented by the upstream %s", serverName)
+			}
+			roots, err := rootCAs()
+			if err != nil {
+				return fmt.Errorf("failed to get the trust bundle: %v", err)
+			}
+			opts := x509.VerifyOptions{
+				DNSName:       serverName,
+				Roots:         roots,
+				Intermediates: x509.NewCertPool(),
+			}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err = cs.PeerCertificates[0].Verify(opts)
+			return err
+		},
+	}
+}
+
+// dotUpstream is a DNS-over-TLS resolver (RFC 7858).
+type dotUpstream struct {
+	address string
+	client  *dns.Client
+}
+
+func newDoTUpstream(u *url.URL, rootCAs func() (*x509.CertPool, error)) *dotUpstream {
+	port := u.Port()
+	if port == "" {
+		port = defaultDoTPort
+	}
+	return &dotUpstream{
+		address: net.JoinHostPort(u.Hostname(), port),
+		client: &dns.Client{
+			Net:          "tcp-tls",
+			TLSConfig:    upstreamTLSConfig(u.Hostname(), rootCAs),
+			DialTimeout:  5 * time.Second,
+			ReadTimeout:  5 * time.Second,
+			WriteTimeout: 5 * time.Second,
+		},
+	}
+}
+
+func (u *dotUpstream) exchange(ctx context.Context, req *dns.Msg) (*dns.Msg, error) {
+	response, _, err := u.client.ExchangeContext(ctx, req, u.address)
+	return response, err
+}
+
+func (u *dotUpstream) close() {}
+
+func (u *dotUpstream) String() string {
+	return dotScheme + "://" + u.address
+}
+
+// dohUpstream is a DNS-over-HTTPS resolver (RFC 8484).
+type dohUpstream struct {
+	url    string
+	client *http.Client
+}
+
+func newDoHUpstream(u *url.URL, rootCAs func() (*x509.CertPool, error)) *dohUpstream {
+	return &dohUpstream{
+		url: u.String(),
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
+				TLSClientConfig:     upstreamTLSConfig(u.Hostname(), rootCAs),
+				ForceAttemptHTTP2:   true,
+				MaxIdleConns:        10,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: 5 * time.Second,
+			},
+		},
+	}
+}
+
+func (u *dohUpstream) exchange(ctx context.Context, req *dns.Msg) (*dns.Msg, error) {
+	// The ID is 0 in DNS-over-HTTPS, so that the responses are cacheable by HTTP caches.
+	msg := req.Copy()
+	msg.Id = 0
+	body, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, u.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", dohMediaType)
+	httpReq.Header.Set("Accept", dohMediaType)
+	httpResp, err := u.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", httpResp.StatusCode, u.url)
+	}
+	b, err := io.ReadAll(io.LimitReader(httpResp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+	response := new(dns.Msg)
+	if err := response.Unpack(b); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %v", u.url, err)
+	}
+	response.Id = req.Id
+	return response, nil
+}
+
+func (u *dohUpstream) close() {
+	u.client.CloseIdleConnections()
+}
+
+func (u *dohUpstream) String() string {
+	return u.url
+}
+
+// clusterDomains returns the cluster domain, e.g. cluster.local for the proxy domain svc.cluster.local, and
+// the search domains. The hosts in these domains that are not in the name table, such as services hidden from
+// the proxy or pods, are only known to the nameservers of resolv.conf, and must not leak to external resolvers.
+func clusterDomains(proxyDomain string, searchNamespaces []string) []string {
+	var out []string
+	if proxyDomain != "" {
+		out = append(out, dns.Fqdn(strings.TrimPrefix(proxyDomain, "svc.")))
+	}
+	for _, search := range searchNamespaces {
+		if search != "" && search != "." {
+			out = append(out, dns.Fqdn(search))
+		}
+	}
+	return out
+}
+
+// inClusterDomain returns true if the host is in one of the clusterDomains.
+func (h *LocalDNSServer) inClusterDomain(host string) bool {
+	for _, domain := range h.clusterDomains {
+		if dns.IsSubDomain(domain, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryEncryptedUpstreams sends the request to the encrypted resolvers in order, and returns the first
+// response, or nil if none of them answer.
+func (h *LocalDNSServer) queryEncryptedUpstreams(req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
+	for _, upstream := range h.encryptedUpstreams {
+		response, err := upstream.exchange(context.Background(), req)
+		if err == nil {
+			return response
+		}
+		scope.Infof("upstream %s failure: %v", upstream, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	dnsProto "istio.io/istio/pkg/dns/proto"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// encryptedUpstreams starts a DNS-over-HTTPS and a DNS-over-TLS upstream, answering with 1.1.1.1 and
+// 2.2.2.2 respectively, and returns their addresses and the trust bundle of their certificate.
+func encryptedUpstreams(t *testing.T) (doh string, dot string, roots *x509.CertPool) {
+	doHServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dohMediaType {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		req := new(dns.Msg)
+		if err := req.Unpack(b); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		response := new(dns.Msg)
+		response.SetReply(req)
+		response.Answer = a(req.Question[0].Name, []netip.Addr{netip.MustParseAddr("1.1.1.1")})
+		b, _ = response.Pack()
+		w.Header().Set("Content-Type", dohMediaType)
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(doHServer.Close)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: doHServer.TLS.Certificates})
+	assert.NoError(t, err)
+	doTServer := &dns.Server{
+		Listener: listener,
+		Net:      "tcp-tls",
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			response := new(dns.Msg)
+			response.SetReply(req)
+			response.Answer = a(req.Question[0].Name, []netip.Addr{netip.MustParseAddr("2.2.2.2")})
+			_ = w.WriteMsg(response)
+		}),
+	}
+	go func() {
+		_ = doTServer.ActivateAndServe()
+	}()
+	t.Cleanup(func() {
+		_ = doTServer.Shutdown()
+	})
+
+	roots = x509.NewCertPool()
+	roots.AddCert(doHServer.Certificate())
+	return doHServer.URL + "/dns-query", "tls://" + listener.Addr().String(), roots
+}
+
+func TestEncryptedUpstreams(t *testing.T) {
+	doh, dot, roots := encryptedUpstreams(t)
+	meshRoots := func() (*x509.CertPool, error) {
+		return roots, nil
+	}
+	plaintext := makeUpstream(t, map[string]string{
+		"www.bing.com.":                   "3.3.3.3",
+		"hidden.ns2.svc.cluster.local.":   "3.3.3.4",
+		"10-0-0-1.ns2.pod.cluster.local.": "3.3.3.5",
+	})
+
+	cases := []struct {
+		name     string
+		upstream UpstreamConfig
+		host     string
+		expected []dns.RR
+		rcode    int
+	}{
+		{
+			name:     "dns over https",
+			upstream: UpstreamConfig{Addresses: []string{doh}, RootCAs: meshRoots},
+			expected: a("www.bing.com.", []netip.Addr{netip.MustParseAddr("1.1.1.1")}),
+		},
+		{
+			name:     "dns over tls",
+			upstream: UpstreamConfig{Addresses: []string{dot}, RootCAs: meshRoots},
+			expected: a("www.bing.com.", []netip.Addr{netip.MustParseAddr("2.2.2.2")}),
+		},
+		{
+			name:     "next upstream on failure",
+			upstream: UpstreamConfig{Addresses: []string{"tls://127.0.0.1:1", doh}, RootCAs: meshRoots},
+			expected: a("www.bing.com.", []netip.Addr{netip.MustParseAddr("1.1.1.1")}),
+		},
+		{
+			name:     "untrusted upstream",
+			upstream: UpstreamConfig{Addresses: []string{doh, dot}},
+			rcode:    dns.RcodeServerFailure,
+		},
+		{
+			name: "untrusted upstream with plaintext fallback",
+			upstream: UpstreamConfig{
+				Addresses: []string{doh, dot},
+				Fallback:  FallbackPlaintext,
+			},
+			expected: a("www.bing.com.", []netip.Addr{netip.MustParseAddr("3.3.3.3")}),
+		},
+		{
+			name:     "cluster host missing from the name table",
+			upstream: UpstreamConfig{Addresses: []string{doh, dot}, RootCAs: meshRoots},
+			host:     "hidden.ns2.svc.cluster.local.",
+			expected: a("hidden.ns2.svc.cluster.local.", []netip.Addr{netip.MustParseAddr("3.3.3.4")}),
+		},
+		{
+			name:     "pod host",
+			upstream: UpstreamConfig{Addresses: []string{doh, dot}, RootCAs: meshRoots},
+			host:     "10-0-0-1.ns2.pod.cluster.local.",
+			expected: a("10-0-0-1.ns2.pod.cluster.local.", []netip.Addr{netip.MustParseAddr("3.3.3.5")}),
+		},
+		{
+			name:     "plaintext upstream",
+			upstream: UpstreamConfig{},
+			expected: a("www.bing.com.", []netip.Addr{netip.MustParseAddr("3.3.3.3")}),
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", false, CacheConfig{}, tt.upstream)
+			assert.NoError(t, err)
+			d.resolvConfServers = []string{plaintext}
+			d.StartDNS()
+			t.Cleanup(d.Close)
+			d.UpdateLookupTable(&dnsProto.NameTable{})
+
+			host := tt.host
+			if host == "" {
+				host = "www.bing.com."
+			}
+			m := new(dns.Msg)
+			m.SetQuestion(host, dns.TypeA)
+			client := dns.Client{Net: "udp", Timeout: 10 * time.Second}
+			res, _, err := client.Exchange(m, d.dnsProxies[0].Address())
+			assert.NoError(t, err)
+			assert.Equal(t, res.Rcode, tt.rcode)
+			if !equalsDNSrecords(res.Answer, tt.expected) {
+				t.Errorf("dns responses do not match. \n got %v\nwant %v", res.Answer, tt.expected)
+			}
+		})
+	}
+}
+
+func TestInvalidUpstreamConfig(t *testing.T) {
+	for _, cfg := range []UpstreamConfig{
+		{Addresses: []string{"udp://1.1.1.1"}},
+		{Addresses: []string{"tls://"}},
+		{Addresses: []string{"https://dns.google/dns-query"}, Fallback: "ALWAYS"},
+	} {
+		if _, err := newEncryptedUpstreams(cfg); err == nil {
+			t.Errorf("expected an error for %+v", cfg)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/protocol"
 	dnsProto "istio.io/istio/pkg/dns/proto"
 	netutil "istio.io/istio/pkg/util/net"
 )
@@ -117,6 +118,7 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 		nameInfo := &dnsProto.NameTable_NameInfo{
 			Ips:      addressList,
 			Registry: string(svc.Attributes.ServiceRegistry),
+			Ports:    buildPorts(svc.Ports),
 		}
 		if svc.Attributes.ServiceRegistry == provider.Kubernetes &&
 			!strings.HasSuffix(hostName.String(), "."+constants.DefaultClusterSetLocalDomain) {
@@ -132,3 +134,23 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 	}
 	return out
 }
+
+// buildPorts returns the ports of a service, from which the agent answers SRV queries for the service.
+func buildPorts(ports model.PortList) []*dnsProto.NameTable_NameInfo_Port {
+	if len(ports) == 0 {
+		return nil
+	}
+	out := make([]*dnsProto.NameTable_NameInfo_Port, 0, len(ports))
+	for _, p := range ports {
+		proto := "tcp"
+		if p.Protocol == protocol.UDP {
+			proto = "udp"
+		}
+		out = append(out, &dnsProto.NameTable_NameInfo_Port{
+			Name:     p.Name,
+			Number:   uint32(p.Port),
+			Protocol: proto,
+		})
+	}
+	return out
+}
@@ -168,6 +168,8 @@ func TestNameTable(t *testing.T) {
 		},
 	}
 
+	headlessPorts := []*dnsProto.NameTable_NameInfo_Port{{Name: "tcp-port", Number: 9000, Protocol: "tcp"}}
+
 	push := model.NewPushContext()
 	push.Mesh = mesh
 	push.AddPublicServices([]*model.Service{headlessService})
@@ -242,6 +244,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -275,6 +278,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -314,6 +318,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -354,6 +359,7 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "headless-svc",
 						Namespace: "testns",
+						Ports:     headlessPorts,
 					},
 				},
 			},
@@ -369,6 +375,10 @@ func TestNameTable(t *testing.T) {
 						Registry:  "Kubernetes",
 						Shortname: "wildcard-svc",
 						Namespace: "testns",
+						Ports: []*dnsProto.NameTable_NameInfo_Port{
+							{Name: "tcp-port", Number: 9000, Protocol: "tcp"},
+							{Name: "http-port", Number: 8000, Protocol: "tcp"},
+						},
 					},
 				},
 			},
@@ -390,6 +400,7 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "9.6.7.8", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
 					},
 				},
 			},
@@ -403,6 +414,7 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
 					},
 				},
 			},
@@ -416,6 +428,7 @@ func TestNameTable(t *testing.T) {
 					"foo.bar.com": {
 						Ips:      []string{"1.2.3.4", "19.6.7.8", "9.16.7.8"},
 						Registry: "External",
+						Ports:    headlessPorts,
 					},
 				},
 			},
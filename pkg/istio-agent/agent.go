@@ -16,6 +16,8 @@ package istioagent
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/netip"
@@ -68,6 +70,13 @@ const (
 	CitadelCACertPath = "./var/run/secrets/istio"
 )
 
+const (
+	// DNSUpstreamSystemTrustBundle verifies the DNS upstreams with the system trust bundle.
+	DNSUpstreamSystemTrustBundle = "SYSTEM"
+	// DNSUpstreamMeshTrustBundle verifies the DNS upstreams with the trust bundle of the mesh.
+	DNSUpstreamMeshTrustBundle = "MESH"
+)
+
 const (
 	// MetadataClientCertKey is ISTIO_META env var used for client key.
 	MetadataClientCertKey = "ISTIO_META_TLS_CLIENT_KEY"
@@ -125,6 +134,15 @@ type AgentOptions struct {
 	DNSAddr string
 	// DNSForwardParallel indicates whether the agent should send parallel DNS queries to all upstream nameservers.
 	DNSForwardParallel bool
+	// DNSCache configures the cache of the responses of the upstream nameservers.
+	DNSCache dnsClient.CacheConfig
+	// DNSUpstreams are the DNS-over-TLS or DNS-over-HTTPS resolvers the DNS proxy uses instead of the
+	// nameservers of resolv.conf.
+	DNSUpstreams []string
+	// DNSUpstreamTrustBundle is the trust bundle the DNSUpstreams are verified with, SYSTEM or MESH.
+	DNSUpstreamTrustBundle string
+	// DNSUpstreamFallback is the policy when none of the DNSUpstreams answer.
+	DNSUpstreamFallback dnsClient.FallbackPolicy
 	// ProxyType is the type of proxy we are configured to handle
 	ProxyType model.NodeType
 	// ProxyNamespace to use for local dns resolution
@@ -186,6 +204,12 @@ type AgentOptions struct {
 	IstiodSAN string
 
 	WASMOptions wasm.Options
+
+	// XdsSnapshotPath is the file the last known xDS config is persisted to, to serve it to Envoy while
+	// istiod is unreachable. If empty, the config is not persisted.
+	XdsSnapshotPath string
+	// XdsSnapshotMaxAge is the maximum age of the persisted config that is served.
+	XdsSnapshotMaxAge time.Duration
 }
 
 // NewAgent hosts the functionality for local SDS and XDS. This consists of the local SDS server and
@@ -436,6 +460,39 @@ func (a *Agent) initSdsServer() error {
 	return nil
 }
 
+// workloadCertificate returns the workload certificate, which gRPC health checks present to the workload.
+func (a *Agent) workloadCertificate() (*tls.Certificate, error) {
+	if a.secretCache == nil {
+		return nil, fmt.Errorf("the workload certificate is managed by an external SDS server")
+	}
+	sk, err := a.secretCache.GenerateSecret(security.WorkloadKeyCertResourceName)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(sk.CertificateChain, sk.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// meshRootCertificates returns the trust bundle of the mesh, which DNS-over-TLS and DNS-over-HTTPS upstreams
+// may be verified with.
+func (a *Agent) meshRootCertificates() (*x509.CertPool, error) {
+	if a.secretCache == nil {
+		return nil, fmt.Errorf("the mesh trust bundle is managed by an external SDS server")
+	}
+	sk, err := a.secretCache.GenerateSecret(security.RootCertReqResourceName)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(sk.RootCert) {
+		return nil, fmt.Errorf("no certificates in the mesh trust bundle")
+	}
+	return roots, nil
+}
+
 // getWorkloadCerts will attempt to get a cert, with infinite exponential backoff
 // It will not return until both workload cert and root cert are generated.
 //
@@ -490,8 +547,20 @@ func (a *Agent) startFileWatcher(ctx context.Context, filePath string, handler f
 func (a *Agent) initLocalDNSServer() (err error) {
 	// we don't need dns server on gateways
 	if a.cfg.DNSCapture && a.cfg.ProxyType == model.SidecarProxy {
+		upstreams := dnsClient.UpstreamConfig{
+			Addresses: a.cfg.DNSUpstreams,
+			Fallback:  a.cfg.DNSUpstreamFallback,
+		}
+		switch a.cfg.DNSUpstreamTrustBundle {
+		case "", DNSUpstreamSystemTrustBundle:
+		case DNSUpstreamMeshTrustBundle:
+			upstreams.RootCAs = a.meshRootCertificates
+		default:
+			return fmt.Errorf("unknown DNS upstream trust bundle %q, expected %s or %s", a.cfg.DNSUpstreamTrustBundle,
+				DNSUpstreamSystemTrustBundle, DNSUpstreamMeshTrustBundle)
+		}
 		if a.localDNSServer, err = dnsClient.NewLocalDNSServer(a.cfg.ProxyNamespace, a.cfg.ProxyDomain, a.cfg.DNSAddr,
-			a.cfg.DNSForwardParallel); err != nil {
+			a.cfg.DNSForwardParallel, a.cfg.DNSCache, upstreams); err != nil {
 			return err
 		}
 		a.localDNSServer.StartDNS()
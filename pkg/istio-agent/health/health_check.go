@@ -15,6 +15,7 @@
 package health
 
 import (
+	"crypto/tls"
 	"net/http"
 	"strings"
 	"time"
@@ -81,7 +82,11 @@ func fillInDefaults(cfg *v1alpha3.ReadinessProbe, ipAddresses []string) *v1alpha
 	return cfg
 }
 
-func NewWorkloadHealthChecker(cfg *v1alpha3.ReadinessProbe, envoyProbe ready.Prober, proxyAddrs []string, ipv6 bool) *WorkloadHealthChecker {
+// NewWorkloadHealthChecker returns a health checker of the workload. The workload certificate is presented by
+// gRPC probes with the grpcs scheme.
+func NewWorkloadHealthChecker(cfg *v1alpha3.ReadinessProbe, envoyProbe ready.Prober, proxyAddrs []string, ipv6 bool,
+	workloadCert func() (*tls.Certificate, error),
+) *WorkloadHealthChecker {
 	// if a config does not exist return a no-op prober
 	if cfg == nil {
 		return nil
@@ -90,7 +95,11 @@ func NewWorkloadHealthChecker(cfg *v1alpha3.ReadinessProbe, envoyProbe ready.Pro
 	var prober Prober
 	switch healthCheckMethod := cfg.HealthCheckMethod.(type) {
 	case *v1alpha3.ReadinessProbe_HttpGet:
-		prober = NewHTTPProber(healthCheckMethod.HttpGet, ipv6)
+		if scheme := healthCheckMethod.HttpGet.Scheme; scheme == GRPCScheme || scheme == GRPCSScheme {
+			prober = NewGRPCProber(GRPCConfigFromHTTP(healthCheckMethod.HttpGet), workloadCert, ipv6)
+		} else {
+			prober = NewHTTPProber(healthCheckMethod.HttpGet, ipv6)
+		}
 	case *v1alpha3.ReadinessProbe_TcpSocket:
 		prober = &TCPProber{Config: healthCheckMethod.TcpSocket}
 	case *v1alpha3.ReadinessProbe_Exec:
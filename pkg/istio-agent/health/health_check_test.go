@@ -47,7 +47,7 @@ func TestWorkloadHealthChecker_PerformApplicationHealthCheck(t *testing.T) {
 					Port: uint32(port),
 				},
 			},
-		}, nil, []string{"127.0.0.1"}, false)
+		}, nil, []string{"127.0.0.1"}, false, nil)
 		// Speed up tests
 		tcpHealthChecker.config.CheckFrequency = time.Millisecond
 
@@ -142,7 +142,7 @@ func TestWorkloadHealthChecker_PerformApplicationHealthCheck(t *testing.T) {
 					Host:   host,
 				},
 			},
-		}, nil, []string{"127.0.0.1"}, false)
+		}, nil, []string{"127.0.0.1"}, false, nil)
 		// Speed up tests
 		httpHealthChecker.config.CheckFrequency = time.Millisecond
 		quitChan := test.NewStop(t)
@@ -23,8 +23,14 @@ import (
 	"net/url"
 	"os/exec"
 	"strconv"
+	"strings"
 	"time"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
 	"istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
@@ -186,6 +192,99 @@ func (e *ExecProber) Probe(timeout time.Duration) (ProbeResult, error) {
 	return Healthy, nil
 }
 
+// Schemes of httpGet probes that are probed with the gRPC health checking protocol, as WorkloadGroup probes
+// have no gRPC health check method.
+const (
+	GRPCScheme  = "grpc"
+	GRPCSScheme = "grpcs"
+)
+
+// GRPCHealthCheckConfig configures a GRPCProber.
+type GRPCHealthCheckConfig struct {
+	Host string
+	Port uint32
+	// Service is the name of the service to check. If empty, the health of the server is checked.
+	Service string
+	// TLS is true if the server is called with TLS, presenting the workload certificate.
+	TLS bool
+}
+
+// GRPCConfigFromHTTP returns the gRPC health check of an httpGet probe with the grpc or grpcs scheme. The
+// path of the probe is the name of the service to check.
+func GRPCConfigFromHTTP(cfg *v1alpha3.HTTPHealthCheckConfig) *GRPCHealthCheckConfig {
+	return &GRPCHealthCheckConfig{
+		Host:    cfg.Host,
+		Port:    cfg.Port,
+		Service: strings.TrimPrefix(cfg.Path, "/"),
+		TLS:     strings.EqualFold(cfg.Scheme, GRPCSScheme),
+	}
+}
+
+// GRPCProber probes a server with the standard grpc.health.v1 health checking protocol.
+type GRPCProber struct {
+	Config *GRPCHealthCheckConfig
+	// WorkloadCertificate returns the certificate presented to the server with TLS. If nil, no client
+	// certificate is presented.
+	WorkloadCertificate func() (*tls.Certificate, error)
+	dialer              *net.Dialer
+}
+
+var _ Prober = &GRPCProber{}
+
+func NewGRPCProber(cfg *GRPCHealthCheckConfig, workloadCert func() (*tls.Certificate, error), ipv6 bool) *GRPCProber {
+	d := &net.Dialer{
+		LocalAddr: status.UpstreamLocalAddressIPv4,
+	}
+	if ipv6 {
+		d.LocalAddr = status.UpstreamLocalAddressIPv6
+	}
+	return &GRPCProber{Config: cfg, WorkloadCertificate: workloadCert, dialer: d}
+}
+
+// Probe returns healthy if the server reports the service as SERVING.
+func (g *GRPCProber) Probe(timeout time.Duration) (ProbeResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	creds := insecure.NewCredentials()
+	if g.Config.TLS {
+		// nolint: gosec
+		// As for the HTTPS probes, the server is not verified, as it is just a health check over localhost.
+		cfg := &tls.Config{InsecureSkipVerify: true}
+		if g.WorkloadCertificate != nil {
+			cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return g.WorkloadCertificate()
+			}
+		}
+		creds = credentials.NewTLS(cfg)
+	}
+	target := net.JoinHostPort(g.Config.Host, strconv.Itoa(int(g.Config.Port)))
+	conn, err := grpc.DialContext(ctx, target,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return g.dialer.DialContext(ctx, "tcp", addr)
+		}),
+		grpc.WithUserAgent("istio-probe/1.0"),
+		grpc.WithBlock())
+	// if we were unable to connect, count as failure
+	if err != nil {
+		return Unhealthy, err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			healthCheckLog.Errorf("Unable to close gRPC connection: %v", err)
+		}
+	}()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: g.Config.Service})
+	if err != nil {
+		return Unhealthy, err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return Unhealthy, fmt.Errorf("service %q is %v", g.Config.Service, resp.Status)
+	}
+	return Healthy, nil
+}
+
 type EnvoyProber struct {
 	Config ready.Prober
 }
@@ -24,6 +24,10 @@ import (
 	"testing"
 	"time"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
 	"istio.io/api/networking/v1alpha3"
 )
 
@@ -119,6 +123,66 @@ func TestTcpProber(t *testing.T) {
 	}
 }
 
+func TestGRPCProber(t *testing.T) {
+	tests := []struct {
+		desc                string
+		service             string
+		stopped             bool
+		expectedProbeResult ProbeResult
+		expectedError       bool
+	}{
+		{
+			desc:                "Healthy - server",
+			expectedProbeResult: Healthy,
+		},
+		{
+			desc:                "Healthy - service serving",
+			service:             "serving",
+			expectedProbeResult: Healthy,
+		},
+		{
+			desc:                "Unhealthy - service not serving",
+			service:             "not-serving",
+			expectedProbeResult: Unhealthy,
+			expectedError:       true,
+		},
+		{
+			desc:                "Unhealthy - unknown service",
+			service:             "unknown",
+			expectedProbeResult: Unhealthy,
+			expectedError:       true,
+		},
+		{
+			desc:                "Unhealthy - Could not connect to server",
+			stopped:             true,
+			expectedProbeResult: Unhealthy,
+			expectedError:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			server, port := createGRPCServer(t)
+			defer server.Stop()
+			grpcProber := NewGRPCProber(GRPCConfigFromHTTP(&v1alpha3.HTTPHealthCheckConfig{
+				Path:   "/" + tt.service,
+				Port:   port,
+				Host:   "127.0.0.1",
+				Scheme: GRPCScheme,
+			}), nil, false)
+
+			if tt.stopped {
+				server.Stop()
+			}
+
+			got, err := grpcProber.Probe(time.Second)
+			if got != tt.expectedProbeResult || (err != nil) != tt.expectedError {
+				t.Errorf("%s: got: %v, expected: %v, got error: %v, expected error %v", tt.desc, got, tt.expectedProbeResult, err, tt.expectedError)
+			}
+		})
+	}
+}
+
 func TestExecProber(t *testing.T) {
 	tests := []struct {
 		desc                string
@@ -182,3 +246,19 @@ func createHTTPServer(statusCode int) (*httptest.Server, uint32) {
 
 	return server, uint32(port)
 }
+
+func createGRPCServer(t *testing.T) (*grpc.Server, uint32) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := health.NewServer()
+	hs.SetServingStatus("serving", healthpb.HealthCheckResponse_SERVING)
+	hs.SetServingStatus("not-serving", healthpb.HealthCheckResponse_NOT_SERVING)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, hs)
+	go func() {
+		_ = server.Serve(l)
+	}()
+	return server, uint32(l.Addr().(*net.TCPAddr).Port)
+}
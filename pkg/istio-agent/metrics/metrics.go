@@ -60,6 +60,12 @@ var (
 		"The total number of Xds Proxy Responses",
 	)
 
+	// XdsProxySnapshotStaleness records the age of the last known config served to Envoy while Istiod is unreachable.
+	XdsProxySnapshotStaleness = monitoring.NewGauge(
+		"xds_proxy_snapshot_staleness_seconds",
+		"The age in seconds of the last known config served while Istiod is unreachable, or 0 when connected to Istiod",
+	)
+
 	IstiodConnectionCancellations = istiodDisconnections.With(disconnectionTypeTag.Value(Cancel))
 	IstiodConnectionErrors        = istiodDisconnections.With(disconnectionTypeTag.Value(Error))
 	EnvoyConnectionCancellations  = envoyDisconnections.With(disconnectionTypeTag.Value(Cancel))
@@ -74,5 +80,6 @@ func init() {
 		envoyDisconnections,
 		XdsProxyRequests,
 		XdsProxyResponses,
+		XdsProxySnapshotStaleness,
 	)
 }
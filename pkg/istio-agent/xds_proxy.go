@@ -121,6 +121,10 @@ type XdsProxy struct {
 	ecdsLastNonce         atomic.String
 	downstreamGrpcOptions []grpc.ServerOption
 	istiodSAN             string
+
+	// snapshot keeps the last known config, served while istiod is unreachable. It is nil unless
+	// XDS_SNAPSHOT_PATH is set, and with delta xDS, whose responses are not kept.
+	snapshot *xdsSnapshot
 }
 
 var proxyLog = log.RegisterScope("xdsproxy", "XDS Proxy in Istio Agent", 0)
@@ -145,13 +149,15 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 	}
 
 	cache := wasm.NewLocalFileCache(constants.IstioDataDir, ia.cfg.WASMOptions)
+	healthChecker := health.NewWorkloadHealthChecker(ia.proxyConfig.ReadinessProbe, envoyProbe, ia.cfg.ProxyIPAddresses, ia.cfg.IsIPv6,
+		ia.workloadCertificate)
 	proxy := &XdsProxy{
 		istiodAddress:         ia.proxyConfig.DiscoveryAddress,
 		istiodSAN:             ia.cfg.IstiodSAN,
 		clusterID:             ia.secOpts.ClusterID,
 		handlers:              map[string]ResponseHandler{},
 		stopChan:              make(chan struct{}),
-		healthChecker:         health.NewWorkloadHealthChecker(ia.proxyConfig.ReadinessProbe, envoyProbe, ia.cfg.ProxyIPAddresses, ia.cfg.IsIPv6),
+		healthChecker:         healthChecker,
 		xdsHeaders:            ia.cfg.XDSHeaders,
 		xdsUdsPath:            ia.cfg.XdsUdsPath,
 		wasmCache:             cache,
@@ -160,6 +166,16 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		downstreamGrpcOptions: ia.cfg.DownstreamGrpcOptions,
 	}
 
+	if ia.cfg.XdsSnapshotPath != "" {
+		if features.DeltaXds {
+			// The delta xDS stream of Envoy is proxied as is, without keeping its responses.
+			proxyLog.Warnf("XDS_SNAPSHOT_PATH is not supported with ISTIO_DELTA_XDS, the xDS config is not persisted")
+		} else {
+			proxy.snapshot = newXdsSnapshot(ia.cfg.XdsSnapshotPath, ia.cfg.XdsSnapshotMaxAge)
+			go proxy.snapshot.run(proxy.stopChan)
+		}
+	}
+
 	if ia.localDNSServer != nil {
 		proxy.handlers[v3.NameTableType] = func(resp *anypb.Any) error {
 			var nt dnsProto.NameTable
@@ -345,6 +361,9 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 	if err != nil {
 		proxyLog.Errorf("failed to connect to upstream %s: %v", p.istiodAddress, err)
 		metrics.IstiodConnectionFailures.Increment()
+		if p.snapshot.usable() {
+			return p.serveSnapshot(con)
+		}
 		return err
 	}
 	defer upstreamConn.Close()
@@ -375,10 +394,18 @@ func (p *XdsProxy) handleUpstream(ctx context.Context, con *ProxyConnection, xds
 		proxyLog.Debugf("failed to create upstream grpc client: %v", err)
 		// Increase metric when xds connection error, for example: forgot to restart ingressgateway or sidecar after changing root CA.
 		metrics.IstiodConnectionErrors.Increment()
+		if p.snapshot.usable() {
+			return p.serveSnapshot(con)
+		}
 		return err
 	}
 	proxyLog.Infof("connected to upstream XDS server: %s", p.istiodAddress)
 	defer proxyLog.Debugf("disconnected from XDS server: %s", p.istiodAddress)
+	if p.snapshot != nil {
+		metrics.XdsProxySnapshotStaleness.Record(0)
+		p.snapshot.setLive(true)
+		defer p.snapshot.setLive(false)
+	}
 
 	con.upstream = upstream
 
@@ -448,6 +475,7 @@ func (p *XdsProxy) handleUpstreamRequest(con *ProxyConnection) {
 				return
 			}
 
+			p.snapshot.requested(req)
 			// forward to istiod
 			con.sendRequest(req)
 			if !initialRequestsSent.Load() && req.TypeUrl == v3.ListenerType {
@@ -525,6 +553,8 @@ func (p *XdsProxy) handleUpstreamResponse(con *ProxyConnection) {
 						Code:    int32(codes.Internal),
 						Message: err.Error(),
 					}
+				} else {
+					p.snapshot.ack(resp)
 				}
 				// Send ACK/NACK
 				con.sendRequest(&discovery.DiscoveryRequest{
@@ -549,16 +579,19 @@ func (p *XdsProxy) handleUpstreamResponse(con *ProxyConnection) {
 					})
 				} else {
 					// Otherwise, forward ECDS resource update directly to Envoy.
+					p.snapshot.sent(resp)
 					forwardToEnvoy(con, resp)
 				}
 			default:
 				if strings.HasPrefix(resp.TypeUrl, v3.DebugType) {
 					p.forwardToTap(resp)
 				} else {
+					p.snapshot.sent(resp)
 					forwardToEnvoy(con, resp)
 				}
 			}
 		case resp := <-forwardEnvoyCh:
+			p.snapshot.sent(resp)
 			forwardToEnvoy(con, resp)
 		case <-con.stopChan:
 			return
@@ -0,0 +1,448 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/istio-agent/metrics"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// snapshotFlushInterval is how often the snapshot is written to disk, if it changed.
+	snapshotFlushInterval = time.Second
+	// snapshotRefreshInterval is how often the update time of the snapshot is refreshed while connected to
+	// istiod, as the snapshot is current even if istiod does not push.
+	snapshotRefreshInterval = time.Minute
+	// snapshotReconnectInterval is how often istiod is dialed while the snapshot is served.
+	snapshotReconnectInterval = 5 * time.Second
+)
+
+// mergedTypes are the types istiod pushes partially, with only the resources that changed, returning the
+// name of their resources. Their resources are merged by name across responses, so that the snapshot has all
+// the resources Envoy has, rather than those of the last push.
+var mergedTypes = map[string]func(*anypb.Any) (string, error){
+	v3.EndpointType: func(r *anypb.Any) (string, error) {
+		cla := &endpoint.ClusterLoadAssignment{}
+		err := r.UnmarshalTo(cla)
+		return cla.ClusterName, err
+	},
+	v3.RouteType: func(r *anypb.Any) (string, error) {
+		rc := &route.RouteConfiguration{}
+		err := r.UnmarshalTo(rc)
+		return rc.Name, err
+	},
+	v3.ExtensionConfigurationType: func(r *anypb.Any) (string, error) {
+		tec := &core.TypedExtensionConfig{}
+		err := r.UnmarshalTo(tec)
+		return tec.Name, err
+	},
+}
+
+// xdsSnapshot keeps the last responses ACKed by Envoy, and by the agent for the types it handles itself
+// (e.g. NDS), for each type URL, merging the resources of the mergedTypes, and persists them to a file. When
+// istiod is unreachable the XdsProxy serves the snapshot, so that a restarted Envoy and the DNS proxy start
+// with the last known config.
+// A nil xdsSnapshot keeps nothing.
+type xdsSnapshot struct {
+	path   string
+	maxAge time.Duration
+
+	mu sync.Mutex
+	// responses are the last ACKed responses by type URL. The responses of the mergedTypes have the merged
+	// resources.
+	responses map[string]*discovery.DiscoveryResponse
+	// resources are the merged resources of the mergedTypes by type URL and name
+	resources map[string]map[string]*anypb.Any
+	// subscriptions are the resource names of the mergedTypes last requested by Envoy, by type URL. They
+	// follow the clusters and listeners Envoy last accepted, and resources outside of them are dropped.
+	subscriptions map[string]sets.Set[string]
+	// pending are the responses sent to Envoy and not yet ACKed, by type URL
+	pending map[string]*discovery.DiscoveryResponse
+	// updatedAt is the last time the responses were known to be current
+	updatedAt time.Time
+	// live is true while connected to istiod
+	live  bool
+	dirty bool
+}
+
+// snapshotFile is the format of the persisted snapshot.
+type snapshotFile struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Responses are the serialized DiscoveryResponses. They are kept as protobuf rather than JSON, so that
+	// the types of the resources do not need to be known.
+	Responses [][]byte `json:"responses"`
+}
+
+// newXdsSnapshot returns a snapshot persisted to the path, loading the responses already persisted there.
+func newXdsSnapshot(path string, maxAge time.Duration) *xdsSnapshot {
+	s := &xdsSnapshot{
+		path:          path,
+		maxAge:        maxAge,
+		responses:     map[string]*discovery.DiscoveryResponse{},
+		resources:     map[string]map[string]*anypb.Any{},
+		subscriptions: map[string]sets.Set[string]{},
+		pending:       map[string]*discovery.DiscoveryResponse{},
+	}
+	if err := s.load(); err != nil {
+		proxyLog.Warnf("failed to load the xDS snapshot from %s, starting with an empty snapshot: %v", path, err)
+	}
+	return s
+}
+
+func (s *xdsSnapshot) load() error {
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f := snapshotFile{}
+	if err := json.Unmarshal(b, &f); err != nil {
+		return err
+	}
+	responses := map[string]*discovery.DiscoveryResponse{}
+	for _, r := range f.Responses {
+		resp := &discovery.DiscoveryResponse{}
+		if err := proto.Unmarshal(r, resp); err != nil {
+			return err
+		}
+		responses[resp.TypeUrl] = resp
+	}
+	s.responses = responses
+	for typeURL, resp := range responses {
+		if nameOf, f := mergedTypes[typeURL]; f {
+			s.resources[typeURL] = resourcesByName(resp, nameOf)
+		}
+	}
+	s.updatedAt = f.UpdatedAt
+	return nil
+}
+
+// sent records a response forwarded to Envoy, which is kept once Envoy ACKs it.
+func (s *xdsSnapshot) sent(resp *discovery.DiscoveryResponse) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[resp.TypeUrl] = resp
+}
+
+// requested keeps the pending response of the type of the request if the request ACKs it, and drops the
+// merged resources Envoy no longer requests.
+func (s *xdsSnapshot) requested(req *discovery.DiscoveryRequest) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, f := mergedTypes[req.TypeUrl]; f && len(req.ResourceNames) > 0 {
+		s.subscriptions[req.TypeUrl] = sets.New(req.ResourceNames...)
+		if prev := s.responses[req.TypeUrl]; prev != nil && s.pruneLocked(req.TypeUrl) {
+			s.responses[req.TypeUrl] = s.mergedLocked(prev)
+			s.dirty = true
+		}
+	}
+	if req.ResponseNonce == "" {
+		return
+	}
+	resp := s.pending[req.TypeUrl]
+	if resp == nil || resp.Nonce != req.ResponseNonce {
+		return
+	}
+	delete(s.pending, req.TypeUrl)
+	if req.ErrorDetail == nil {
+		s.keepLocked(resp)
+	}
+}
+
+// ack keeps a response handled by the agent.
+func (s *xdsSnapshot) ack(resp *discovery.DiscoveryResponse) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keepLocked(resp)
+}
+
+func (s *xdsSnapshot) keepLocked(resp *discovery.DiscoveryResponse) {
+	if nameOf, f := mergedTypes[resp.TypeUrl]; f {
+		byName := s.resources[resp.TypeUrl]
+		if byName == nil {
+			byName = map[string]*anypb.Any{}
+			s.resources[resp.TypeUrl] = byName
+		}
+		for name, r := range resourcesByName(resp, nameOf) {
+			byName[name] = r
+		}
+		s.pruneLocked(resp.TypeUrl)
+		resp = s.mergedLocked(resp)
+	}
+	s.responses[resp.TypeUrl] = resp
+	s.updatedAt = time.Now()
+	s.dirty = true
+}
+
+// pruneLocked drops the merged resources of the type that Envoy no longer requests, and returns true if any
+// was dropped.
+func (s *xdsSnapshot) pruneLocked(typeURL string) bool {
+	subscribed, f := s.subscriptions[typeURL]
+	if !f {
+		return false
+	}
+	pruned := false
+	for name := range s.resources[typeURL] {
+		if !subscribed.Contains(name) {
+			delete(s.resources[typeURL], name)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// mergedLocked returns a copy of the response of a merged type with the merged resources, sorted by name.
+func (s *xdsSnapshot) mergedLocked(resp *discovery.DiscoveryResponse) *discovery.DiscoveryResponse {
+	byName := s.resources[resp.TypeUrl]
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	resources := make([]*anypb.Any, 0, len(names))
+	for _, name := range names {
+		resources = append(resources, byName[name])
+	}
+	return &discovery.DiscoveryResponse{
+		VersionInfo:  resp.VersionInfo,
+		Resources:    resources,
+		TypeUrl:      resp.TypeUrl,
+		Nonce:        resp.Nonce,
+		ControlPlane: resp.ControlPlane,
+	}
+}
+
+// resourcesByName returns the resources of the response by name. Resources whose name cannot be read are
+// skipped.
+func resourcesByName(resp *discovery.DiscoveryResponse, nameOf func(*anypb.Any) (string, error)) map[string]*anypb.Any {
+	out := make(map[string]*anypb.Any, len(resp.Resources))
+	for _, r := range resp.Resources {
+		name, err := nameOf(r)
+		if err != nil {
+			proxyLog.Warnf("failed to read the name of a %s resource, not keeping it: %v", resp.TypeUrl, err)
+			continue
+		}
+		out[name] = r
+	}
+	return out
+}
+
+// response returns the kept response of the type URL, or nil.
+func (s *xdsSnapshot) response(typeURL string) *discovery.DiscoveryResponse {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.responses[typeURL]
+}
+
+// setLive marks whether the proxy is connected to istiod, and so whether the snapshot is current.
+func (s *xdsSnapshot) setLive(live bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if live || s.live {
+		s.updatedAt = time.Now()
+		s.dirty = true
+	}
+	s.live = live
+}
+
+// age returns how long ago the snapshot was last known to be current.
+func (s *xdsSnapshot) age() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.updatedAt)
+}
+
+// usable returns true if the snapshot has responses, and is not older than the max age.
+func (s *xdsSnapshot) usable() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	empty := len(s.responses) == 0
+	s.mu.Unlock()
+	return !empty && s.age() <= s.maxAge
+}
+
+// flush writes the snapshot to its file, if it changed since the last flush.
+func (s *xdsSnapshot) flush() error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	f := snapshotFile{UpdatedAt: s.updatedAt}
+	for _, resp := range s.responses {
+		b, err := proto.Marshal(resp)
+		if err != nil {
+			s.mu.Unlock()
+			return fmt.Errorf("failed to marshal %s: %v", resp.TypeUrl, err)
+		}
+		f.Responses = append(f.Responses, b)
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, so that a crash does not leave a partial snapshot.
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// run periodically persists the snapshot until stop is closed.
+func (s *xdsSnapshot) run(stop <-chan struct{}) {
+	flush := time.NewTicker(snapshotFlushInterval)
+	defer flush.Stop()
+	refresh := time.NewTicker(snapshotRefreshInterval)
+	defer refresh.Stop()
+	for {
+		select {
+		case <-flush.C:
+			if err := s.flush(); err != nil {
+				proxyLog.Warnf("failed to persist the xDS snapshot to %s: %v", s.path, err)
+			}
+		case <-refresh.C:
+			s.mu.Lock()
+			if s.live {
+				s.updatedAt = time.Now()
+				s.dirty = true
+			}
+			s.mu.Unlock()
+		case <-stop:
+			if err := s.flush(); err != nil {
+				proxyLog.Warnf("failed to persist the xDS snapshot to %s: %v", s.path, err)
+			}
+			return
+		}
+	}
+}
+
+// serveSnapshot serves the snapshot to Envoy while istiod is unreachable, and passes the snapshot of the
+// types handled by the agent, such as the NDS of the DNS proxy, to their handlers. Once istiod is reachable
+// again, the downstream stream is terminated, so that Envoy reconnects and resumes the xDS sequence with istiod.
+func (p *XdsProxy) serveSnapshot(con *ProxyConnection) error {
+	proxyLog.Warnf("upstream %s is unreachable, serving the xDS config saved %v ago", p.istiodAddress,
+		p.snapshot.age().Round(time.Second))
+	metrics.XdsProxySnapshotStaleness.Record(p.snapshot.age().Seconds())
+	for typeURL, h := range p.handlers {
+		if resp := p.snapshot.response(typeURL); resp != nil && len(resp.Resources) > 0 {
+			if err := h(resp.Resources[0]); err != nil {
+				proxyLog.Warnf("failed to handle the saved %s: %v", typeURL, err)
+			}
+		}
+	}
+
+	go func() {
+		for {
+			req, err := con.downstream.Recv()
+			if err != nil {
+				select {
+				case con.downstreamError <- err:
+				case <-con.stopChan:
+				}
+				return
+			}
+			con.sendRequest(req)
+		}
+	}()
+
+	t := time.NewTicker(snapshotReconnectInterval)
+	defer t.Stop()
+	for {
+		select {
+		case req := <-con.requestsChan.Get():
+			con.requestsChan.Load()
+			// Only answer the initial requests of each type. The others are ACKs or NACKs of the snapshot.
+			if req.ResponseNonce != "" {
+				continue
+			}
+			if resp := p.snapshot.response(req.TypeUrl); resp != nil {
+				proxyLog.Debugf("serving saved response for type url %s", req.TypeUrl)
+				forwardToEnvoy(con, resp)
+			}
+		case <-t.C:
+			metrics.XdsProxySnapshotStaleness.Record(p.snapshot.age().Seconds())
+			if p.istiodReachable() {
+				proxyLog.Infof("upstream %s is reachable again, reconnecting", p.istiodAddress)
+				return status.Error(codes.Unavailable, "reconnecting to the upstream XDS server")
+			}
+		case err := <-con.downstreamError:
+			return err
+		case <-con.stopChan:
+			return nil
+		}
+	}
+}
+
+// istiodReachable returns true if a connection to istiod can be established.
+func (p *XdsProxy) istiodReachable() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), snapshotReconnectInterval)
+	defer cancel()
+	p.optsMutex.RLock()
+	opts := make([]grpc.DialOption, 0, len(p.istiodDialOptions)+1)
+	opts = append(opts, p.istiodDialOptions...)
+	p.optsMutex.RUnlock()
+	conn, err := grpc.DialContext(ctx, p.istiodAddress, append(opts, grpc.WithBlock())...)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
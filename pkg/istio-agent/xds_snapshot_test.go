@@ -0,0 +1,178 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pilot/pkg/xds"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestXdsSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot")
+	s := newXdsSnapshot(path, time.Hour)
+	assert.Equal(t, s.usable(), false)
+
+	cds := &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, VersionInfo: "1", Nonce: "a"}
+	s.sent(cds)
+	// NACKs and requests for other responses are not kept
+	s.requested(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "a", ErrorDetail: &google_rpc.Status{}})
+	assert.Equal(t, s.response(v3.ClusterType) == nil, true)
+	s.sent(cds)
+	s.requested(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "b"})
+	assert.Equal(t, s.response(v3.ClusterType) == nil, true)
+	s.requested(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "a"})
+	assert.Equal(t, s.response(v3.ClusterType), cds)
+
+	nds := &discovery.DiscoveryResponse{TypeUrl: v3.NameTableType, VersionInfo: "1", Nonce: "c"}
+	s.ack(nds)
+	assert.Equal(t, s.usable(), true)
+	assert.NoError(t, s.flush())
+
+	loaded := newXdsSnapshot(path, time.Hour)
+	assert.Equal(t, proto.Equal(loaded.response(v3.ClusterType), cds), true)
+	assert.Equal(t, proto.Equal(loaded.response(v3.NameTableType), nds), true)
+	assert.Equal(t, loaded.usable(), true)
+
+	expired := newXdsSnapshot(path, 0)
+	assert.Equal(t, expired.usable(), false)
+}
+
+func TestXdsSnapshotMergesPartialResponses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot")
+	s := newXdsSnapshot(path, time.Hour)
+	cla := func(cluster string, weight uint32) *anypb.Any {
+		return protoconv.MessageToAny(&endpoint.ClusterLoadAssignment{
+			ClusterName: cluster,
+			Policy:      &endpoint.ClusterLoadAssignment_Policy{OverprovisioningFactor: wrapperspb.UInt32(weight)},
+		})
+	}
+	eds := func(resp *discovery.DiscoveryResponse, names ...string) {
+		t.Helper()
+		s.sent(resp)
+		s.requested(&discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: resp.Nonce, ResourceNames: names})
+	}
+	clusters := func(resp *discovery.DiscoveryResponse) map[string]uint32 {
+		t.Helper()
+		out := map[string]uint32{}
+		for _, r := range resp.Resources {
+			got := &endpoint.ClusterLoadAssignment{}
+			assert.NoError(t, r.UnmarshalTo(got))
+			out[got.ClusterName] = got.Policy.OverprovisioningFactor.GetValue()
+		}
+		return out
+	}
+
+	eds(&discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "a", Resources: []*anypb.Any{cla("a", 1), cla("b", 1)}}, "a", "b")
+	// Incremental pushes only have the clusters that changed
+	eds(&discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "b", Resources: []*anypb.Any{cla("b", 2)}}, "a", "b")
+	assert.Equal(t, clusters(s.response(v3.EndpointType)), map[string]uint32{"a": 1, "b": 2})
+	assert.Equal(t, s.response(v3.EndpointType).Nonce, "b")
+
+	// Clusters Envoy no longer requests are dropped
+	s.requested(&discovery.DiscoveryRequest{TypeUrl: v3.EndpointType, ResponseNonce: "b", ResourceNames: []string{"b", "c"}})
+	assert.Equal(t, clusters(s.response(v3.EndpointType)), map[string]uint32{"b": 2})
+	eds(&discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "c", Resources: []*anypb.Any{cla("c", 1)}}, "b", "c")
+	assert.Equal(t, clusters(s.response(v3.EndpointType)), map[string]uint32{"b": 2, "c": 1})
+
+	// The merged resources are persisted, and merged with the responses after a restart
+	assert.NoError(t, s.flush())
+	s = newXdsSnapshot(path, time.Hour)
+	assert.Equal(t, clusters(s.response(v3.EndpointType)), map[string]uint32{"b": 2, "c": 1})
+	eds(&discovery.DiscoveryResponse{TypeUrl: v3.EndpointType, Nonce: "d", Resources: []*anypb.Any{cla("c", 3)}}, "b", "c")
+	assert.Equal(t, clusters(s.response(v3.EndpointType)), map[string]uint32{"b": 2, "c": 3})
+}
+
+// Validates that the config ACKed by Envoy is served from the snapshot when istiod is unreachable.
+func TestXdsProxyServesSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot")
+	proxy := setupXdsProxy(t)
+	proxy.snapshot = newXdsSnapshot(path, time.Hour)
+	f := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	setDialOptions(proxy, f.BufListener)
+
+	node := &core.Node{
+		Id:       "sidecar~1.1.1.1~debug~cluster.local",
+		Metadata: model.NodeMetadata{Namespace: "default", InstanceIPs: []string{"1.1.1.1"}}.ToStruct(),
+	}
+	downstream := stream(t, setupDownstreamConnection(t, proxy))
+	assert.NoError(t, downstream.Send(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, Node: node}))
+	cds, err := downstream.Recv()
+	assert.NoError(t, err)
+	assert.NoError(t, downstream.Send(&discovery.DiscoveryRequest{
+		TypeUrl:       v3.ClusterType,
+		VersionInfo:   cds.VersionInfo,
+		ResponseNonce: cds.Nonce,
+		Node:          node,
+	}))
+	retry.UntilSuccessOrFail(t, func() error {
+		if proxy.snapshot.response(v3.ClusterType) == nil {
+			return fmt.Errorf("CDS response not kept")
+		}
+		return nil
+	}, retry.Timeout(time.Second*5))
+	assert.NoError(t, proxy.snapshot.flush())
+
+	unreachable := func(p *XdsProxy) {
+		p.istiodDialOptions = []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				return nil, errors.New("istiod is unreachable")
+			}),
+		}
+	}
+
+	// A restarted agent serves the persisted CDS response while istiod is unreachable
+	offline := setupXdsProxy(t)
+	offline.snapshot = newXdsSnapshot(path, time.Hour)
+	unreachable(offline)
+	downstream = stream(t, setupDownstreamConnection(t, offline))
+	assert.NoError(t, downstream.Send(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, Node: node}))
+	res, err := downstream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, res.VersionInfo, cds.VersionInfo)
+	assert.Equal(t, len(res.Resources), len(cds.Resources))
+
+	// Snapshots older than the max age are not served
+	expired := setupXdsProxy(t)
+	expired.snapshot = newXdsSnapshot(path, 0)
+	unreachable(expired)
+	downstream = stream(t, setupDownstreamConnection(t, expired))
+	assert.NoError(t, downstream.Send(&discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, Node: node}))
+	if _, err := downstream.Recv(); err == nil {
+		t.Fatalf("expected the stream to fail without a usable snapshot")
+	}
+}